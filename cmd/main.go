@@ -1,23 +1,133 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"invoice-system/internal/audit"
 	"invoice-system/internal/database"
+	"invoice-system/internal/dunning"
+	"invoice-system/internal/fx"
 	"invoice-system/internal/handlers"
+	"invoice-system/internal/mailer"
+	"invoice-system/internal/money"
+	"invoice-system/internal/scheduler"
+	"invoice-system/internal/storage"
+	"invoice-system/internal/templates"
+	"invoice-system/internal/terms"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gorilla/mux"
 )
 
 func main() {
+    // Configure how money calculations round
+    rounding, err := money.ParseRoundingMode(os.Getenv("MONEY_ROUNDING"))
+    if err != nil {
+        log.Fatal("Invalid MONEY_ROUNDING: ", err)
+    }
+    money.Rounding = rounding
+
+    if base := os.Getenv("BASE_CURRENCY"); base != "" {
+        fx.BaseCurrency = strings.ToUpper(base)
+    }
+
+    if v := os.Getenv("DEFAULT_PAYMENT_TERMS"); v != "" {
+        terms.Default, err = terms.Parse(v)
+        if err != nil {
+            log.Fatal("Invalid DEFAULT_PAYMENT_TERMS: ", err)
+        }
+    }
+
+    templates.Dir = os.Getenv("TEMPLATE_DIR")
+
+    // Email delivery is optional; without SMTP_HOST invoices cannot be sent
+    if host := os.Getenv("SMTP_HOST"); host != "" {
+        port := 587
+        if v := os.Getenv("SMTP_PORT"); v != "" {
+            port, err = strconv.Atoi(v)
+            if err != nil {
+                log.Fatal("Invalid SMTP_PORT: ", v)
+            }
+        }
+        security, err := mailer.ParseSecurity(os.Getenv("SMTP_SECURITY"))
+        if err != nil {
+            log.Fatal("Invalid SMTP_SECURITY: ", err)
+        }
+        from := os.Getenv("SMTP_FROM")
+        if from == "" {
+            log.Fatal("SMTP_FROM is required when SMTP_HOST is set")
+        }
+        mailer.Default = &mailer.SMTP{
+            Host:     host,
+            Port:     port,
+            Username: os.Getenv("SMTP_USERNAME"),
+            Password: os.Getenv("SMTP_PASSWORD"),
+            From:     from,
+            Security: security,
+        }
+        dunning.Default = dunning.Email{Mailer: mailer.Default}
+    }
+
+    // Attachments are kept on the local disk unless an S3 bucket is set up
+    switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+    case "", "local":
+        dir := os.Getenv("STORAGE_DIR")
+        if dir == "" {
+            dir = "attachments"
+        }
+        storage.Default = storage.Local{Dir: dir}
+    case "s3":
+        region := os.Getenv("S3_REGION")
+        if region == "" {
+            region = "us-east-1"
+        }
+        store := &storage.S3{
+            Endpoint:        os.Getenv("S3_ENDPOINT"),
+            Region:          region,
+            Bucket:          os.Getenv("S3_BUCKET"),
+            AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+            SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+            SessionToken:    os.Getenv("S3_SESSION_TOKEN"),
+        }
+        if store.Endpoint == "" || store.Bucket == "" {
+            log.Fatal("S3_ENDPOINT and S3_BUCKET are required when STORAGE_BACKEND is s3")
+        }
+        storage.Default = store
+    default:
+        log.Fatal("Invalid STORAGE_BACKEND: ", backend)
+    }
+    if v := os.Getenv("ATTACHMENT_MAX_BYTES"); v != "" {
+        handlers.MaxAttachmentSize, err = strconv.ParseInt(v, 10, 64)
+        if err != nil || handlers.MaxAttachmentSize <= 0 {
+            log.Fatal("Invalid ATTACHMENT_MAX_BYTES: ", v)
+        }
+    }
+
     // Initialize database
     database.InitDB()
 
+    // Load exchange rates shipped as a file
+    if path := os.Getenv("EXCHANGE_RATES_FILE"); path != "" {
+        rates, err := fx.LoadFile(path)
+        if err != nil {
+            log.Fatal("Failed to load exchange rates: ", err)
+        }
+        err = fx.Import(database.DB, rates, path)
+        if err != nil {
+            log.Fatal("Failed to import exchange rates: ", err)
+        }
+        log.Printf("Imported %d exchange rates from %s", len(rates), path)
+    }
+
     // Initialize router
     r := mux.NewRouter()
+    r.Use(audit.Middleware)
 
     // Customer routes
     r.HandleFunc("/api/customers", handlers.GetCustomers).Methods("GET")
@@ -25,6 +135,8 @@ func main() {
     r.HandleFunc("/api/customers/{id}", handlers.GetCustomer).Methods("GET")
     r.HandleFunc("/api/customers/{id}", handlers.UpdateCustomer).Methods("PUT")
     r.HandleFunc("/api/customers/{id}", handlers.DeleteCustomer).Methods("DELETE")
+    r.HandleFunc("/api/customers/{id}/restore", handlers.RestoreCustomer).Methods("POST")
+    r.HandleFunc("/api/customers/{id}/statement", handlers.GetCustomerStatement).Methods("GET")
 
     // Item routes
     r.HandleFunc("/api/items", handlers.GetItems).Methods("GET")
@@ -32,6 +144,17 @@ func main() {
     r.HandleFunc("/api/items/{id}", handlers.GetItem).Methods("GET")
     r.HandleFunc("/api/items/{id}", handlers.UpdateItem).Methods("PUT")
     r.HandleFunc("/api/items/{id}", handlers.DeleteItem).Methods("DELETE")
+    r.HandleFunc("/api/items/{id}/restore", handlers.RestoreItem).Methods("POST")
+
+    // Tax rate routes
+    r.HandleFunc("/api/tax-rates", handlers.GetTaxRates).Methods("GET")
+    r.HandleFunc("/api/tax-rates", handlers.CreateTaxRate).Methods("POST")
+    r.HandleFunc("/api/tax-rates/{id}", handlers.GetTaxRate).Methods("GET")
+    r.HandleFunc("/api/tax-rates/{id}", handlers.UpdateTaxRate).Methods("PUT")
+
+    // Exchange rate routes
+    r.HandleFunc("/api/exchange-rates", handlers.GetExchangeRates).Methods("GET")
+    r.HandleFunc("/api/exchange-rates/import", handlers.ImportExchangeRates).Methods("POST")
 
     // Invoice routes
     r.HandleFunc("/api/invoices", handlers.GetInvoices).Methods("GET")
@@ -39,7 +162,106 @@ func main() {
     r.HandleFunc("/api/invoices/{id}", handlers.GetInvoice).Methods("GET")
     r.HandleFunc("/api/invoices/{id}", handlers.UpdateInvoice).Methods("PUT")
     r.HandleFunc("/api/invoices/{id}", handlers.DeleteInvoice).Methods("DELETE")
+    r.HandleFunc("/api/invoices/{id}/restore", handlers.RestoreInvoice).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/duplicate", handlers.DuplicateInvoice).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/pdf", handlers.GetInvoicePDF).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/html", handlers.GetInvoiceHTML).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/items", handlers.GetInvoiceItems).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/items", handlers.AddInvoiceItem).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/items", handlers.ReplaceInvoiceItems).Methods("PUT")
+    r.HandleFunc("/api/invoices/{id}/items/{lineId}", handlers.UpdateInvoiceItem).Methods("PUT")
+    r.HandleFunc("/api/invoices/{id}/items/{lineId}", handlers.DeleteInvoiceItem).Methods("DELETE")
     r.HandleFunc("/api/invoices/{id}/pay", handlers.MarkInvoiceAsPaid).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/payments", handlers.GetPayments).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/payments", handlers.CreatePayment).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/issue", handlers.IssueInvoice).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/send", handlers.SendInvoice).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/deliveries", handlers.GetInvoiceDeliveries).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/void", handlers.VoidInvoice).Methods("POST")
+    r.HandleFunc("/api/invoices/{id}/history", handlers.GetInvoiceStatusHistory).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/late-fees", handlers.GetInvoiceLateFees).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/reminders", handlers.GetInvoiceReminders).Methods("GET")
+
+    // Credit note routes
+    r.HandleFunc("/api/invoices/{id}/credit-notes", handlers.GetInvoiceCreditNotes).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/credit-notes", handlers.CreateCreditNote).Methods("POST")
+    r.HandleFunc("/api/credit-notes/{id}", handlers.GetCreditNote).Methods("GET")
+    r.HandleFunc("/api/credit-notes/{id}/refunds", handlers.RefundCreditNote).Methods("POST")
+
+    // Quote routes
+    r.HandleFunc("/api/quotes", handlers.GetQuotes).Methods("GET")
+    r.HandleFunc("/api/quotes", handlers.CreateQuote).Methods("POST")
+    r.HandleFunc("/api/quotes/{id}", handlers.GetQuote).Methods("GET")
+    r.HandleFunc("/api/quotes/{id}", handlers.UpdateQuote).Methods("PUT")
+    r.HandleFunc("/api/quotes/{id}", handlers.DeleteQuote).Methods("DELETE")
+    r.HandleFunc("/api/quotes/{id}/send", handlers.SendQuote).Methods("POST")
+    r.HandleFunc("/api/quotes/{id}/accept", handlers.AcceptQuote).Methods("POST")
+    r.HandleFunc("/api/quotes/{id}/decline", handlers.DeclineQuote).Methods("POST")
+    r.HandleFunc("/api/quotes/{id}/convert", handlers.ConvertQuote).Methods("POST")
+
+    // Numbering routes
+    r.HandleFunc("/api/sequences", handlers.GetSequences).Methods("GET")
+    r.HandleFunc("/api/sequences/{name}", handlers.UpdateSequence).Methods("PUT")
+
+    // Report routes
+    r.HandleFunc("/api/reports/fx-gain-loss", handlers.GetFXGainLoss).Methods("GET")
+    r.HandleFunc("/api/reports/ar-aging", handlers.GetARAging).Methods("GET")
+    r.HandleFunc("/api/reports/revenue", handlers.GetRevenueReport).Methods("GET")
+
+    // Recurring invoice routes
+    r.HandleFunc("/api/recurring-invoices", handlers.GetRecurringInvoices).Methods("GET")
+    r.HandleFunc("/api/recurring-invoices", handlers.CreateRecurringInvoice).Methods("POST")
+    r.HandleFunc("/api/recurring-invoices/{id}", handlers.GetRecurringInvoice).Methods("GET")
+    r.HandleFunc("/api/recurring-invoices/{id}", handlers.UpdateRecurringInvoice).Methods("PUT")
+    r.HandleFunc("/api/recurring-invoices/{id}", handlers.DeleteRecurringInvoice).Methods("DELETE")
+    r.HandleFunc("/api/recurring-invoices/{id}/runs", handlers.GetRecurringInvoiceRuns).Methods("GET")
+
+    // Late fee policy routes
+    r.HandleFunc("/api/late-fee-policies", handlers.GetLateFeePolicies).Methods("GET")
+    r.HandleFunc("/api/late-fee-policies", handlers.CreateLateFeePolicy).Methods("POST")
+    r.HandleFunc("/api/late-fee-policies/{id}", handlers.GetLateFeePolicy).Methods("GET")
+    r.HandleFunc("/api/late-fee-policies/{id}", handlers.UpdateLateFeePolicy).Methods("PUT")
+
+    // Payment reminder routes
+    r.HandleFunc("/api/reminder-rules", handlers.GetReminderRules).Methods("GET")
+    r.HandleFunc("/api/reminder-rules", handlers.CreateReminderRule).Methods("POST")
+    r.HandleFunc("/api/reminder-rules/{id}", handlers.GetReminderRule).Methods("GET")
+    r.HandleFunc("/api/reminder-rules/{id}", handlers.UpdateReminderRule).Methods("PUT")
+    r.HandleFunc("/api/reminder-rules/{id}", handlers.DeleteReminderRule).Methods("DELETE")
+
+    // Company profile and invoice template routes
+    r.HandleFunc("/api/company", handlers.GetCompanyProfile).Methods("GET")
+    r.HandleFunc("/api/company", handlers.UpdateCompanyProfile).Methods("PUT")
+    r.HandleFunc("/api/templates", handlers.GetInvoiceTemplates).Methods("GET")
+    r.HandleFunc("/api/templates/{name}", handlers.GetInvoiceTemplate).Methods("GET")
+    r.HandleFunc("/api/templates/{name}", handlers.SaveInvoiceTemplate).Methods("PUT")
+    r.HandleFunc("/api/templates/{name}", handlers.DeleteInvoiceTemplate).Methods("DELETE")
+
+    // Attachment routes
+    r.HandleFunc("/api/invoices/{id}/attachments", handlers.GetInvoiceAttachments).Methods("GET")
+    r.HandleFunc("/api/invoices/{id}/attachments", handlers.UploadInvoiceAttachment).Methods("POST")
+    r.HandleFunc("/api/customers/{id}/attachments", handlers.GetCustomerAttachments).Methods("GET")
+    r.HandleFunc("/api/customers/{id}/attachments", handlers.UploadCustomerAttachment).Methods("POST")
+    r.HandleFunc("/api/attachments/{id}", handlers.DownloadAttachment).Methods("GET")
+    r.HandleFunc("/api/attachments/{id}", handlers.DeleteAttachment).Methods("DELETE")
+
+    // Audit routes
+    r.HandleFunc("/api/audit", handlers.GetAuditLog).Methods("GET")
+
+    // Background jobs
+    interval := time.Hour
+    if v := os.Getenv("SCHEDULER_INTERVAL"); v != "" {
+        interval, err = time.ParseDuration(v)
+        if err != nil || interval <= 0 {
+            log.Fatal("Invalid SCHEDULER_INTERVAL: ", v)
+        }
+    }
+    jobs := scheduler.New()
+    jobs.Every("recurring-invoices", interval, handlers.GenerateRecurringInvoices)
+    jobs.Every("overdue-invoices", interval, handlers.ProcessOverdueInvoices)
+    jobs.Every("payment-reminders", interval, handlers.ProcessPaymentReminders)
+    jobs.Every("quote-expiry", interval, handlers.ExpireQuotes)
+    jobs.Start(context.Background())
 
     // Start server
     port := os.Getenv("PORT")
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+    EntityCustomer   = "customer"
+    EntityItem       = "item"
+    EntityInvoice    = "invoice"
+    EntityAttachment = "attachment"
+
+    ActionCreate  = "create"
+    ActionUpdate  = "update"
+    ActionDelete  = "delete"
+    ActionRestore = "restore"
+    ActionPay     = "pay"
+    ActionIssue   = "issue"
+    ActionSend    = "send"
+    ActionVoid    = "void"
+    ActionCredit  = "credit"
+    ActionRefund  = "refund"
+    ActionOverdue = "overdue"
+    ActionLateFee = "late_fee"
+
+    // SystemActor is recorded for changes made by background jobs.
+    SystemActor = "scheduler"
+
+    // RequestIDHeader carries the request id in and out. Callers may pick
+    // their own id to follow a request across services.
+    RequestIDHeader = "X-Request-ID"
+
+    maxRequestIDLength = 64
+)
+
+type contextKey struct{}
+
+// Middleware gives every request an id, echoed in the response headers and
+// recorded with the audit entries the request writes.
+func Middleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(RequestIDHeader)
+        if id == "" || len(id) > maxRequestIDLength {
+            id = newRequestID()
+        }
+        w.Header().Set(RequestIDHeader, id)
+        next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+    })
+}
+
+// RequestID returns the id Middleware gave the request, or "" outside of
+// a request.
+func RequestID(ctx context.Context) string {
+    id, _ := ctx.Value(contextKey{}).(string)
+    return id
+}
+
+func newRequestID() string {
+    b := make([]byte, 16)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+type execer interface {
+    Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Entry describes one change. Before is nil for creations; both snapshots
+// are stored as JSON.
+type Entry struct {
+    RequestID  string
+    Actor      string
+    EntityType string
+    EntityID   int
+    Action     string
+    Before     interface{}
+    After      interface{}
+}
+
+// Record appends an entry to the audit log. Pass the transaction making
+// the change so the entry commits or rolls back with it.
+func Record(x execer, e Entry) error {
+    before, err := snapshot(e.Before)
+    if err != nil {
+        return err
+    }
+    after, err := snapshot(e.After)
+    if err != nil {
+        return err
+    }
+
+    _, err = x.Exec(`
+        INSERT INTO audit_log (request_id, actor, entity_type, entity_id, action, before_data, after_data)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, e.RequestID, e.Actor, e.EntityType, e.EntityID, e.Action, before, after)
+    return err
+}
+
+func snapshot(v interface{}) (interface{}, error) {
+    if v == nil {
+        return nil, nil
+    }
+    b, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+    return string(b), nil
+}
\ No newline at end of file
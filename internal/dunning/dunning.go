@@ -0,0 +1,142 @@
+// Package dunning picks the payment reminder an unpaid invoice is due and
+// renders it for delivery through a Notifier.
+package dunning
+
+import (
+	"bytes"
+	_ "embed"
+	"strings"
+
+	"invoice-system/internal/mailer"
+	"invoice-system/internal/models"
+	"invoice-system/internal/templates"
+)
+
+// DefaultSubject is used by rules without a subject of their own.
+const DefaultSubject = "Payment reminder: invoice {{.Invoice.InvoiceNumber}}"
+
+//go:embed reminder.html
+var defaultBody string
+
+// Notice is a rendered reminder.
+type Notice struct {
+    To      string
+    Subject string
+    HTML    string
+}
+
+// Notifier delivers reminders. Channel names it in the reminder log.
+type Notifier interface {
+    Channel() string
+    Notify(n Notice) error
+}
+
+// Default delivers the reminders of the background job. It is nil when no
+// channel is configured, which leaves the job switched off.
+var Default Notifier
+
+// Email sends reminders as email.
+type Email struct {
+    Mailer mailer.Mailer
+}
+
+func (e Email) Channel() string {
+    return "email"
+}
+
+func (e Email) Notify(n Notice) error {
+    return e.Mailer.Send(mailer.Message{
+        To:      []string{n.To},
+        Subject: n.Subject,
+        HTML:    n.HTML,
+    })
+}
+
+// Data is what reminder templates are executed with. DaysFromDue is
+// negative before the due date; DaysOverdue and DaysUntilDue are the
+// positive side of it or zero.
+type Data struct {
+    templates.Data
+    DaysFromDue  int
+    DaysOverdue  int
+    DaysUntilDue int
+}
+
+func NewData(d templates.Data, daysFromDue int) Data {
+    data := Data{Data: d, DaysFromDue: daysFromDue}
+    if daysFromDue > 0 {
+        data.DaysOverdue = daysFromDue
+    } else {
+        data.DaysUntilDue = -daysFromDue
+    }
+    return data
+}
+
+// Select returns the active rule with the latest offset that has been
+// reached daysFromDue days after the due date. Earlier steps that were
+// missed, for instance while the job was down, are not sent late.
+func Select(rules []models.ReminderRule, daysFromDue int) (models.ReminderRule, bool) {
+    var best models.ReminderRule
+    found := false
+    for _, r := range rules {
+        if !r.Active || r.OffsetDays > daysFromDue {
+            continue
+        }
+        if !found || r.OffsetDays > best.OffsetDays {
+            best, found = r, true
+        }
+    }
+    return best, found
+}
+
+// Validate checks that the templates of a rule compile.
+func Validate(rule models.ReminderRule) error {
+    subject, body := source(rule)
+    if _, err := templates.ParseText("subject", subject); err != nil {
+        return err
+    }
+    _, err := templates.Parse("body", body)
+    return err
+}
+
+// Render executes the templates of a rule for one invoice.
+func Render(rule models.ReminderRule, data Data) (Notice, error) {
+    subject, body := source(rule)
+
+    st, err := templates.ParseText("subject", subject)
+    if err != nil {
+        return Notice{}, err
+    }
+    var s strings.Builder
+    err = st.Execute(&s, data)
+    if err != nil {
+        return Notice{}, err
+    }
+
+    bt, err := templates.Parse("body", body)
+    if err != nil {
+        return Notice{}, err
+    }
+    var b bytes.Buffer
+    err = bt.Execute(&b, data)
+    if err != nil {
+        return Notice{}, err
+    }
+
+    return Notice{
+        To:      data.Customer.Email,
+        Subject: strings.TrimSpace(s.String()),
+        HTML:    b.String(),
+    }, nil
+}
+
+func source(rule models.ReminderRule) (subject, body string) {
+    subject, body = rule.Subject, rule.Body
+    if subject == "" {
+        subject = DefaultSubject
+    }
+    if body == "" {
+        body = defaultBody
+    }
+    return subject, body
+}
\ No newline at end of file
@@ -0,0 +1,152 @@
+package dunning
+
+import (
+	"strings"
+	"testing"
+
+	"invoice-system/internal/models"
+	"invoice-system/internal/templates"
+)
+
+func TestSelect(t *testing.T) {
+    rules := []models.ReminderRule{
+        {ID: 1, OffsetDays: -3, Active: true},
+        {ID: 2, OffsetDays: 0, Active: true},
+        {ID: 3, OffsetDays: 7, Active: true},
+        {ID: 4, OffsetDays: 14, Active: false},
+        {ID: 5, OffsetDays: 30, Active: true},
+    }
+    tests := []struct {
+        daysFromDue int
+        wantID      int
+    }{
+        {-10, 0},
+        {-3, 1},
+        {-1, 1},
+        {0, 2},
+        {6, 2},
+        {7, 3},
+        // The inactive step is skipped and the previous one stays current
+        {20, 3},
+        // Missed steps are not sent late; only the latest reached one is
+        {45, 5},
+    }
+    for _, tt := range tests {
+        got, ok := Select(rules, tt.daysFromDue)
+        if tt.wantID == 0 {
+            if ok {
+                t.Errorf("Select(%d) = rule %d, want none", tt.daysFromDue, got.ID)
+            }
+            continue
+        }
+        if !ok || got.ID != tt.wantID {
+            t.Errorf("Select(%d) = rule %d (%v), want rule %d", tt.daysFromDue, got.ID, ok, tt.wantID)
+        }
+    }
+
+    if _, ok := Select(nil, 10); ok {
+        t.Error("Select without rules found a rule")
+    }
+}
+
+func TestNewData(t *testing.T) {
+    tests := []struct {
+        daysFromDue       int
+        overdue, untilDue int
+    }{
+        {-5, 0, 5},
+        {0, 0, 0},
+        {3, 3, 0},
+    }
+    for _, tt := range tests {
+        d := NewData(templates.Data{}, tt.daysFromDue)
+        if d.DaysFromDue != tt.daysFromDue || d.DaysOverdue != tt.overdue || d.DaysUntilDue != tt.untilDue {
+            t.Errorf("NewData(%d) = %d/%d/%d, want %d/%d/%d", tt.daysFromDue,
+                d.DaysFromDue, d.DaysOverdue, d.DaysUntilDue, tt.daysFromDue, tt.overdue, tt.untilDue)
+        }
+    }
+}
+
+func reminderData(daysFromDue int) Data {
+    var d templates.Data
+    d.Invoice.InvoiceNumber = "INV-2024-0042"
+    d.Invoice.DueDate = "2024-05-31"
+    d.Invoice.Currency = "IDR"
+    d.Invoice.BalanceDue = 150000000
+    d.Customer.Name = "Toko <Maju> & Sons"
+    d.Customer.Email = "finance@maju.example"
+    d.Company.LegalName = "PT Contoh Indonesia"
+    return NewData(d, daysFromDue)
+}
+
+func TestRenderDefault(t *testing.T) {
+    tests := []struct {
+        daysFromDue int
+        want        string
+    }{
+        {-3, "is due in 3 days, on 31 May 2024"},
+        {-1, "is due in 1 day, on 31 May 2024"},
+        {0, "is due today"},
+        {1, "due on 31 May 2024, is 1 day overdue"},
+        {10, "is 10 days overdue"},
+    }
+    for _, tt := range tests {
+        n, err := Render(models.ReminderRule{}, reminderData(tt.daysFromDue))
+        if err != nil {
+            t.Fatalf("Render(%d) error = %v", tt.daysFromDue, err)
+        }
+        if !strings.Contains(n.HTML, tt.want) {
+            t.Errorf("Render(%d) body does not contain %q:\n%s", tt.daysFromDue, tt.want, n.HTML)
+        }
+        if n.To != "finance@maju.example" || n.Subject != "Payment reminder: invoice INV-2024-0042" {
+            t.Errorf("Render(%d) = to %q subject %q", tt.daysFromDue, n.To, n.Subject)
+        }
+        if !strings.Contains(n.HTML, "IDR 1,500,000.00") {
+            t.Errorf("Render(%d) body does not show the balance", tt.daysFromDue)
+        }
+        if !strings.Contains(n.HTML, "Toko &lt;Maju&gt; &amp; Sons") {
+            t.Errorf("Render(%d) body does not escape the customer name", tt.daysFromDue)
+        }
+    }
+}
+
+func TestRenderRule(t *testing.T) {
+    rule := models.ReminderRule{
+        Subject: "  {{.DaysOverdue}} days late: {{.Customer.Name}}  ",
+        Body:    "<p>{{.Invoice.InvoiceNumber}} for {{.Customer.Name}}</p>",
+    }
+    n, err := Render(rule, reminderData(7))
+    if err != nil {
+        t.Fatal(err)
+    }
+    // The subject is plain text: trimmed, not HTML escaped
+    if n.Subject != "7 days late: Toko <Maju> & Sons" {
+        t.Errorf("Subject = %q", n.Subject)
+    }
+    if n.HTML != "<p>INV-2024-0042 for Toko &lt;Maju&gt; &amp; Sons</p>" {
+        t.Errorf("HTML = %q", n.HTML)
+    }
+}
+
+func TestTemplateErrors(t *testing.T) {
+    tests := []struct {
+        name        string
+        rule        models.ReminderRule
+        invalid     bool
+        renderFails bool
+    }{
+        {"built-in", models.ReminderRule{}, false, false},
+        {"unclosed subject", models.ReminderRule{Subject: "{{.Invoice.InvoiceNumber"}, true, true},
+        {"unknown function in body", models.ReminderRule{Body: "{{shout .Customer.Name}}"}, true, true},
+        {"unbalanced body", models.ReminderRule{Body: "{{if .DaysOverdue}}late"}, true, true},
+        {"unknown field", models.ReminderRule{Body: "{{.Customer.Nickname}}"}, false, true},
+    }
+    for _, tt := range tests {
+        if err := Validate(tt.rule); (err != nil) != tt.invalid {
+            t.Errorf("%s: Validate = %v, want error %v", tt.name, err, tt.invalid)
+        }
+        if _, err := Render(tt.rule, reminderData(3)); (err != nil) != tt.renderFails {
+            t.Errorf("%s: Render error = %v, want error %v", tt.name, err, tt.renderFails)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,41 @@
+// Package fx looks up and loads the exchange rates used to convert
+// documents into the base currency.
+package fx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"invoice-system/internal/money"
+)
+
+// BaseCurrency is the currency reports are converted into. It is set at
+// start-up from BASE_CURRENCY.
+var BaseCurrency = "IDR"
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+type queryer interface {
+    QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// RateOn returns the latest rate of currency published on or before date
+// (YYYY-MM-DD). The base currency is always quoted at one.
+func RateOn(q queryer, currency, date string) (money.ExchangeRate, error) {
+    if currency == BaseCurrency {
+        return money.One, nil
+    }
+
+    var rate money.ExchangeRate
+    err := q.QueryRow(`
+        SELECT rate FROM exchange_rates
+        WHERE currency = ? AND rate_date <= ?
+        ORDER BY rate_date DESC
+        LIMIT 1
+    `, currency, date).Scan(&rate)
+    if err == sql.ErrNoRows {
+        return 0, fmt.Errorf("%w for %s on %s", ErrRateNotFound, currency, date)
+    }
+    return rate, err
+}
\ No newline at end of file
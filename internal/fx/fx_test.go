@@ -0,0 +1,101 @@
+package fx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"invoice-system/internal/money"
+)
+
+// rateTable is a stand-in for the exchange_rates table. It answers the
+// lookup RateOn makes by returning the latest rate on or before the date.
+type rateTable map[string]map[string]string
+
+func (t rateTable) Open(string) (driver.Conn, error) { return rateConn{t}, nil }
+
+type rateConn struct{ t rateTable }
+
+func (c rateConn) Prepare(query string) (driver.Stmt, error) {
+    if !strings.Contains(query, "rate_date <= ?") || !strings.Contains(query, "ORDER BY rate_date DESC") {
+        return nil, errors.New("unexpected query: " + query)
+    }
+    return rateStmt{c.t}, nil
+}
+func (c rateConn) Close() error              { return nil }
+func (c rateConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type rateStmt struct{ t rateTable }
+
+func (s rateStmt) Close() error  { return nil }
+func (s rateStmt) NumInput() int { return 2 }
+func (s rateStmt) Exec([]driver.Value) (driver.Result, error) {
+    return nil, errors.New("not supported")
+}
+func (s rateStmt) Query(args []driver.Value) (driver.Rows, error) {
+    currency, date := args[0].(string), args[1].(string)
+    var latest string
+    for d := range s.t[currency] {
+        if d <= date && d > latest {
+            latest = d
+        }
+    }
+    rows := &rateRows{}
+    if latest != "" {
+        rows.rate = []string{s.t[currency][latest]}
+    }
+    return rows, nil
+}
+
+type rateRows struct{ rate []string }
+
+func (r *rateRows) Columns() []string { return []string{"rate"} }
+func (r *rateRows) Close() error      { return nil }
+func (r *rateRows) Next(dest []driver.Value) error {
+    if len(r.rate) == 0 {
+        return io.EOF
+    }
+    dest[0], r.rate = []byte(r.rate[0]), r.rate[1:]
+    return nil
+}
+
+func TestRateOn(t *testing.T) {
+    sql.Register("fxtest", rateTable{
+        "USD": {"2024-01-02": "15500.00000000", "2024-01-05": "15600.00000000"},
+        "EUR": {"2024-01-03": "16800.00000000"},
+    })
+    db, err := sql.Open("fxtest", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+
+    tests := []struct {
+        currency string
+        date     string
+        want     money.ExchangeRate
+        wantErr  error
+    }{
+        {"USD", "2024-01-02", 1550000000000, nil},
+        {"USD", "2024-01-04", 1550000000000, nil},
+        {"USD", "2024-01-05", 1560000000000, nil},
+        {"USD", "2024-12-31", 1560000000000, nil},
+        {"EUR", "2024-01-02", 0, ErrRateNotFound},
+        {"JPY", "2024-01-05", 0, ErrRateNotFound},
+        // The base currency never needs a stored rate
+        {"IDR", "2000-01-01", money.One, nil},
+    }
+    for _, tt := range tests {
+        got, err := RateOn(db, tt.currency, tt.date)
+        if !errors.Is(err, tt.wantErr) {
+            t.Errorf("RateOn(%s, %s) error = %v, want %v", tt.currency, tt.date, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("RateOn(%s, %s) = %s, want %s", tt.currency, tt.date, got, tt.want)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,126 @@
+package fx
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+)
+
+// ParseCSV reads rates from CSV with a currency,rate_date,rate header.
+func ParseCSV(r io.Reader) ([]models.ExchangeRate, error) {
+    records, err := csv.NewReader(r).ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    if len(records) == 0 {
+        return nil, nil
+    }
+
+    col := map[string]int{}
+    for i, name := range records[0] {
+        col[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+    for _, name := range []string{"currency", "rate_date", "rate"} {
+        if _, ok := col[name]; !ok {
+            return nil, fmt.Errorf("csv header is missing %q", name)
+        }
+    }
+
+    var rates []models.ExchangeRate
+    for line, rec := range records[1:] {
+        rate, err := money.ParseExchangeRate(rec[col["rate"]])
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %v", line+2, err)
+        }
+        rates = append(rates, models.ExchangeRate{
+            Currency: strings.ToUpper(strings.TrimSpace(rec[col["currency"]])),
+            RateDate: strings.TrimSpace(rec[col["rate_date"]]),
+            Rate:     rate,
+        })
+    }
+    return rates, validateRates(rates)
+}
+
+// ParseJSON reads rates from a JSON array of exchange rate objects.
+func ParseJSON(r io.Reader) ([]models.ExchangeRate, error) {
+    var rates []models.ExchangeRate
+    err := json.NewDecoder(r).Decode(&rates)
+    if err != nil {
+        return nil, err
+    }
+    for i := range rates {
+        rates[i].Currency = strings.ToUpper(rates[i].Currency)
+    }
+    return rates, validateRates(rates)
+}
+
+// LoadFile parses a .csv or .json rates file.
+func LoadFile(path string) ([]models.ExchangeRate, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".csv":
+        return ParseCSV(f)
+    case ".json":
+        return ParseJSON(f)
+    }
+    return nil, fmt.Errorf("unsupported rates file %s", path)
+}
+
+// Import stores rates, replacing any rate already recorded for the same
+// currency and date.
+func Import(db *sql.DB, rates []models.ExchangeRate, source string) error {
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+
+    for _, r := range rates {
+        _, err = tx.Exec(`
+            INSERT INTO exchange_rates (currency, rate_date, rate, source)
+            VALUES (?, ?, ?, ?)
+            ON DUPLICATE KEY UPDATE rate = VALUES(rate), source = VALUES(source)
+        `, r.Currency, r.RateDate, r.Rate, source)
+        if err != nil {
+            tx.Rollback()
+            return err
+        }
+    }
+    return tx.Commit()
+}
+
+// validateRates rejects malformed rates and a second rate for the same
+// currency and date, which Import would silently let win.
+func validateRates(rates []models.ExchangeRate) error {
+    seen := map[string]int{}
+    for i, r := range rates {
+        if len(r.Currency) != 3 {
+            return fmt.Errorf("rate %d: invalid currency %q", i+1, r.Currency)
+        }
+        if _, err := time.Parse("2006-01-02", r.RateDate); err != nil {
+            return fmt.Errorf("rate %d: rate_date must be YYYY-MM-DD", i+1)
+        }
+        if r.Rate <= 0 {
+            return fmt.Errorf("rate %d: rate must be positive", i+1)
+        }
+        key := r.Currency + " " + r.RateDate
+        if first, ok := seen[key]; ok {
+            return fmt.Errorf("rate %d: duplicates rate %d for %s on %s", i+1, first, r.Currency, r.RateDate)
+        }
+        seen[key] = i + 1
+    }
+    return nil
+}
\ No newline at end of file
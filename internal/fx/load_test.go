@@ -0,0 +1,123 @@
+package fx
+
+import (
+	"strings"
+	"testing"
+
+	"invoice-system/internal/models"
+)
+
+func TestParseCSV(t *testing.T) {
+    tests := []struct {
+        name    string
+        in      string
+        want    []models.ExchangeRate
+        wantErr bool
+    }{
+        {
+            name: "columns in any order",
+            in:   "Rate,currency,RATE_DATE\n15500.5,usd,2024-01-02\n16800,EUR,2024-01-02\n",
+            want: []models.ExchangeRate{
+                {Currency: "USD", RateDate: "2024-01-02", Rate: 1550050000000},
+                {Currency: "EUR", RateDate: "2024-01-02", Rate: 1680000000000},
+            },
+        },
+        {name: "empty file", in: ""},
+        {name: "missing column", in: "currency,rate\nUSD,15500\n", wantErr: true},
+        {name: "short row", in: "currency,rate_date,rate\nUSD,2024-01-02\n", wantErr: true},
+        {name: "bad rate", in: "currency,rate_date,rate\nUSD,2024-01-02,abc\n", wantErr: true},
+        {name: "too many decimals", in: "currency,rate_date,rate\nUSD,2024-01-02,1.123456789\n", wantErr: true},
+        {name: "bad date", in: "currency,rate_date,rate\nUSD,02/01/2024,15500\n", wantErr: true},
+        {name: "bad currency", in: "currency,rate_date,rate\nUS,2024-01-02,15500\n", wantErr: true},
+        {name: "zero rate", in: "currency,rate_date,rate\nUSD,2024-01-02,0\n", wantErr: true},
+        {name: "duplicate", in: "currency,rate_date,rate\nUSD,2024-01-02,15500\nusd,2024-01-02,15600\n", wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := ParseCSV(strings.NewReader(tt.in))
+        if (err != nil) != tt.wantErr {
+            t.Errorf("%s: ParseCSV error = %v, want error %v", tt.name, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && !equalRates(got, tt.want) {
+            t.Errorf("%s: ParseCSV = %+v, want %+v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestParseJSON(t *testing.T) {
+    tests := []struct {
+        name    string
+        in      string
+        want    []models.ExchangeRate
+        wantErr bool
+    }{
+        {
+            name: "numbers and strings",
+            in:   `[{"currency":"usd","rate_date":"2024-01-02","rate":15500.5},{"currency":"SGD","rate_date":"2024-01-02","rate":"11600"}]`,
+            want: []models.ExchangeRate{
+                {Currency: "USD", RateDate: "2024-01-02", Rate: 1550050000000},
+                {Currency: "SGD", RateDate: "2024-01-02", Rate: 1160000000000},
+            },
+        },
+        {name: "malformed", in: `{"currency":"USD"}`, wantErr: true},
+        {name: "missing date", in: `[{"currency":"USD","rate":15500}]`, wantErr: true},
+        {name: "negative rate", in: `[{"currency":"USD","rate_date":"2024-01-02","rate":-1}]`, wantErr: true},
+        {
+            name:    "duplicate",
+            in:      `[{"currency":"USD","rate_date":"2024-01-02","rate":15500},{"currency":"usd","rate_date":"2024-01-02","rate":15600}]`,
+            wantErr: true,
+        },
+    }
+    for _, tt := range tests {
+        got, err := ParseJSON(strings.NewReader(tt.in))
+        if (err != nil) != tt.wantErr {
+            t.Errorf("%s: ParseJSON error = %v, want error %v", tt.name, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && !equalRates(got, tt.want) {
+            t.Errorf("%s: ParseJSON = %+v, want %+v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestValidateRates(t *testing.T) {
+    tests := []struct {
+        name    string
+        rates   []models.ExchangeRate
+        wantErr bool
+    }{
+        {"valid", []models.ExchangeRate{{Currency: "USD", RateDate: "2024-01-02", Rate: 1}}, false},
+        {"same currency on other days", []models.ExchangeRate{
+            {Currency: "USD", RateDate: "2024-01-02", Rate: 1},
+            {Currency: "USD", RateDate: "2024-01-03", Rate: 1},
+        }, false},
+        {"same day in other currencies", []models.ExchangeRate{
+            {Currency: "USD", RateDate: "2024-01-02", Rate: 1},
+            {Currency: "EUR", RateDate: "2024-01-02", Rate: 1},
+        }, false},
+        {"duplicate", []models.ExchangeRate{
+            {Currency: "USD", RateDate: "2024-01-02", Rate: 1},
+            {Currency: "USD", RateDate: "2024-01-02", Rate: 2},
+        }, true},
+        {"currency too long", []models.ExchangeRate{{Currency: "USDX", RateDate: "2024-01-02", Rate: 1}}, true},
+        {"impossible date", []models.ExchangeRate{{Currency: "USD", RateDate: "2024-02-30", Rate: 1}}, true},
+        {"zero rate", []models.ExchangeRate{{Currency: "USD", RateDate: "2024-01-02"}}, true},
+    }
+    for _, tt := range tests {
+        if err := validateRates(tt.rates); (err != nil) != tt.wantErr {
+            t.Errorf("%s: validateRates = %v, want error %v", tt.name, err, tt.wantErr)
+        }
+    }
+}
+
+func equalRates(a, b []models.ExchangeRate) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i].Currency != b[i].Currency || a[i].RateDate != b[i].RateDate || a[i].Rate != b[i].Rate {
+            return false
+        }
+    }
+    return true
+}
\ No newline at end of file
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+    errAttachmentTooLarge = errors.New("attachment too large")
+    errNoFile             = errors.New("a multipart file field named file is required")
+    errEmptyFile          = errors.New("file is empty")
+)
+
+// MaxAttachmentSize limits uploaded files, in bytes.
+var MaxAttachmentSize int64 = 10 << 20
+
+// attachmentTypes are the sniffed content types accepted as attachments.
+var attachmentTypes = map[string]bool{
+    "application/pdf": true,
+    "application/zip": true,
+    "image/gif":       true,
+    "image/jpeg":      true,
+    "image/png":       true,
+    "image/webp":      true,
+    "text/plain":      true,
+}
+
+// officeTypes names the Office formats, which sniff as plain zip files.
+var officeTypes = map[string]string{
+    ".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+    ".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+    ".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+    ".odt":  "application/vnd.oasis.opendocument.text",
+    ".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+}
+
+func UploadInvoiceAttachment(w http.ResponseWriter, r *http.Request) {
+    uploadAttachment(w, r, audit.EntityInvoice)
+}
+
+func UploadCustomerAttachment(w http.ResponseWriter, r *http.Request) {
+    uploadAttachment(w, r, audit.EntityCustomer)
+}
+
+func GetInvoiceAttachments(w http.ResponseWriter, r *http.Request) {
+    listAttachments(w, r, audit.EntityInvoice)
+}
+
+func GetCustomerAttachments(w http.ResponseWriter, r *http.Request) {
+    listAttachments(w, r, audit.EntityCustomer)
+}
+
+// uploadAttachment stores the "file" part of a multipart upload against an
+// invoice or customer that has not been deleted.
+func uploadAttachment(w http.ResponseWriter, r *http.Request, entity string) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    if storage.Default == nil {
+        http.Error(w, "Attachment storage is not configured", http.StatusServiceUnavailable)
+        return
+    }
+
+    table, notFound := "invoices", "Invoice not found"
+    if entity == audit.EntityCustomer {
+        table, notFound = "customers", "Customer not found"
+    }
+    var exists int
+    err = database.DB.QueryRow("SELECT 1 FROM "+table+" WHERE id = ? AND deleted_at IS NULL", id).Scan(&exists)
+    if err == sql.ErrNoRows {
+        http.Error(w, notFound, http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    // Leave room for the multipart headers around the file
+    r.Body = http.MaxBytesReader(w, r.Body, MaxAttachmentSize+1<<20)
+    name, data, err := readUploadedFile(r)
+    if err != nil {
+        if _, ok := err.(*http.MaxBytesError); ok {
+            err = errAttachmentTooLarge
+        }
+        if err == errAttachmentTooLarge {
+            http.Error(w, fmt.Sprintf("Attachment exceeds %d bytes", MaxAttachmentSize), http.StatusRequestEntityTooLarge)
+        } else {
+            http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        }
+        return
+    }
+
+    contentType := sniffAttachmentType(name, data)
+    if contentType == "" {
+        http.Error(w, "Unsupported attachment type", http.StatusUnsupportedMediaType)
+        return
+    }
+
+    sum := sha256.Sum256(data)
+    a := models.Attachment{
+        EntityType:  entity,
+        EntityID:    id,
+        FileName:    name,
+        ContentType: contentType,
+        Size:        int64(len(data)),
+        SHA256:      hex.EncodeToString(sum[:]),
+        StorageKey:  fmt.Sprintf("%s/%d/%s", entity, id, randomKey()),
+        UploadedBy:  actorFromRequest(r),
+    }
+
+    err = storage.Default.Put(a.StorageKey, a.ContentType, data)
+    if err != nil {
+        log.Printf("storing attachment %s: %v", a.StorageKey, err)
+        http.Error(w, "Storage error", http.StatusBadGateway)
+        return
+    }
+
+    err = insertAttachment(r, &a)
+    if err != nil {
+        // Do not leave an object behind that no row points at
+        if err := storage.Default.Delete(a.StorageKey); err != nil {
+            log.Printf("removing orphaned attachment %s: %v", a.StorageKey, err)
+        }
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(a)
+}
+
+// readUploadedFile returns the name and content of the "file" part,
+// reading at most one byte past MaxAttachmentSize.
+func readUploadedFile(r *http.Request) (string, []byte, error) {
+    reader, err := r.MultipartReader()
+    if err != nil {
+        return "", nil, errNoFile
+    }
+    for {
+        part, err := reader.NextPart()
+        if err == io.EOF {
+            return "", nil, errNoFile
+        } else if err != nil {
+            return "", nil, err
+        }
+        if part.FormName() != "file" {
+            part.Close()
+            continue
+        }
+
+        data, err := io.ReadAll(io.LimitReader(part, MaxAttachmentSize+1))
+        part.Close()
+        if err != nil {
+            return "", nil, err
+        }
+        if int64(len(data)) > MaxAttachmentSize {
+            return "", nil, errAttachmentTooLarge
+        }
+        if len(data) == 0 {
+            return "", nil, errEmptyFile
+        }
+        return cleanFileName(part.FileName()), data, nil
+    }
+}
+
+// sniffAttachmentType detects the content type from the data, returning ""
+// for types that are not accepted. CSV and Office files are told apart
+// from plain text and zip files by their extension.
+func sniffAttachmentType(name string, data []byte) string {
+    detected := http.DetectContentType(data)
+    mediaType, _, err := mime.ParseMediaType(detected)
+    if err != nil || !attachmentTypes[mediaType] {
+        return ""
+    }
+
+    ext := strings.ToLower(path.Ext(name))
+    switch {
+    case mediaType == "application/zip" && officeTypes[ext] != "":
+        return officeTypes[ext]
+    case mediaType == "text/plain" && ext == ".csv":
+        return strings.Replace(detected, "text/plain", "text/csv", 1)
+    }
+    return detected
+}
+
+// cleanFileName keeps the base name of an uploaded file, which browsers
+// may send with a client path.
+func cleanFileName(name string) string {
+    name = strings.ReplaceAll(name, "\\", "/")
+    name = strings.TrimSpace(path.Base(name))
+    if name == "." || name == "/" || name == "" {
+        return "attachment"
+    }
+    for len(name) > 255 {
+        _, size := utf8.DecodeLastRuneInString(name)
+        name = name[:len(name)-size]
+    }
+    return name
+}
+
+func randomKey() string {
+    b := make([]byte, 16)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+func insertAttachment(r *http.Request, a *models.Attachment) error {
+    tx, err := database.DB.Begin()
+    if err != nil {
+        return err
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO attachments (entity_type, entity_id, file_name, content_type, size, sha256, storage_key, uploaded_by)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    `, a.EntityType, a.EntityID, a.FileName, a.ContentType, a.Size, a.SHA256, a.StorageKey, a.UploadedBy)
+    if err == nil {
+        var id int64
+        id, _ = res.LastInsertId()
+        *a, err = loadAttachment(tx, int(id))
+    }
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityAttachment, a.ID, audit.ActionCreate, nil, a)
+    }
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+func listAttachments(w http.ResponseWriter, r *http.Request, entity string) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query(`
+        SELECT `+attachmentColumns+`
+        FROM attachments
+        WHERE entity_type = ? AND entity_id = ?
+        ORDER BY id
+    `, entity, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    attachments := []models.Attachment{}
+    for rows.Next() {
+        var a models.Attachment
+        err := scanAttachment(rows, &a)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        attachments = append(attachments, a)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(attachments)
+}
+
+// DownloadAttachment streams the file with the sniffed content type. The
+// nosniff header stops browsers from second-guessing it.
+func DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    if storage.Default == nil {
+        http.Error(w, "Attachment storage is not configured", http.StatusServiceUnavailable)
+        return
+    }
+
+    a, err := loadAttachment(database.DB, id)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Attachment not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    body, err := storage.Default.Get(a.StorageKey)
+    if err == storage.ErrNotFound {
+        http.Error(w, "Attachment content not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        log.Printf("reading attachment %s: %v", a.StorageKey, err)
+        http.Error(w, "Storage error", http.StatusBadGateway)
+        return
+    }
+    defer body.Close()
+
+    w.Header().Set("Content-Type", a.ContentType)
+    w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+    w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}))
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    io.Copy(w, body)
+}
+
+// DeleteAttachment removes the metadata and then the file. A file the
+// storage fails to delete is left behind as an orphan and logged; the
+// attachment is gone either way.
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    if storage.Default == nil {
+        http.Error(w, "Attachment storage is not configured", http.StatusServiceUnavailable)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    a, err := loadAttachment(tx, id)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        http.Error(w, "Attachment not found", http.StatusNotFound)
+        return
+    }
+    if err == nil {
+        _, err = tx.Exec("DELETE FROM attachments WHERE id = ?", id)
+    }
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityAttachment, id, audit.ActionDelete, a, nil)
+    }
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    err = storage.Default.Delete(a.StorageKey)
+    if err != nil {
+        log.Printf("orphaned attachment %s: %v", a.StorageKey, err)
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+const attachmentColumns = `
+    id, entity_type, entity_id, file_name, content_type, size, sha256, storage_key, uploaded_by, created_at
+`
+
+func scanAttachment(row rowScanner, a *models.Attachment) error {
+    return row.Scan(
+        &a.ID,
+        &a.EntityType,
+        &a.EntityID,
+        &a.FileName,
+        &a.ContentType,
+        &a.Size,
+        &a.SHA256,
+        &a.StorageKey,
+        &a.UploadedBy,
+        &a.CreatedAt,
+    )
+}
+
+func loadAttachment(q queryer, id int) (models.Attachment, error) {
+    var a models.Attachment
+    err := scanAttachment(q.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", id), &a)
+    return a, err
+}
\ No newline at end of file
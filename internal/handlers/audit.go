@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+)
+
+// GetAuditLog lists audit entries oldest first, filtered by entity type,
+// entity id and request id.
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+    page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+    if page < 1 {
+        page = 1
+    }
+    limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+    if limit < 1 {
+        limit = 10
+    }
+    offset := (page - 1) * limit
+
+    query := `
+        SELECT id, request_id, actor, entity_type, entity_id, action, before_data, after_data, created_at
+        FROM audit_log
+    `
+    var args []interface{}
+    clauses := []string{}
+
+    if entity := r.URL.Query().Get("entity"); entity != "" {
+        clauses = append(clauses, "entity_type = ?")
+        args = append(args, entity)
+    }
+    if v := r.URL.Query().Get("id"); v != "" {
+        id, err := strconv.Atoi(v)
+        if err != nil {
+            http.Error(w, "Invalid ID", http.StatusBadRequest)
+            return
+        }
+        clauses = append(clauses, "entity_id = ?")
+        args = append(args, id)
+    }
+    if requestID := r.URL.Query().Get("request_id"); requestID != "" {
+        clauses = append(clauses, "request_id = ?")
+        args = append(args, requestID)
+    }
+
+    if len(clauses) > 0 {
+        query += " WHERE " + joinClauses(clauses, " AND ")
+    }
+    query += " ORDER BY id LIMIT ? OFFSET ?"
+    args = append(args, limit, offset)
+
+    rows, err := database.DB.Query(query, args...)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    entries := []models.AuditEntry{}
+    for rows.Next() {
+        var e models.AuditEntry
+        var before, after []byte
+        err := rows.Scan(&e.ID, &e.RequestID, &e.Actor, &e.EntityType, &e.EntityID, &e.Action, &before, &after, &e.CreatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        e.Before = before
+        e.After = after
+        entries = append(entries, e)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(entries)
+}
+
+// recordAudit logs a change made on behalf of the request, inside the
+// transaction that makes it.
+func recordAudit(tx *sql.Tx, r *http.Request, entity string, id int, action string, before, after interface{}) error {
+    return audit.Record(tx, audit.Entry{
+        RequestID:  audit.RequestID(r.Context()),
+        Actor:      actorFromRequest(r),
+        EntityType: entity,
+        EntityID:   id,
+        Action:     action,
+        Before:     before,
+        After:      after,
+    })
+}
+
+// auditInvoiceChange records the invoice as it now stands in tx against
+// its detail before the change.
+func auditInvoiceChange(tx *sql.Tx, r *http.Request, id int, action string, before interface{}) error {
+    after, err := loadInvoiceDetail(tx, id)
+    if err != nil {
+        return err
+    }
+    return recordAudit(tx, r, audit.EntityInvoice, id, action, before, after)
+}
+
+// auditInvoiceJob is auditInvoiceChange for background jobs, which have no
+// request to take the actor and request id from.
+func auditInvoiceJob(tx *sql.Tx, id int, action string, before interface{}) error {
+    after, err := loadInvoiceDetail(tx, id)
+    if err != nil {
+        return err
+    }
+    return audit.Record(tx, audit.Entry{
+        Actor:      audit.SystemActor,
+        EntityType: audit.EntityInvoice,
+        EntityID:   id,
+        Action:     action,
+        Before:     before,
+        After:      after,
+    })
+}
\ No newline at end of file
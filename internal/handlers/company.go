@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+)
+
+func GetCompanyProfile(w http.ResponseWriter, r *http.Request) {
+    c, err := loadCompanyProfile(database.DB)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(c)
+}
+
+func UpdateCompanyProfile(w http.ResponseWriter, r *http.Request) {
+    var req models.CompanyProfile
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    _, err = database.DB.Exec(`
+        INSERT INTO company_profile
+            (id, legal_name, address, tax_id, email, phone, logo_url, bank_details, footer_terms)
+        VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE
+            legal_name = VALUES(legal_name), address = VALUES(address),
+            tax_id = VALUES(tax_id), email = VALUES(email), phone = VALUES(phone),
+            logo_url = VALUES(logo_url), bank_details = VALUES(bank_details),
+            footer_terms = VALUES(footer_terms)
+    `, req.LegalName, req.Address, req.TaxID, req.Email, req.Phone, req.LogoURL, req.BankDetails, req.FooterTerms)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    req.UpdatedAt = time.Now()
+    json.NewEncoder(w).Encode(req)
+}
+
+// loadCompanyProfile returns the stored profile, or the SELLER_* settings
+// until one has been saved.
+func loadCompanyProfile(q queryer) (models.CompanyProfile, error) {
+    var c models.CompanyProfile
+    err := q.QueryRow(`
+        SELECT legal_name, address, tax_id, email, phone, logo_url, bank_details, footer_terms, updated_at
+        FROM company_profile
+        WHERE id = 1
+    `).Scan(&c.LegalName, &c.Address, &c.TaxID, &c.Email, &c.Phone, &c.LogoURL, &c.BankDetails, &c.FooterTerms, &c.UpdatedAt)
+    if err == sql.ErrNoRows {
+        return models.CompanyProfile{
+            LegalName:   os.Getenv("SELLER_NAME"),
+            Address:     os.Getenv("SELLER_ADDRESS"),
+            TaxID:       os.Getenv("SELLER_TAX_ID"),
+            Email:       os.Getenv("SELLER_EMAIL"),
+            BankDetails: os.Getenv("PAYMENT_INSTRUCTIONS"),
+        }, nil
+    }
+    return c, err
+}
\ No newline at end of file
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/numbering"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+    errInvoiceNotCreditable = errors.New("invoice cannot be credited")
+    errCreditExceedsLine    = errors.New("credited quantity exceeds invoiced quantity")
+    errNothingToCredit      = errors.New("nothing left to credit")
+    errRefundExceedsCredit  = errors.New("refund exceeds refundable amount")
+)
+
+type creditNoteLineInput struct {
+    InvoiceItemID int `json:"invoice_item_id" validate:"required"`
+    Quantity      int `json:"quantity" validate:"required,min=1"`
+}
+
+func GetInvoiceCreditNotes(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query("SELECT "+creditNoteColumns+" FROM credit_notes c WHERE c.invoice_id = ? ORDER BY c.id", id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    notes := []models.CreditNote{}
+    for rows.Next() {
+        var cn models.CreditNote
+        err := scanCreditNote(rows, &cn)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        notes = append(notes, cn)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(notes)
+}
+
+func GetCreditNote(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    writeCreditNoteDetail(w, id, http.StatusOK)
+}
+
+// CreateCreditNote credits some or all of the lines of an invoice. Without
+// explicit lines every quantity not yet credited is credited.
+func CreateCreditNote(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    invoiceID, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req struct {
+        IssueDate string                `json:"issue_date"`
+        Reason    string                `json:"reason" validate:"required"`
+        Items     []creditNoteLineInput `json:"items" validate:"omitempty,dive"`
+    }
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    if req.IssueDate == "" {
+        req.IssueDate = time.Now().Format("2006-01-02")
+    } else if _, err := time.Parse("2006-01-02", req.IssueDate); err != nil {
+        http.Error(w, "Validation error: issue_date must be YYYY-MM-DD", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    var creditNoteID int
+    before, err := loadInvoiceDetail(tx, invoiceID)
+    if err == sql.ErrNoRows {
+        err = errInvoiceNotFound
+    }
+    if err == nil {
+        creditNoteID, err = createCreditNote(tx, invoiceID, req.IssueDate, req.Reason, req.Items, actorFromRequest(r))
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, invoiceID, audit.ActionCredit, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeCreditNoteError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    writeCreditNoteDetail(w, creditNoteID, http.StatusCreated)
+}
+
+// RefundCreditNote pays a credited amount back to the customer. Refunds are
+// recorded in the payments ledger as negative payments on the invoice.
+func RefundCreditNote(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req models.Payment
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    if req.PaidAt == "" {
+        req.PaidAt = time.Now().Format("2006-01-02")
+    } else if _, err := time.Parse("2006-01-02", req.PaidAt); err != nil {
+        http.Error(w, "Validation error: paid_at must be YYYY-MM-DD", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    cn, err := loadCreditNote(tx, id)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        http.Error(w, "Credit note not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    var paymentID int
+    before, err := loadInvoiceDetail(tx, cn.InvoiceID)
+    if err == nil {
+        paymentID, err = recordRefund(tx, cn, &req)
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, cn.InvoiceID, audit.ActionRefund, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeCreditNoteError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    req.ID = paymentID
+    req.InvoiceID = cn.InvoiceID
+    req.CreditNoteID = &cn.ID
+    req.Amount = -req.Amount
+    req.CreatedAt = time.Now()
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(req)
+}
+
+func createCreditNote(tx *sql.Tx, invoiceID int, issueDate, reason string, items []creditNoteLineInput, actor string) (int, error) {
+    var status models.InvoiceStatus
+    err := tx.QueryRow("SELECT status FROM invoices WHERE id = ? FOR UPDATE", invoiceID).Scan(&status)
+    if err == sql.ErrNoRows {
+        return 0, errInvoiceNotFound
+    } else if err != nil {
+        return 0, err
+    }
+    if !status.Creditable() {
+        return 0, errInvoiceNotCreditable
+    }
+
+    // Quantity still creditable per invoice line
+    rows, err := tx.Query(`
+        SELECT ii.id, ii.quantity, ii.quantity - COALESCE(SUM(cni.quantity), 0),
+            ii.price, ii.line_total, COALESCE(SUM(cni.amount), 0)
+        FROM invoice_items ii
+        LEFT JOIN credit_note_items cni ON cni.invoice_item_id = ii.id
+        WHERE ii.invoice_id = ?
+        GROUP BY ii.id, ii.quantity, ii.price, ii.line_total
+    `, invoiceID)
+    if err != nil {
+        return 0, err
+    }
+    invoiced := map[int]int{}
+    remaining := map[int]int{}
+    prices := map[int]money.Amount{}
+    lineTotals := map[int]money.Amount{}
+    credited := map[int]money.Amount{}
+    var order []int
+    for rows.Next() {
+        var lineID, qty, left int
+        var price, lineTotal, creditedAmount money.Amount
+        if err := rows.Scan(&lineID, &qty, &left, &price, &lineTotal, &creditedAmount); err != nil {
+            rows.Close()
+            return 0, err
+        }
+        invoiced[lineID] = qty
+        remaining[lineID] = left
+        prices[lineID] = price
+        lineTotals[lineID] = lineTotal
+        credited[lineID] = creditedAmount
+        order = append(order, lineID)
+    }
+    rows.Close()
+
+    if len(items) == 0 {
+        for _, lineID := range order {
+            if remaining[lineID] > 0 {
+                items = append(items, creditNoteLineInput{InvoiceItemID: lineID, Quantity: remaining[lineID]})
+            }
+        }
+        if len(items) == 0 {
+            return 0, errNothingToCredit
+        }
+    }
+
+    // Credit the tax-inclusive line total pro rata to the quantity. The
+    // credit that uses up a line takes whatever is left of its total, so
+    // rounding never leaves a cent owed.
+    var total money.Amount
+    amounts := make([]money.Amount, len(items))
+    for i, item := range items {
+        qty, ok := remaining[item.InvoiceItemID]
+        if !ok {
+            return 0, errInvoiceLineNotFound
+        }
+        if item.Quantity > qty {
+            return 0, errCreditExceedsLine
+        }
+        remaining[item.InvoiceItemID] -= item.Quantity
+        if remaining[item.InvoiceItemID] == 0 {
+            amounts[i] = lineTotals[item.InvoiceItemID] - credited[item.InvoiceItemID]
+        } else {
+            amounts[i] = lineTotals[item.InvoiceItemID].Share(money.Amount(item.Quantity), money.Amount(invoiced[item.InvoiceItemID]))
+        }
+        credited[item.InvoiceItemID] += amounts[i]
+        total += amounts[i]
+    }
+
+    issued, _ := time.Parse("2006-01-02", issueDate)
+    number, err := numbering.Next(tx, numbering.CreditNoteSequence, issued)
+    if err != nil {
+        return 0, err
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO credit_notes (credit_note_number, invoice_id, issue_date, reason, total_amount)
+        VALUES (?, ?, ?, ?, ?)
+    `, number, invoiceID, issueDate, reason, total)
+    if err != nil {
+        return 0, err
+    }
+    creditNoteID, _ := res.LastInsertId()
+
+    for i, item := range items {
+        _, err = tx.Exec(`
+            INSERT INTO credit_note_items (credit_note_id, invoice_item_id, quantity, price, amount)
+            VALUES (?, ?, ?, ?, ?)
+        `, creditNoteID, item.InvoiceItemID, item.Quantity, prices[item.InvoiceItemID], amounts[i])
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    err = settleInvoiceStatus(tx, invoiceID, actor)
+    return int(creditNoteID), err
+}
+
+// recordRefund refunds at most what is left on the credit note and never
+// more than the customer has overpaid on the invoice.
+func recordRefund(tx *sql.Tx, cn models.CreditNote, p *models.Payment) (int, error) {
+    var locked int
+    err := tx.QueryRow("SELECT id FROM invoices WHERE id = ? FOR UPDATE", cn.InvoiceID).Scan(&locked)
+    if err != nil {
+        return 0, err
+    }
+
+    inv, err := loadInvoice(tx, cn.InvoiceID)
+    if err != nil {
+        return 0, err
+    }
+    if p.Amount > cn.TotalAmount-cn.AmountRefunded || p.Amount > -inv.BalanceDue {
+        return 0, errRefundExceedsCredit
+    }
+    err = paymentExchangeRate(tx, inv, p)
+    if err != nil {
+        return 0, err
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO payments (invoice_id, credit_note_id, amount, exchange_rate, paid_at, method, reference)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, cn.InvoiceID, cn.ID, -p.Amount, p.ExchangeRate, p.PaidAt, p.Method, p.Reference)
+    if err != nil {
+        return 0, err
+    }
+    paymentID, _ := res.LastInsertId()
+    return int(paymentID), nil
+}
+
+const creditNoteColumns = `
+    c.id, c.credit_note_number, c.invoice_id, c.issue_date, c.reason, c.total_amount,
+    c.created_at, c.updated_at,
+    (SELECT COALESCE(-SUM(p.amount), 0) FROM payments p WHERE p.credit_note_id = c.id)
+`
+
+func scanCreditNote(row rowScanner, cn *models.CreditNote) error {
+    return row.Scan(
+        &cn.ID,
+        &cn.CreditNoteNumber,
+        &cn.InvoiceID,
+        &cn.IssueDate,
+        &cn.Reason,
+        &cn.TotalAmount,
+        &cn.CreatedAt,
+        &cn.UpdatedAt,
+        &cn.AmountRefunded,
+    )
+}
+
+func loadCreditNote(q queryer, id int) (models.CreditNote, error) {
+    var cn models.CreditNote
+    err := scanCreditNote(q.QueryRow("SELECT "+creditNoteColumns+" FROM credit_notes c WHERE c.id = ?", id), &cn)
+    return cn, err
+}
+
+func getCreditNoteLines(q queryer, creditNoteID int) ([]models.CreditNoteLine, error) {
+    rows, err := q.Query(`
+        SELECT cni.id, cni.credit_note_id, cni.invoice_item_id, ii.item_id, it.name,
+            cni.quantity, cni.price, cni.amount
+        FROM credit_note_items cni
+        JOIN invoice_items ii ON ii.id = cni.invoice_item_id
+        JOIN items it ON it.id = ii.item_id
+        WHERE cni.credit_note_id = ?
+        ORDER BY cni.id
+    `, creditNoteID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    lines := []models.CreditNoteLine{}
+    for rows.Next() {
+        var l models.CreditNoteLine
+        err := rows.Scan(&l.ID, &l.CreditNoteID, &l.InvoiceItemID, &l.ItemID, &l.ItemName, &l.Quantity, &l.UnitPrice, &l.LineTotal)
+        if err != nil {
+            return nil, err
+        }
+        lines = append(lines, l)
+    }
+    return lines, rows.Err()
+}
+
+func writeCreditNoteDetail(w http.ResponseWriter, id int, status int) {
+    cn, err := loadCreditNote(database.DB, id)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Credit note not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    lines, err := getCreditNoteLines(database.DB, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(models.CreditNoteDetail{CreditNote: cn, Items: lines})
+}
+
+func writeCreditNoteError(w http.ResponseWriter, err error) {
+    switch err {
+    case errInvoiceNotCreditable:
+        http.Error(w, "Only issued invoices can be credited", http.StatusConflict)
+    case errCreditExceedsLine:
+        http.Error(w, "Credited quantity exceeds the quantity left on the invoice line", http.StatusBadRequest)
+    case errNothingToCredit:
+        http.Error(w, "Invoice is already fully credited", http.StatusConflict)
+    case errRefundExceedsCredit:
+        http.Error(w, "Refund exceeds the credited amount owed to the customer", http.StatusBadRequest)
+    default:
+        writeInvoiceError(w, err)
+    }
+}
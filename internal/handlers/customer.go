@@ -3,12 +3,16 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"invoice-system/internal/audit"
 	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
 	"invoice-system/internal/models"
+	"invoice-system/internal/terms"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
@@ -16,6 +20,11 @@ import (
 
 var validate = validator.New()
 
+var (
+    errUnknownLateFeePolicy = errors.New("late_fee_policy_id does not name a late fee policy")
+    errDuplicateEmail       = errors.New("another customer already uses this email")
+)
+
 func GetCustomers(w http.ResponseWriter, r *http.Request) {
     page, _ := strconv.Atoi(r.URL.Query().Get("page"))
     if page < 1 {
@@ -27,11 +36,17 @@ func GetCustomers(w http.ResponseWriter, r *http.Request) {
     }
     offset := (page - 1) * limit
 
-    rows, err := database.DB.Query(`
-        SELECT id, name, email, address, created_at, updated_at
+    query := `
+        SELECT id, name, email, address, currency, late_fee_policy_id, payment_terms, reminders_opt_out,
+            created_at, updated_at, deleted_at
         FROM customers
-        LIMIT ? OFFSET ?
-    `, limit, offset)
+    `
+    if r.URL.Query().Get("include_deleted") != "true" {
+        query += " WHERE deleted_at IS NULL"
+    }
+    query += " LIMIT ? OFFSET ?"
+
+    rows, err := database.DB.Query(query, limit, offset)
     if err != nil {
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
@@ -41,7 +56,7 @@ func GetCustomers(w http.ResponseWriter, r *http.Request) {
     var customers []models.Customer
     for rows.Next() {
         var c models.Customer
-        err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Address, &c.CreatedAt, &c.UpdatedAt)
+        err := rows.Scan(&c.ID, &c.Name, &c.Email, &c.Address, &c.Currency, &c.LateFeePolicyID, &c.PaymentTerms, &c.RemindersOptOut, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt)
         if err != nil {
             http.Error(w, "Scan error", http.StatusInternalServerError)
             return
@@ -66,23 +81,63 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
         return
     }
+    if req.Currency == "" {
+        req.Currency = fx.BaseCurrency
+    }
+    if req.PaymentTerms != "" {
+        t, err := terms.Parse(req.PaymentTerms)
+        if err != nil {
+            http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+            return
+        }
+        req.PaymentTerms = string(t)
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    err = checkLateFeePolicy(tx, req.LateFeePolicyID)
+    if err == nil {
+        err = checkCustomerEmail(tx, req.Email, 0)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeCustomerCheckError(w, err)
+        return
+    }
 
-    res, err := database.DB.Exec(`
-        INSERT INTO customers (name, email, address)
-        VALUES (?, ?, ?)
-    `, req.Name, req.Email, req.Address)
+    res, err := tx.Exec(`
+        INSERT INTO customers (name, email, address, currency, late_fee_policy_id, payment_terms, reminders_opt_out)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, req.Name, req.Email, req.Address, req.Currency, req.LateFeePolicyID, req.PaymentTerms, req.RemindersOptOut)
     if err != nil {
+        tx.Rollback()
         http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
         return
     }
 
     id, _ := res.LastInsertId()
-    req.ID = int(id)
-    req.CreatedAt = time.Now()
-    req.UpdatedAt = time.Now()
+    customer, err := loadCustomer(tx, int(id))
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityCustomer, customer.ID, audit.ActionCreate, nil, customer)
+    }
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
 
     w.WriteHeader(http.StatusCreated)
-    json.NewEncoder(w).Encode(req)
+    json.NewEncoder(w).Encode(customer)
 }
 
 func GetCustomer(w http.ResponseWriter, r *http.Request) {
@@ -93,19 +148,7 @@ func GetCustomer(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    var customer models.Customer
-    err = database.DB.QueryRow(`
-        SELECT id, name, email, address, created_at, updated_at
-        FROM customers
-        WHERE id = ?
-    `, id).Scan(
-        &customer.ID,
-        &customer.Name,
-        &customer.Email,
-        &customer.Address,
-        &customer.CreatedAt,
-        &customer.UpdatedAt,
-    )
+    customer, err := loadCustomer(database.DB, id)
     if err == sql.ErrNoRows {
         http.Error(w, "Customer not found", http.StatusNotFound)
         return
@@ -117,6 +160,8 @@ func GetCustomer(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(customer)
 }
 
+// UpdateCustomer changes the fields present in the request body; a null
+// late_fee_policy_id clears the policy.
 func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id, err := strconv.Atoi(params["id"])
@@ -125,34 +170,107 @@ func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    var req models.Customer
-    err = json.NewDecoder(r.Body).Decode(&req)
+    var body json.RawMessage
+    err = json.NewDecoder(r.Body).Decode(&body)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    before, err := loadCustomer(tx, id)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        http.Error(w, "Customer not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    // Fields left out of the request keep their stored values. The pointers
+    // are copied so decoding cannot write through to before.
+    req := before
+    if before.LateFeePolicyID != nil {
+        policyID := *before.LateFeePolicyID
+        req.LateFeePolicyID = &policyID
+    }
+    req.DeletedAt = nil
+    err = json.Unmarshal(body, &req)
     if err != nil {
+        tx.Rollback()
         http.Error(w, "Invalid JSON", http.StatusBadRequest)
         return
     }
 
     err = validate.Struct(req)
     if err != nil {
+        tx.Rollback()
         http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
         return
     }
+    if req.Currency == "" {
+        req.Currency = fx.BaseCurrency
+    }
+    if req.PaymentTerms != "" {
+        t, err := terms.Parse(req.PaymentTerms)
+        if err != nil {
+            tx.Rollback()
+            http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+            return
+        }
+        req.PaymentTerms = string(t)
+    }
+
+    err = checkLateFeePolicy(tx, req.LateFeePolicyID)
+    if err == nil {
+        err = checkCustomerEmail(tx, req.Email, id)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeCustomerCheckError(w, err)
+        return
+    }
 
-    _, err = database.DB.Exec(`
+    _, err = tx.Exec(`
         UPDATE customers
-        SET name = ?, email = ?, address = ?, updated_at = ?
+        SET name = ?, email = ?, address = ?, currency = ?, late_fee_policy_id = ?,
+            payment_terms = ?, reminders_opt_out = ?, updated_at = ?
         WHERE id = ?
-    `, req.Name, req.Email, req.Address, time.Now(), id)
+    `, req.Name, req.Email, req.Address, req.Currency, req.LateFeePolicyID, req.PaymentTerms, req.RemindersOptOut, time.Now(), id)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    after, err := loadCustomer(tx, id)
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityCustomer, id, audit.ActionUpdate, before, after)
+    }
     if err != nil {
+        tx.Rollback()
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
     }
 
-    req.ID = id
-    req.UpdatedAt = time.Now()
-    json.NewEncoder(w).Encode(req)
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(after)
 }
 
+// DeleteCustomer hides the customer from lists and new invoices; existing
+// documents keep pointing at it.
 func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id, err := strconv.Atoi(params["id"])
@@ -161,11 +279,135 @@ func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    _, err = database.DB.Exec("DELETE FROM customers WHERE id = ?", id)
-    if err != nil {
+    err = setCustomerDeleted(r, id, true)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Customer not found", http.StatusNotFound)
+        return
+    } else if err != nil {
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
     }
 
     w.WriteHeader(http.StatusNoContent)
+}
+
+func RestoreCustomer(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    err = setCustomerDeleted(r, id, false)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Customer not found", http.StatusNotFound)
+        return
+    } else if err == errDuplicateEmail {
+        http.Error(w, "Another customer uses this email; change it before restoring", http.StatusConflict)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    GetCustomer(w, r)
+}
+
+// setCustomerDeleted soft-deletes or restores a customer, leaving one that
+// is already in the requested state alone.
+func setCustomerDeleted(r *http.Request, id int, deleted bool) error {
+    tx, err := database.DB.Begin()
+    if err != nil {
+        return err
+    }
+
+    before, err := loadCustomer(tx, id)
+    if err == nil && (before.DeletedAt != nil) == deleted {
+        tx.Rollback()
+        return nil
+    }
+
+    var action string
+    var after models.Customer
+    if err == nil && !deleted {
+        err = checkCustomerEmail(tx, before.Email, id)
+    }
+    if err == nil {
+        action, err = setDeletedAt(tx, "customers", id, deleted)
+    }
+    if err == nil {
+        after, err = loadCustomer(tx, id)
+    }
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityCustomer, id, action, before, after)
+    }
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+// checkLateFeePolicy rejects a policy id that does not exist, which would
+// otherwise fail every late fee assessment for the customer's invoices.
+func checkLateFeePolicy(q queryer, id *int) error {
+    if id == nil {
+        return nil
+    }
+    var exists int
+    err := q.QueryRow("SELECT 1 FROM late_fee_policies WHERE id = ?", *id).Scan(&exists)
+    if err == sql.ErrNoRows {
+        return errUnknownLateFeePolicy
+    }
+    return err
+}
+
+// checkCustomerEmail rejects an email used by another customer that is
+// not deleted.
+func checkCustomerEmail(q queryer, email string, id int) error {
+    var exists int
+    err := q.QueryRow(`
+        SELECT 1 FROM customers WHERE email = ? AND id <> ? AND deleted_at IS NULL
+    `, email, id).Scan(&exists)
+    if err == sql.ErrNoRows {
+        return nil
+    } else if err != nil {
+        return err
+    }
+    return errDuplicateEmail
+}
+
+func writeCustomerCheckError(w http.ResponseWriter, err error) {
+    switch err {
+    case errUnknownLateFeePolicy:
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+    case errDuplicateEmail:
+        http.Error(w, "A customer with this email already exists", http.StatusConflict)
+    default:
+        http.Error(w, "Database error", http.StatusInternalServerError)
+    }
+}
+
+func loadCustomer(q queryer, id int) (models.Customer, error) {
+    var c models.Customer
+    err := q.QueryRow(`
+        SELECT id, name, email, address, currency, late_fee_policy_id, payment_terms, reminders_opt_out,
+            created_at, updated_at, deleted_at
+        FROM customers
+        WHERE id = ?
+    `, id).Scan(
+        &c.ID,
+        &c.Name,
+        &c.Email,
+        &c.Address,
+        &c.Currency,
+        &c.LateFeePolicyID,
+        &c.PaymentTerms,
+        &c.RemindersOptOut,
+        &c.CreatedAt,
+        &c.UpdatedAt,
+        &c.DeletedAt,
+    )
+    return c, err
 }
\ No newline at end of file
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
+	"invoice-system/internal/models"
+)
+
+func GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+    currency := r.URL.Query().Get("currency")
+    startDate := r.URL.Query().Get("start_date")
+    endDate := r.URL.Query().Get("end_date")
+
+    page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+    if page < 1 {
+        page = 1
+    }
+    limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+    if limit < 1 {
+        limit = 10
+    }
+    offset := (page - 1) * limit
+
+    query := "SELECT id, currency, rate_date, rate, source, created_at FROM exchange_rates"
+    var args []interface{}
+    clauses := []string{}
+
+    if currency != "" {
+        clauses = append(clauses, "currency = ?")
+        args = append(args, strings.ToUpper(currency))
+    }
+    if startDate != "" {
+        clauses = append(clauses, "rate_date >= ?")
+        args = append(args, startDate)
+    }
+    if endDate != "" {
+        clauses = append(clauses, "rate_date <= ?")
+        args = append(args, endDate)
+    }
+
+    if len(clauses) > 0 {
+        query += " WHERE " + joinClauses(clauses, " AND ")
+    }
+
+    query += " ORDER BY rate_date DESC, currency LIMIT ? OFFSET ?"
+    args = append(args, limit, offset)
+
+    rows, err := database.DB.Query(query, args...)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    rates := []models.ExchangeRate{}
+    for rows.Next() {
+        var er models.ExchangeRate
+        err := rows.Scan(&er.ID, &er.Currency, &er.RateDate, &er.Rate, &er.Source, &er.CreatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        rates = append(rates, er)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(rates)
+}
+
+// ImportExchangeRates loads rates from a CSV body (Content-Type text/csv)
+// or a JSON array, replacing rates already stored for the same day.
+func ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+    var rates []models.ExchangeRate
+    var err error
+    if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+        rates, err = fx.ParseCSV(r.Body)
+    } else {
+        rates, err = fx.ParseJSON(r.Body)
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    source := r.URL.Query().Get("source")
+    if source == "" {
+        source = "api"
+    }
+
+    err = fx.Import(database.DB, rates, source)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(map[string]int{"imported": len(rates)})
+}
\ No newline at end of file
@@ -3,13 +3,20 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"invoice-system/internal/audit"
 	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
 	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/numbering"
+	"invoice-system/internal/pricing"
+	"invoice-system/internal/terms"
 
 	"github.com/gorilla/mux"
 )
@@ -29,11 +36,7 @@ func GetInvoices(w http.ResponseWriter, r *http.Request) {
     }
     offset := (page - 1) * limit
 
-    query := `
-        SELECT i.id, i.invoice_number, i.customer_id, i.issue_date, i.due_date, 
-            i.total_amount, i.status, i.created_at, i.updated_at
-        FROM invoices i
-    `
+    query := "SELECT " + invoiceColumns + " FROM invoices i"
     var args []interface{}
     clauses := []string{}
 
@@ -49,6 +52,9 @@ func GetInvoices(w http.ResponseWriter, r *http.Request) {
         clauses = append(clauses, "i.issue_date <= ?")
         args = append(args, endDate)
     }
+    if r.URL.Query().Get("include_deleted") != "true" {
+        clauses = append(clauses, "i.deleted_at IS NULL")
+    }
 
     if len(clauses) > 0 {
         query += " WHERE " + joinClauses(clauses, " AND ")
@@ -67,17 +73,7 @@ func GetInvoices(w http.ResponseWriter, r *http.Request) {
     var invoices []models.Invoice
     for rows.Next() {
         var inv models.Invoice
-        err := rows.Scan(
-            &inv.ID,
-            &inv.InvoiceNumber,
-            &inv.CustomerID,
-            &inv.IssueDate,
-            &inv.DueDate,
-            &inv.TotalAmount,
-            &inv.Status,
-            &inv.CreatedAt,
-            &inv.UpdatedAt,
-        )
+        err := scanInvoice(rows, &inv)
         if err != nil {
             http.Error(w, "Scan error", http.StatusInternalServerError)
             return
@@ -90,16 +86,7 @@ func GetInvoices(w http.ResponseWriter, r *http.Request) {
 }
 
 func CreateInvoice(w http.ResponseWriter, r *http.Request) {
-    var req struct {
-        CustomerID int `json:"customer_id" validate:"required"`
-        IssueDate  string `json:"issue_date" validate:"required"`
-        DueDate    string `json:"due_date" validate:"required"`
-        Items      []struct {
-            ItemID   int     `json:"item_id" validate:"required"`
-            Quantity int     `json:"quantity" validate:"required,min=1"`
-        } `json:"items" validate:"required,min=1"`
-    }
-
+    var req invoiceInput
     err := json.NewDecoder(r.Body).Decode(&req)
     if err != nil {
         http.Error(w, "Invalid JSON", http.StatusBadRequest)
@@ -107,6 +94,12 @@ func CreateInvoice(w http.ResponseWriter, r *http.Request) {
     }
 
     err = validate.Struct(req)
+    if err == nil {
+        err = pricing.Discount{Type: req.DiscountType, Value: req.DiscountValue}.Validate()
+    }
+    if err == nil {
+        err = validateLineDiscounts(req.Items)
+    }
     if err != nil {
         http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
         return
@@ -118,77 +111,229 @@ func CreateInvoice(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    invoiceNumber := "INV-" + strconv.FormatInt(time.Now().UnixNano(), 10)
-    totalAmount := 0.0
+    invoiceID, err := createInvoice(tx, req)
+    if err == nil {
+        err = auditInvoiceChange(tx, r, invoiceID, audit.ActionCreate, nil)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeInvoiceError(w, err)
+        return
+    }
+
+    tx.Commit()
+
+    invoice, err := loadInvoiceDetail(database.DB, invoiceID)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(invoice)
+}
+
+type invoiceInput struct {
+    CustomerID       int                `json:"customer_id" validate:"required"`
+    IssueDate        string             `json:"issue_date" validate:"required"`
+    // DueDate defaults to the date derived from the payment terms, which
+    // default to the customer's terms.
+    DueDate          string             `json:"due_date"`
+    PaymentTerms     string             `json:"payment_terms"`
+    Currency         string             `json:"currency" validate:"omitempty,iso4217"`
+    PricesIncludeTax bool               `json:"prices_include_tax"`
+    DiscountType     string             `json:"discount_type"`
+    DiscountValue    money.Rate         `json:"discount_value"`
+    Items            []invoiceLineInput `json:"items" validate:"required,min=1,dive"`
+}
+
+// createInvoice inserts a draft invoice with its lines and totals and
+// returns its id. Recurring schedules generate invoices through it too.
+func createInvoice(tx *sql.Tx, in invoiceInput) (int, error) {
+    // Bill in the customer's currency and on the customer's payment terms
+    // unless the request chooses them, under the customer's late fee policy
+    var currency, customerTerms string
+    var lateFeePolicyID *int
+    err := tx.QueryRow(`
+        SELECT currency, payment_terms, late_fee_policy_id FROM customers
+        WHERE id = ? AND deleted_at IS NULL
+    `, in.CustomerID).Scan(&currency, &customerTerms, &lateFeePolicyID)
+    if err == sql.ErrNoRows {
+        return 0, errCustomerNotFound
+    } else if err != nil {
+        return 0, err
+    }
+    if in.Currency == "" {
+        in.Currency = currency
+    }
+    if in.PaymentTerms == "" {
+        in.PaymentTerms = customerTerms
+    }
+
+    paymentTerms := terms.Default
+    if in.PaymentTerms != "" {
+        paymentTerms, err = terms.Parse(in.PaymentTerms)
+        if err != nil {
+            return 0, errInvalidDates{err}
+        }
+    }
+    in.DueDate, err = invoiceDueDate(in.IssueDate, in.DueDate, paymentTerms)
+    if err != nil {
+        return 0, err
+    }
+
+    // Number by the issue date so backdated invoices fall in their own period
+    issued, _ := time.Parse("2006-01-02", in.IssueDate)
+    invoiceNumber, err := numbering.Next(tx, numbering.InvoiceSequence, issued)
+    if err != nil {
+        return 0, err
+    }
 
     // Insert invoice
     res, err := tx.Exec(`
-        INSERT INTO invoices (invoice_number, customer_id, issue_date, due_date, total_amount)
-        VALUES (?, ?, ?, ?, ?)
-    `, invoiceNumber, req.CustomerID, req.IssueDate, req.DueDate, totalAmount)
+        INSERT INTO invoices (invoice_number, customer_id, issue_date, due_date, payment_terms,
+            currency, prices_include_tax, discount_type, discount_value, total_amount,
+            late_fee_policy_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, invoiceNumber, in.CustomerID, in.IssueDate, in.DueDate, paymentTerms,
+        in.Currency, in.PricesIncludeTax, in.DiscountType, in.DiscountValue, 0,
+        lateFeePolicyID)
     if err != nil {
-        tx.Rollback()
-        http.Error(w, "Invoice creation error", http.StatusInternalServerError)
-        return
+        return 0, err
     }
 
-    invoiceID, _ := res.LastInsertId()
+    id, _ := res.LastInsertId()
+    invoiceID := int(id)
+
+    err = fixExchangeRate(tx, invoiceID)
+    if err != nil {
+        return 0, err
+    }
 
     // Insert invoice items and calculate total
-    for _, item := range req.Items {
-        var price float64
-        err = tx.QueryRow("SELECT price FROM items WHERE id = ?", item.ItemID).Scan(&price)
+    for _, item := range in.Items {
+        _, err = insertInvoiceLine(tx, invoiceID, item)
         if err != nil {
-            tx.Rollback()
-            http.Error(w, "Item not found", http.StatusBadRequest)
-            return
+            return 0, err
         }
+    }
+
+    // Apply discounts and taxes and update totals
+    err = recalculateInvoice(tx, invoiceID)
+    return invoiceID, err
+}
 
-        _, err = tx.Exec(`
-            INSERT INTO invoice_items (invoice_id, item_id, quantity, price)
-            VALUES (?, ?, ?, ?)
-        `, invoiceID, item.ItemID, item.Quantity, price)
+// DuplicateInvoice copies an invoice into a new draft for the same customer,
+// issued today unless issue_date says otherwise. Lines keep their snapshot
+// prices unless refresh_prices asks for the current item prices; late fee
+// lines are left behind.
+func DuplicateInvoice(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req struct {
+        IssueDate     string `json:"issue_date"`
+        RefreshPrices bool   `json:"refresh_prices"`
+    }
+    if r.ContentLength != 0 {
+        err = json.NewDecoder(r.Body).Decode(&req)
         if err != nil {
-            tx.Rollback()
-            http.Error(w, "Item insertion error", http.StatusInternalServerError)
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
             return
         }
+    }
+    if req.IssueDate == "" {
+        req.IssueDate = time.Now().Format("2006-01-02")
+    }
 
-        totalAmount += price * float64(item.Quantity)
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
     }
 
-    // Update total amount
-    _, err = tx.Exec("UPDATE invoices SET total_amount = ? WHERE id = ?", totalAmount, invoiceID)
+    in, err := duplicateInvoiceInput(tx, id, req.IssueDate, req.RefreshPrices)
+    if err == nil {
+        id, err = createInvoice(tx, in)
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, id, audit.ActionCreate, nil)
+    }
     if err != nil {
         tx.Rollback()
-        http.Error(w, "Total update error", http.StatusInternalServerError)
+        writeInvoiceError(w, err)
         return
     }
 
     tx.Commit()
 
-    var invoice models.Invoice
-    err = database.DB.QueryRow(`
-        SELECT id, invoice_number, customer_id, issue_date, due_date, 
-            total_amount, status, created_at, updated_at
-        FROM invoices
-        WHERE id = ?
-    `, invoiceID).Scan(
-        &invoice.ID,
-        &invoice.InvoiceNumber,
-        &invoice.CustomerID,
-        &invoice.IssueDate,
-        &invoice.DueDate,
-        &invoice.TotalAmount,
-        &invoice.Status,
-        &invoice.CreatedAt,
-        &invoice.UpdatedAt,
-    )
+    invoice, err := loadInvoiceDetail(database.DB, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
 
     w.WriteHeader(http.StatusCreated)
     json.NewEncoder(w).Encode(invoice)
 }
 
+// duplicateInvoiceInput rebuilds the request that would create a copy of
+// an invoice. The due date is derived again from the payment terms.
+func duplicateInvoiceInput(tx *sql.Tx, id int, issueDate string, refreshPrices bool) (invoiceInput, error) {
+    inv, err := loadInvoiceDetail(tx, id)
+    if err == sql.ErrNoRows {
+        return invoiceInput{}, errInvoiceNotFound
+    } else if err != nil {
+        return invoiceInput{}, err
+    }
+
+    feeLines, err := queryIDs(tx, `
+        SELECT invoice_item_id FROM late_fees WHERE invoice_id = ? AND invoice_item_id IS NOT NULL
+    `, id)
+    if err != nil {
+        return invoiceInput{}, err
+    }
+    isFeeLine := map[int]bool{}
+    for _, lineID := range feeLines {
+        isFeeLine[lineID] = true
+    }
+
+    in := invoiceInput{
+        CustomerID:       inv.CustomerID,
+        IssueDate:        issueDate,
+        PaymentTerms:     inv.PaymentTerms,
+        Currency:         inv.Currency,
+        PricesIncludeTax: inv.PricesIncludeTax,
+        DiscountType:     inv.DiscountType,
+        DiscountValue:    inv.DiscountValue,
+    }
+    for _, line := range inv.Items {
+        if isFeeLine[line.ID] {
+            continue
+        }
+        l := invoiceLineInput{
+            ItemID:        line.ItemID,
+            Quantity:      line.Quantity,
+            DiscountType:  line.DiscountType,
+            DiscountValue: line.DiscountValue,
+            TaxRateIDs:    []int{},
+        }
+        for _, t := range line.Taxes {
+            l.TaxRateIDs = append(l.TaxRateIDs, t.TaxRateID)
+        }
+        if !refreshPrices {
+            price := line.UnitPrice
+            l.UnitPrice = &price
+        }
+        in.Items = append(in.Items, l)
+    }
+    return in, nil
+}
+
 func GetInvoice(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id, err := strconv.Atoi(params["id"])
@@ -197,23 +342,7 @@ func GetInvoice(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    var invoice models.Invoice
-    err = database.DB.QueryRow(`
-        SELECT id, invoice_number, customer_id, issue_date, due_date, 
-            total_amount, status, created_at, updated_at
-        FROM invoices
-        WHERE id = ?
-    `, id).Scan(
-        &invoice.ID,
-        &invoice.InvoiceNumber,
-        &invoice.CustomerID,
-        &invoice.IssueDate,
-        &invoice.DueDate,
-        &invoice.TotalAmount,
-        &invoice.Status,
-        &invoice.CreatedAt,
-        &invoice.UpdatedAt,
-    )
+    invoice, err := loadInvoiceDetail(database.DB, id)
     if err == sql.ErrNoRows {
         http.Error(w, "Invoice not found", http.StatusNotFound)
         return
@@ -234,9 +363,13 @@ func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
     }
 
     var req struct {
-        IssueDate string `json:"issue_date"`
-        DueDate   string `json:"due_date"`
-        Status    string `json:"status"`
+        IssueDate        string               `json:"issue_date"`
+        DueDate          string               `json:"due_date"`
+        Status           models.InvoiceStatus `json:"status"`
+        PricesIncludeTax *bool                `json:"prices_include_tax"`
+        DiscountType     *string              `json:"discount_type"`
+        DiscountValue    *money.Rate          `json:"discount_value"`
+        Items            []invoiceLineInput   `json:"items" validate:"omitempty,dive"`
     }
     err = json.NewDecoder(r.Body).Decode(&req)
     if err != nil {
@@ -244,12 +377,91 @@ func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    err = validate.Struct(req)
+    if err == nil {
+        err = validateLineDiscounts(req.Items)
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    if req.Status != "" && !req.Status.Valid() {
+        http.Error(w, "Validation error: unknown status "+string(req.Status), http.StatusBadRequest)
+        return
+    }
+    if req.Status == models.InvoiceVoid {
+        http.Error(w, "Validation error: void invoices through POST /api/invoices/{id}/void with a reason", http.StatusBadRequest)
+        return
+    }
+    // Payment status follows the payments ledger and overdue is set by the
+    // scheduler, so neither can be chosen by the client
+    switch req.Status {
+    case models.InvoicePaid, models.InvoicePartiallyPaid:
+        http.Error(w, "Validation error: record payments through POST /api/invoices/{id}/payments", http.StatusBadRequest)
+        return
+    case models.InvoiceCredited:
+        http.Error(w, "Validation error: record credit notes through POST /api/invoices/{id}/credit-notes", http.StatusBadRequest)
+        return
+    case models.InvoiceOverdue:
+        http.Error(w, "Validation error: overdue is set automatically after the due date", http.StatusBadRequest)
+        return
+    }
+
     tx, err := database.DB.Begin()
     if err != nil {
         http.Error(w, "Transaction error", http.StatusInternalServerError)
         return
     }
 
+    before, err := loadInvoiceDetail(tx, id)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        http.Error(w, "Invoice not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    // Pricing changes are only allowed on drafts. A non-empty items list
+    // replaces every line of the invoice.
+    repricing := req.PricesIncludeTax != nil || req.DiscountType != nil || req.DiscountValue != nil
+    if len(req.Items) > 0 || repricing {
+        err = lockEditableInvoice(tx, id)
+        if err == nil && req.PricesIncludeTax != nil {
+            _, err = tx.Exec("UPDATE invoices SET prices_include_tax = ? WHERE id = ?", *req.PricesIncludeTax, id)
+        }
+        if err == nil && (req.DiscountType != nil || req.DiscountValue != nil) {
+            err = updateInvoiceDiscount(tx, id, req.DiscountType, req.DiscountValue)
+        }
+        if err == nil && len(req.Items) > 0 {
+            err = replaceInvoiceLines(tx, id, req.Items)
+        }
+        if err == nil {
+            err = recalculateInvoice(tx, id)
+        }
+        if err != nil {
+            tx.Rollback()
+            writeInvoiceError(w, err)
+            return
+        }
+    }
+
+    // Dates are part of the issued document and, like pricing, stay fixed
+    // once the invoice leaves draft
+    if req.IssueDate != "" || req.DueDate != "" {
+        err = lockEditableInvoice(tx, id)
+        if err == nil {
+            err = checkInvoiceDates(tx, id, req.IssueDate, req.DueDate)
+        }
+        if err != nil {
+            tx.Rollback()
+            writeInvoiceError(w, err)
+            return
+        }
+    }
+
     query := "UPDATE invoices SET updated_at = ?"
     args := []interface{}{time.Now()}
 
@@ -261,10 +473,6 @@ func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
         query += ", due_date = ?"
         args = append(args, req.DueDate)
     }
-    if req.Status != "" {
-        query += ", status = ?"
-        args = append(args, req.Status)
-    }
 
     query += " WHERE id = ?"
     args = append(args, id)
@@ -276,29 +484,29 @@ func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    tx.Commit()
+    // Status changes go through the same transition rules as the action endpoints
+    if req.Status != "" {
+        err = transitionInvoice(tx, id, req.Status, actorFromRequest(r), "")
+        if err != nil {
+            tx.Rollback()
+            writeInvoiceError(w, err)
+            return
+        }
+    }
 
-    var updatedInvoice models.Invoice
-    err = database.DB.QueryRow(`
-        SELECT id, invoice_number, customer_id, issue_date, due_date, 
-            total_amount, status, created_at, updated_at
-        FROM invoices
-        WHERE id = ?
-    `, id).Scan(
-        &updatedInvoice.ID,
-        &updatedInvoice.InvoiceNumber,
-        &updatedInvoice.CustomerID,
-        &updatedInvoice.IssueDate,
-        &updatedInvoice.DueDate,
-        &updatedInvoice.TotalAmount,
-        &updatedInvoice.Status,
-        &updatedInvoice.CreatedAt,
-        &updatedInvoice.UpdatedAt,
-    )
+    err = auditInvoiceChange(tx, r, id, audit.ActionUpdate, before)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
 
-    json.NewEncoder(w).Encode(updatedInvoice)
+    tx.Commit()
+    writeInvoiceDetail(w, id)
 }
 
+// DeleteInvoice soft-deletes a draft. Issued invoices keep their place in
+// the numbering and are voided instead.
 func DeleteInvoice(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id, err := strconv.Atoi(params["id"])
@@ -307,30 +515,74 @@ func DeleteInvoice(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    tx, err := database.DB.Begin()
+    err = setInvoiceDeleted(r, id, true)
+    if err == errInvoiceNotEditable {
+        http.Error(w, "Only draft invoices can be deleted; void issued invoices instead", http.StatusConflict)
+        return
+    } else if err != nil {
+        writeInvoiceError(w, err)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func RestoreInvoice(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
     if err != nil {
-        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
         return
     }
 
-    _, err = tx.Exec("DELETE FROM invoice_items WHERE invoice_id = ?", id)
+    err = setInvoiceDeleted(r, id, false)
     if err != nil {
-        tx.Rollback()
-        http.Error(w, "Item deletion error", http.StatusInternalServerError)
+        writeInvoiceError(w, err)
         return
     }
 
-    _, err = tx.Exec("DELETE FROM invoices WHERE id = ?", id)
+    writeInvoiceDetail(w, id)
+}
+
+func setInvoiceDeleted(r *http.Request, id int, deleted bool) error {
+    tx, err := database.DB.Begin()
     if err != nil {
+        return err
+    }
+
+    var status models.InvoiceStatus
+    var deletedAt *time.Time
+    err = tx.QueryRow("SELECT status, deleted_at FROM invoices WHERE id = ? FOR UPDATE", id).Scan(&status, &deletedAt)
+    if err == sql.ErrNoRows {
+        err = errInvoiceNotFound
+    }
+    if err == nil && (deletedAt != nil) == deleted {
         tx.Rollback()
-        http.Error(w, "Invoice deletion error", http.StatusInternalServerError)
-        return
+        return nil
+    }
+    if err == nil && status != models.InvoiceDraft {
+        err = errInvoiceNotEditable
     }
 
-    tx.Commit()
-    w.WriteHeader(http.StatusNoContent)
+    var before models.InvoiceDetail
+    var action string
+    if err == nil {
+        before, err = loadInvoiceDetail(tx, id)
+    }
+    if err == nil {
+        action, err = setDeletedAt(tx, "invoices", id, deleted)
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, id, action, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
 }
 
+// MarkInvoiceAsPaid settles the outstanding balance with a single payment.
 func MarkInvoiceAsPaid(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id, err := strconv.Atoi(params["id"])
@@ -339,35 +591,207 @@ func MarkInvoiceAsPaid(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    _, err = database.DB.Exec(`
-        UPDATE invoices
-        SET status = 'paid', updated_at = ?
-        WHERE id = ?
-    `, time.Now(), id)
+    tx, err := database.DB.Begin()
     if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    before, err := loadInvoiceDetail(tx, id)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        http.Error(w, "Invoice not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        tx.Rollback()
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
     }
 
-    var invoice models.Invoice
-    err = database.DB.QueryRow(`
-        SELECT id, invoice_number, customer_id, issue_date, due_date, 
-            total_amount, status, created_at, updated_at
-        FROM invoices
-        WHERE id = ?
-    `, id).Scan(
-        &invoice.ID,
-        &invoice.InvoiceNumber,
-        &invoice.CustomerID,
-        &invoice.IssueDate,
-        &invoice.DueDate,
-        &invoice.TotalAmount,
-        &invoice.Status,
-        &invoice.CreatedAt,
-        &invoice.UpdatedAt,
+    payment := models.Payment{
+        Amount:    before.BalanceDue,
+        PaidAt:    time.Now().Format("2006-01-02"),
+        Method:    "other",
+        Reference: "marked as paid",
+    }
+    if payment.Amount > 0 {
+        _, err = recordPayment(tx, id, &payment, actorFromRequest(r))
+    } else if !before.Status.Payable() {
+        err = errInvoiceNotPayable
+    } else {
+        err = settleInvoiceStatus(tx, id, actorFromRequest(r))
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, id, audit.ActionPay, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeInvoiceError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    writeInvoiceDetail(w, id)
+}
+
+// updateInvoiceDiscount changes the invoice-level discount, keeping the
+// current type or value where the request leaves it out.
+func updateInvoiceDiscount(tx *sql.Tx, id int, discountType *string, discountValue *money.Rate) error {
+    var d pricing.Discount
+    err := tx.QueryRow("SELECT discount_type, discount_value FROM invoices WHERE id = ?", id).Scan(&d.Type, &d.Value)
+    if err != nil {
+        return err
+    }
+    if discountType != nil {
+        d.Type = *discountType
+    }
+    if discountValue != nil {
+        d.Value = *discountValue
+    }
+    if err := d.Validate(); err != nil {
+        return errInvalidDiscount{err}
+    }
+
+    _, err = tx.Exec("UPDATE invoices SET discount_type = ?, discount_value = ? WHERE id = ?", d.Type, d.Value, id)
+    return err
+}
+
+// invoiceDueDate validates the invoice dates, deriving the due date from
+// the payment terms when it is left out.
+func invoiceDueDate(issueDate, dueDate string, t terms.Terms) (string, error) {
+    issued, err := time.Parse("2006-01-02", issueDate)
+    if err != nil {
+        return "", errInvalidDates{errors.New("issue_date must be YYYY-MM-DD")}
+    }
+    if dueDate == "" {
+        return t.DueDate(issued).Format("2006-01-02"), nil
+    }
+
+    due, err := time.Parse("2006-01-02", dueDate)
+    if err != nil {
+        return "", errInvalidDates{errors.New("due_date must be YYYY-MM-DD")}
+    }
+    if due.Before(issued) {
+        return "", errInvalidDates{errors.New("due_date is before issue_date")}
+    }
+    return dueDate, nil
+}
+
+// checkInvoiceDates validates a date change against the stored dates the
+// update leaves alone.
+func checkInvoiceDates(tx *sql.Tx, id int, issueDate, dueDate string) error {
+    var storedIssue, storedDue string
+    err := tx.QueryRow(`
+        SELECT DATE_FORMAT(issue_date, '%Y-%m-%d'), DATE_FORMAT(due_date, '%Y-%m-%d')
+        FROM invoices WHERE id = ?
+    `, id).Scan(&storedIssue, &storedDue)
+    if err == sql.ErrNoRows {
+        return errInvoiceNotFound
+    } else if err != nil {
+        return err
+    }
+
+    if issueDate == "" {
+        issueDate = storedIssue
+    }
+    if dueDate == "" {
+        dueDate = storedDue
+    }
+    _, err = invoiceDueDate(issueDate, dueDate, terms.Default)
+    return err
+}
+
+// fixExchangeRate books the invoice at the rate of its currency on the
+// issue date.
+func fixExchangeRate(tx *sql.Tx, id int) error {
+    var currency, issueDate string
+    err := tx.QueryRow(`
+        SELECT currency, DATE_FORMAT(issue_date, '%Y-%m-%d')
+        FROM invoices WHERE id = ?
+    `, id).Scan(&currency, &issueDate)
+    if err == sql.ErrNoRows {
+        return errInvoiceNotFound
+    } else if err != nil {
+        return err
+    }
+
+    rate, err := fx.RateOn(tx, currency, issueDate)
+    if err != nil {
+        return err
+    }
+    _, err = tx.Exec("UPDATE invoices SET exchange_rate = ? WHERE id = ?", rate, id)
+    return err
+}
+
+// invoiceColumns selects an invoice aliased as i in the order scanInvoice
+// expects, including the net amount paid, the amount credited so far and
+// the quote it was converted from.
+const invoiceColumns = `
+    i.id, i.invoice_number, i.customer_id, i.issue_date, i.due_date, i.payment_terms,
+    i.currency, i.exchange_rate,
+    i.prices_include_tax, i.discount_type, i.discount_value, i.line_discount_total,
+    i.discount_amount, i.subtotal, i.tax_total, i.total_amount,
+    i.status, i.late_fee_policy_id, i.void_reason, i.voided_at,
+    i.created_at, i.updated_at, i.deleted_at,
+    (SELECT COALESCE(SUM(p.amount), 0) FROM payments p WHERE p.invoice_id = i.id),
+    (SELECT COALESCE(SUM(c.total_amount), 0) FROM credit_notes c WHERE c.invoice_id = i.id),
+    (SELECT q.id FROM quotes q WHERE q.invoice_id = i.id)
+`
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+func scanInvoice(row rowScanner, inv *models.Invoice) error {
+    err := row.Scan(
+        &inv.ID,
+        &inv.InvoiceNumber,
+        &inv.CustomerID,
+        &inv.IssueDate,
+        &inv.DueDate,
+        &inv.PaymentTerms,
+        &inv.Currency,
+        &inv.ExchangeRate,
+        &inv.PricesIncludeTax,
+        &inv.DiscountType,
+        &inv.DiscountValue,
+        &inv.LineDiscountTotal,
+        &inv.DiscountAmount,
+        &inv.Subtotal,
+        &inv.TaxTotal,
+        &inv.TotalAmount,
+        &inv.Status,
+        &inv.LateFeePolicyID,
+        &inv.VoidReason,
+        &inv.VoidedAt,
+        &inv.CreatedAt,
+        &inv.UpdatedAt,
+        &inv.DeletedAt,
+        &inv.AmountPaid,
+        &inv.AmountCredited,
+        &inv.QuoteID,
     )
+    if err != nil {
+        return err
+    }
+    inv.BalanceDue = inv.TotalAmount - inv.AmountPaid - inv.AmountCredited
+    // Voiding writes the receivable off
+    if inv.Status == models.InvoiceVoid {
+        inv.BalanceDue = 0
+    }
+    inv.BaseTotalAmount = inv.TotalAmount.ToBase(inv.ExchangeRate)
+    return nil
+}
 
-    json.NewEncoder(w).Encode(invoice)
+func loadInvoice(q queryer, id int) (models.Invoice, error) {
+    var inv models.Invoice
+    err := scanInvoice(q.QueryRow("SELECT "+invoiceColumns+" FROM invoices i WHERE i.id = ?", id), &inv)
+    return inv, err
 }
 
 func joinClauses(clauses []string, sep string) string {
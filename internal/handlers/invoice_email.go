@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/mailer"
+	"invoice-system/internal/models"
+	"invoice-system/internal/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// SendInvoice emails the invoice to the customer: the body is rendered with
+// a layout and the PDF is attached. An issued invoice moves to sent once the
+// SMTP server accepts the message; sending it again only records another
+// delivery. With ?deliver=false the invoice is only marked as sent, for
+// invoices delivered outside the system.
+func SendInvoice(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Query().Get("deliver") == "false" {
+        changeInvoiceStatus(w, r, models.InvoiceSent, audit.ActionSend)
+        return
+    }
+
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req struct {
+        Template string `json:"template"`
+        Note     string `json:"note"`
+    }
+    if r.ContentLength != 0 {
+        err = json.NewDecoder(r.Body).Decode(&req)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+    }
+    if req.Template == "" {
+        req.Template = templates.DefaultName
+    }
+
+    if mailer.Default == nil {
+        http.Error(w, "Email delivery is not configured", http.StatusServiceUnavailable)
+        return
+    }
+
+    data, err := invoiceTemplateData(database.DB, id)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            err = errInvoiceNotFound
+        }
+        writeInvoiceError(w, err)
+        return
+    }
+    if data.Invoice.Status == models.InvoiceDraft || data.Invoice.Status == models.InvoiceVoid {
+        http.Error(w, "Only issued invoices can be sent", http.StatusConflict)
+        return
+    }
+    if data.Customer.Email == "" {
+        http.Error(w, "Validation error: customer has no email address", http.StatusBadRequest)
+        return
+    }
+
+    t, err := templates.Load(database.DB, req.Template)
+    if err == templates.ErrNotFound {
+        http.Error(w, "Template not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    msg, err := invoiceEmail(data, t)
+    if err != nil {
+        http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    // The message goes out before any row is locked; a slow SMTP server
+    // must not hold up payments against the invoice.
+    sendErr := mailer.Default.Send(msg)
+    delivery := models.EmailDelivery{
+        InvoiceID: id,
+        Recipient: data.Customer.Email,
+        Subject:   msg.Subject,
+        Template:  t.Name,
+        Status:    models.DeliverySent,
+        SentBy:    actorFromRequest(r),
+    }
+    if sendErr != nil {
+        delivery.Status = models.DeliveryFailed
+        delivery.Error = sendErr.Error()
+    }
+    err = recordEmailDelivery(delivery)
+    if err != nil {
+        log.Printf("invoice %d: recording email delivery: %v", id, err)
+    }
+    if sendErr != nil {
+        http.Error(w, "Email delivery failed: "+sendErr.Error(), http.StatusBadGateway)
+        return
+    }
+
+    if data.Invoice.Status == models.InvoiceIssued {
+        tx, err := database.DB.Begin()
+        if err != nil {
+            http.Error(w, "Transaction error", http.StatusInternalServerError)
+            return
+        }
+        before, err := loadInvoiceDetail(tx, id)
+        if err == nil {
+            err = transitionInvoice(tx, id, models.InvoiceSent, delivery.SentBy, req.Note)
+        }
+        if err == nil {
+            err = auditInvoiceChange(tx, r, id, audit.ActionSend, before)
+        }
+        if err == errIllegalTransition {
+            // Paid or voided while the message was on its way
+            tx.Rollback()
+        } else if err != nil {
+            tx.Rollback()
+            writeInvoiceError(w, err)
+            return
+        } else if err = tx.Commit(); err != nil {
+            http.Error(w, "Transaction error", http.StatusInternalServerError)
+            return
+        }
+    }
+
+    writeInvoiceDetail(w, id)
+}
+
+func GetInvoiceDeliveries(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query(`
+        SELECT id, invoice_id, recipient, subject, template, status, error, sent_by, created_at
+        FROM email_deliveries
+        WHERE invoice_id = ?
+        ORDER BY id
+    `, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    deliveries := []models.EmailDelivery{}
+    for rows.Next() {
+        var d models.EmailDelivery
+        err := rows.Scan(&d.ID, &d.InvoiceID, &d.Recipient, &d.Subject, &d.Template, &d.Status, &d.Error, &d.SentBy, &d.CreatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        deliveries = append(deliveries, d)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(deliveries)
+}
+
+func recordEmailDelivery(d models.EmailDelivery) error {
+    _, err := database.DB.Exec(`
+        INSERT INTO email_deliveries (invoice_id, recipient, subject, template, status, error, sent_by)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, d.InvoiceID, d.Recipient, d.Subject, d.Template, d.Status, d.Error, d.SentBy)
+    return err
+}
+
+// invoiceEmail builds the message for an invoice: the rendered layout as
+// the body, a short plain text version and the PDF.
+func invoiceEmail(data templates.Data, t models.InvoiceTemplate) (mailer.Message, error) {
+    inv := data.Invoice
+
+    var html bytes.Buffer
+    err := templates.Render(&html, t, data)
+    if err != nil {
+        return mailer.Message{}, err
+    }
+    var pdf bytes.Buffer
+    err = writeInvoicePDF(&pdf, inv, data.Customer, data.Company)
+    if err != nil {
+        return mailer.Message{}, err
+    }
+
+    subject := "Invoice " + inv.InvoiceNumber
+    if data.Company.LegalName != "" {
+        subject += " from " + data.Company.LegalName
+    }
+    text := fmt.Sprintf("Dear %s,\n\nPlease find attached invoice %s for %s %s, due on %s.\n",
+        data.Customer.Name, inv.InvoiceNumber, inv.Currency, inv.BalanceDue.Format(), displayDate(inv.DueDate))
+    if data.Company.BankDetails != "" {
+        text += "\n" + data.Company.BankDetails + "\n"
+    }
+    if data.Company.LegalName != "" {
+        text += "\n" + data.Company.LegalName + "\n"
+    }
+
+    return mailer.Message{
+        To:      []string{data.Customer.Email},
+        Subject: subject,
+        Text:    text,
+        HTML:    html.String(),
+        Attachments: []mailer.Attachment{{
+            Filename:    documentFilename(inv.InvoiceNumber, "pdf"),
+            ContentType: "application/pdf",
+            Data:        pdf.Bytes(),
+        }},
+    }, nil
+}
\ No newline at end of file
@@ -0,0 +1,723 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/pricing"
+
+	"github.com/gorilla/mux"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+    Query(query string, args ...interface{}) (*sql.Rows, error)
+    QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+var (
+    errInvoiceNotFound     = errors.New("invoice not found")
+    errInvoiceNotEditable  = errors.New("invoice is not editable")
+    errItemNotFound        = errors.New("item not found")
+    errInvoiceLineNotFound = errors.New("invoice line not found")
+    errIllegalTransition   = errors.New("illegal invoice status transition")
+    errTaxRateNotFound     = errors.New("tax rate not found")
+    errCustomerNotFound    = errors.New("customer not found")
+    errInvoiceHasPayments  = errors.New("invoice has payments")
+)
+
+// errInvalidDiscount wraps a discount rejected after merging a partial
+// update with the stored discount.
+type errInvalidDiscount struct {
+    error
+}
+
+// errInvalidDates wraps issue and due dates or payment terms that cannot
+// be checked before the customer and stored invoice are loaded.
+type errInvalidDates struct {
+    error
+}
+
+type invoiceLineInput struct {
+    ItemID   int `json:"item_id" validate:"required"`
+    Quantity int `json:"quantity" validate:"required,min=1"`
+    DiscountType  string     `json:"discount_type"`
+    DiscountValue money.Rate `json:"discount_value"`
+    // TaxRateIDs overrides the item's default tax rate; an empty list
+    // makes the line untaxed.
+    TaxRateIDs []int `json:"tax_rate_ids"`
+    // UnitPrice replaces the item price on lines the system adds itself,
+    // such as late fees. Clients always get the item price.
+    UnitPrice *money.Amount `json:"-"`
+}
+
+func (in invoiceLineInput) discount() pricing.Discount {
+    return pricing.Discount{Type: in.DiscountType, Value: in.DiscountValue}
+}
+
+// validateLineDiscounts checks the discounts that the validator tags
+// cannot express.
+func validateLineDiscounts(lines []invoiceLineInput) error {
+    for _, line := range lines {
+        if err := line.discount().Validate(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func GetInvoiceItems(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var exists int
+    err = database.DB.QueryRow("SELECT 1 FROM invoices WHERE id = ?", id).Scan(&exists)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Invoice not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    lines, err := getInvoiceLines(database.DB, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(lines)
+}
+
+func getInvoiceLines(q queryer, invoiceID int) ([]models.InvoiceLine, error) {
+    rows, err := q.Query(`
+        SELECT ii.id, ii.invoice_id, ii.item_id, it.name, ii.quantity, ii.price,
+            ii.discount_type, ii.discount_value, ii.discount_amount, ii.invoice_discount_amount,
+            ii.net_amount, ii.tax_amount, ii.line_total, ii.created_at, ii.updated_at
+        FROM invoice_items ii
+        JOIN items it ON it.id = ii.item_id
+        WHERE ii.invoice_id = ?
+        ORDER BY ii.id
+    `, invoiceID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    lines := []models.InvoiceLine{}
+    for rows.Next() {
+        var l models.InvoiceLine
+        err := rows.Scan(
+            &l.ID,
+            &l.InvoiceID,
+            &l.ItemID,
+            &l.ItemName,
+            &l.Quantity,
+            &l.UnitPrice,
+            &l.DiscountType,
+            &l.DiscountValue,
+            &l.DiscountAmount,
+            &l.InvoiceDiscountAmount,
+            &l.NetAmount,
+            &l.TaxAmount,
+            &l.LineTotal,
+            &l.CreatedAt,
+            &l.UpdatedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+        lines = append(lines, l)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    rows.Close()
+
+    taxes, err := getInvoiceLineTaxes(q, invoiceID)
+    if err != nil {
+        return nil, err
+    }
+    for i := range lines {
+        lines[i].Taxes = taxes[lines[i].ID]
+        if lines[i].Taxes == nil {
+            lines[i].Taxes = []models.InvoiceTax{}
+        }
+    }
+    return lines, nil
+}
+
+// getInvoiceLineTaxes returns the taxes of every line of the invoice keyed
+// by line id.
+func getInvoiceLineTaxes(q queryer, invoiceID int) (map[int][]models.InvoiceTax, error) {
+    rows, err := q.Query(`
+        SELECT t.invoice_item_id, t.tax_rate_id, t.name, t.rate, t.compound,
+            t.taxable_amount, t.tax_amount
+        FROM invoice_item_taxes t
+        JOIN invoice_items ii ON ii.id = t.invoice_item_id
+        WHERE ii.invoice_id = ?
+        ORDER BY t.id
+    `, invoiceID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    taxes := map[int][]models.InvoiceTax{}
+    for rows.Next() {
+        var lineID int
+        var t models.InvoiceTax
+        err := rows.Scan(&lineID, &t.TaxRateID, &t.Name, &t.Rate, &t.Compound, &t.TaxableAmount, &t.TaxAmount)
+        if err != nil {
+            return nil, err
+        }
+        taxes[lineID] = append(taxes[lineID], t)
+    }
+    return taxes, rows.Err()
+}
+
+// getInvoiceTaxSummary totals the invoice taxes per rate.
+func getInvoiceTaxSummary(q queryer, invoiceID int) ([]models.InvoiceTax, error) {
+    rows, err := q.Query(`
+        SELECT t.tax_rate_id, t.name, t.rate, t.compound,
+            SUM(t.taxable_amount), SUM(t.tax_amount)
+        FROM invoice_item_taxes t
+        JOIN invoice_items ii ON ii.id = t.invoice_item_id
+        WHERE ii.invoice_id = ?
+        GROUP BY t.tax_rate_id, t.name, t.rate, t.compound
+        ORDER BY MIN(t.id)
+    `, invoiceID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    taxes := []models.InvoiceTax{}
+    for rows.Next() {
+        var t models.InvoiceTax
+        err := rows.Scan(&t.TaxRateID, &t.Name, &t.Rate, &t.Compound, &t.TaxableAmount, &t.TaxAmount)
+        if err != nil {
+            return nil, err
+        }
+        taxes = append(taxes, t)
+    }
+    return taxes, rows.Err()
+}
+
+func loadInvoiceDetail(q queryer, id int) (models.InvoiceDetail, error) {
+    invoice, err := loadInvoice(q, id)
+    if err != nil {
+        return models.InvoiceDetail{}, err
+    }
+
+    lines, err := getInvoiceLines(q, id)
+    if err != nil {
+        return models.InvoiceDetail{}, err
+    }
+
+    taxes, err := getInvoiceTaxSummary(q, id)
+    if err != nil {
+        return models.InvoiceDetail{}, err
+    }
+
+    return models.InvoiceDetail{Invoice: invoice, Items: lines, Taxes: taxes}, nil
+}
+
+func AddInvoiceItem(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req invoiceLineInput
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err == nil {
+        err = req.discount().Validate()
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    editInvoiceLines(w, r, id, func(tx *sql.Tx) error {
+        _, err := insertInvoiceLine(tx, id, req)
+        return err
+    })
+}
+
+func ReplaceInvoiceItems(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req struct {
+        Items []invoiceLineInput `json:"items" validate:"required,min=1,dive"`
+    }
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err == nil {
+        err = validateLineDiscounts(req.Items)
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    editInvoiceLines(w, r, id, func(tx *sql.Tx) error {
+        return replaceInvoiceLines(tx, id, req.Items)
+    })
+}
+
+func UpdateInvoiceItem(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+    lineID, err := strconv.Atoi(params["lineId"])
+    if err != nil {
+        http.Error(w, "Invalid line ID", http.StatusBadRequest)
+        return
+    }
+
+    var req invoiceLineInput
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err == nil {
+        err = req.discount().Validate()
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    editInvoiceLines(w, r, id, func(tx *sql.Tx) error {
+        var itemID int
+        err := tx.QueryRow(`
+            SELECT item_id FROM invoice_items
+            WHERE id = ? AND invoice_id = ?
+        `, lineID, id).Scan(&itemID)
+        if err == sql.ErrNoRows {
+            return errInvoiceLineNotFound
+        } else if err != nil {
+            return err
+        }
+
+        // Keep the price snapshot unless the line now points at another item
+        if itemID == req.ItemID {
+            _, err = tx.Exec(`
+                UPDATE invoice_items
+                SET quantity = ?, discount_type = ?, discount_value = ?, updated_at = ?
+                WHERE id = ?
+            `, req.Quantity, req.DiscountType, req.DiscountValue, time.Now(), lineID)
+            if err != nil || req.TaxRateIDs == nil {
+                return err
+            }
+            return setLineTaxes(tx, lineID, req.TaxRateIDs)
+        }
+
+        price, defaultRate, err := itemPricing(tx, id, req.ItemID)
+        if err != nil {
+            return err
+        }
+        _, err = tx.Exec(`
+            UPDATE invoice_items
+            SET item_id = ?, quantity = ?, price = ?, discount_type = ?, discount_value = ?, updated_at = ?
+            WHERE id = ?
+        `, req.ItemID, req.Quantity, price, req.DiscountType, req.DiscountValue, time.Now(), lineID)
+        if err != nil {
+            return err
+        }
+        return setLineTaxes(tx, lineID, lineTaxRateIDs(req.TaxRateIDs, defaultRate))
+    })
+}
+
+func DeleteInvoiceItem(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+    lineID, err := strconv.Atoi(params["lineId"])
+    if err != nil {
+        http.Error(w, "Invalid line ID", http.StatusBadRequest)
+        return
+    }
+
+    editInvoiceLines(w, r, id, func(tx *sql.Tx) error {
+        _, err := tx.Exec(`
+            DELETE t FROM invoice_item_taxes t
+            JOIN invoice_items ii ON ii.id = t.invoice_item_id
+            WHERE ii.id = ? AND ii.invoice_id = ?
+        `, lineID, id)
+        if err != nil {
+            return err
+        }
+
+        res, err := tx.Exec("DELETE FROM invoice_items WHERE id = ? AND invoice_id = ?", lineID, id)
+        if err != nil {
+            return err
+        }
+        n, _ := res.RowsAffected()
+        if n == 0 {
+            return errInvoiceLineNotFound
+        }
+        return nil
+    })
+}
+
+// editInvoiceLines runs edit inside a transaction holding the invoice row
+// lock, recomputes the invoice totals and writes the updated detail.
+func editInvoiceLines(w http.ResponseWriter, r *http.Request, id int, edit func(tx *sql.Tx) error) {
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    var before models.InvoiceDetail
+    err = lockEditableInvoice(tx, id)
+    if err == nil {
+        before, err = loadInvoiceDetail(tx, id)
+    }
+    if err == nil {
+        err = edit(tx)
+    }
+    if err == nil {
+        err = recalculateInvoice(tx, id)
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, id, audit.ActionUpdate, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeInvoiceError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    writeInvoiceDetail(w, id)
+}
+
+// writeInvoiceDetail encodes the invoice with its lines as the response.
+func writeInvoiceDetail(w http.ResponseWriter, id int) {
+    detail, err := loadInvoiceDetail(database.DB, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(detail)
+}
+
+func writeInvoiceError(w http.ResponseWriter, err error) {
+    switch err.(type) {
+    case errInvalidDiscount, errInvalidDates:
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    if errors.Is(err, fx.ErrRateNotFound) {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    switch err {
+    case errInvoiceNotFound:
+        http.Error(w, "Invoice not found", http.StatusNotFound)
+    case errInvoiceLineNotFound:
+        http.Error(w, "Invoice line not found", http.StatusNotFound)
+    case errInvoiceNotEditable:
+        http.Error(w, "Only draft invoices can be edited", http.StatusConflict)
+    case errIllegalTransition:
+        http.Error(w, "Illegal invoice status transition", http.StatusConflict)
+    case errInvoiceNotPayable:
+        http.Error(w, "Invoice does not accept payments in its current status", http.StatusConflict)
+    case errInvoiceHasPayments:
+        http.Error(w, "Refund the payments before voiding the invoice", http.StatusConflict)
+    case errOverpayment:
+        http.Error(w, "Payment exceeds balance due", http.StatusBadRequest)
+    case errItemNotFound:
+        http.Error(w, "Item not found", http.StatusBadRequest)
+    case errTaxRateNotFound:
+        http.Error(w, "Tax rate not found or inactive", http.StatusBadRequest)
+    case errCustomerNotFound:
+        http.Error(w, "Customer not found", http.StatusBadRequest)
+    default:
+        http.Error(w, "Database error", http.StatusInternalServerError)
+    }
+}
+
+func lockEditableInvoice(tx *sql.Tx, id int) error {
+    var status models.InvoiceStatus
+    err := tx.QueryRow(`
+        SELECT status FROM invoices WHERE id = ? AND deleted_at IS NULL FOR UPDATE
+    `, id).Scan(&status)
+    if err == sql.ErrNoRows {
+        return errInvoiceNotFound
+    } else if err != nil {
+        return err
+    }
+    if !status.Editable() {
+        return errInvoiceNotEditable
+    }
+    return nil
+}
+
+// itemPricing returns the current price of an item in the invoice currency
+// and the item's default tax rate.
+func itemPricing(tx *sql.Tx, invoiceID, itemID int) (money.Amount, *int, error) {
+    var currency, issueDate string
+    var rate money.ExchangeRate
+    err := tx.QueryRow(`
+        SELECT currency, exchange_rate, DATE_FORMAT(issue_date, '%Y-%m-%d')
+        FROM invoices WHERE id = ?
+    `, invoiceID).Scan(&currency, &rate, &issueDate)
+    if err != nil {
+        return 0, nil, err
+    }
+    return itemPrice(tx, itemID, currency, rate, issueDate)
+}
+
+// itemPrice returns the current price of an item in a document currency
+// and the item's default tax rate. Prices in another currency are converted
+// at the rates of the document date. Deleted items cannot be priced.
+func itemPrice(tx *sql.Tx, itemID int, currency string, rate money.ExchangeRate, date string) (money.Amount, *int, error) {
+    var price money.Amount
+    var itemCurrency string
+    var taxRateID sql.NullInt64
+    err := tx.QueryRow(`
+        SELECT price, currency, tax_rate_id FROM items
+        WHERE id = ? AND deleted_at IS NULL
+    `, itemID).Scan(&price, &itemCurrency, &taxRateID)
+    if err == sql.ErrNoRows {
+        return 0, nil, errItemNotFound
+    } else if err != nil {
+        return 0, nil, err
+    }
+
+    if itemCurrency != currency {
+        itemRate, err := fx.RateOn(tx, itemCurrency, date)
+        if err != nil {
+            return 0, nil, err
+        }
+        price = price.Convert(itemRate, rate)
+    }
+    if !taxRateID.Valid {
+        return price, nil, nil
+    }
+    id := int(taxRateID.Int64)
+    return price, &id, nil
+}
+
+// lineTaxRateIDs applies the item default unless the line overrides it.
+func lineTaxRateIDs(override []int, defaultRate *int) []int {
+    if override != nil {
+        return override
+    }
+    if defaultRate != nil {
+        return []int{*defaultRate}
+    }
+    return nil
+}
+
+func insertInvoiceLine(tx *sql.Tx, invoiceID int, in invoiceLineInput) (int, error) {
+    price, defaultRate, err := itemPricing(tx, invoiceID, in.ItemID)
+    if err != nil {
+        return 0, err
+    }
+    if in.UnitPrice != nil {
+        price = *in.UnitPrice
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO invoice_items (invoice_id, item_id, quantity, price, discount_type, discount_value)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, invoiceID, in.ItemID, in.Quantity, price, in.DiscountType, in.DiscountValue)
+    if err != nil {
+        return 0, err
+    }
+    lineID, _ := res.LastInsertId()
+
+    err = setLineTaxes(tx, int(lineID), lineTaxRateIDs(in.TaxRateIDs, defaultRate))
+    return int(lineID), err
+}
+
+// setLineTaxes replaces the taxes of a line with snapshots of the given
+// active rates. Amounts are filled in by recalculateInvoice.
+func setLineTaxes(tx *sql.Tx, lineID int, rateIDs []int) error {
+    _, err := tx.Exec("DELETE FROM invoice_item_taxes WHERE invoice_item_id = ?", lineID)
+    if err != nil {
+        return err
+    }
+
+    seen := map[int]bool{}
+    for _, rateID := range rateIDs {
+        if seen[rateID] {
+            continue
+        }
+        seen[rateID] = true
+
+        res, err := tx.Exec(`
+            INSERT INTO invoice_item_taxes (invoice_item_id, tax_rate_id, name, rate, compound)
+            SELECT ?, id, name, rate, compound
+            FROM tax_rates
+            WHERE id = ? AND active = TRUE
+        `, lineID, rateID)
+        if err != nil {
+            return err
+        }
+        if n, _ := res.RowsAffected(); n == 0 {
+            return errTaxRateNotFound
+        }
+    }
+    return nil
+}
+
+func replaceInvoiceLines(tx *sql.Tx, invoiceID int, lines []invoiceLineInput) error {
+    _, err := tx.Exec(`
+        DELETE t FROM invoice_item_taxes t
+        JOIN invoice_items ii ON ii.id = t.invoice_item_id
+        WHERE ii.invoice_id = ?
+    `, invoiceID)
+    if err != nil {
+        return err
+    }
+
+    _, err = tx.Exec("DELETE FROM invoice_items WHERE invoice_id = ?", invoiceID)
+    if err != nil {
+        return err
+    }
+    for _, line := range lines {
+        _, err = insertInvoiceLine(tx, invoiceID, line)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// recalculateInvoice prices every line through the discount and tax rules
+// and stores the line, tax and invoice totals.
+func recalculateInvoice(tx *sql.Tx, invoiceID int) error {
+    var inclusive bool
+    var discount pricing.Discount
+    err := tx.QueryRow(`
+        SELECT prices_include_tax, discount_type, discount_value
+        FROM invoices WHERE id = ?
+    `, invoiceID).Scan(&inclusive, &discount.Type, &discount.Value)
+    if err != nil {
+        return err
+    }
+
+    rows, err := tx.Query(`
+        SELECT id, price, quantity, discount_type, discount_value FROM invoice_items
+        WHERE invoice_id = ?
+        ORDER BY id
+    `, invoiceID)
+    if err != nil {
+        return err
+    }
+    var lineIDs []int
+    var lines []pricing.Line
+    for rows.Next() {
+        var id int
+        var l pricing.Line
+        if err := rows.Scan(&id, &l.UnitPrice, &l.Quantity, &l.Discount.Type, &l.Discount.Value); err != nil {
+            rows.Close()
+            return err
+        }
+        lineIDs = append(lineIDs, id)
+        lines = append(lines, l)
+    }
+    rows.Close()
+
+    taxes, err := getInvoiceLineTaxes(tx, invoiceID)
+    if err != nil {
+        return err
+    }
+    for i, id := range lineIDs {
+        for _, t := range taxes[id] {
+            lines[i].Rates = append(lines[i].Rates, pricing.Rate{
+                ID:       t.TaxRateID,
+                Name:     t.Name,
+                Percent:  t.Rate,
+                Compound: t.Compound,
+            })
+        }
+    }
+
+    result := pricing.Calculate(lines, inclusive, discount)
+
+    for i, id := range lineIDs {
+        lr := result.Lines[i]
+        _, err = tx.Exec(`
+            UPDATE invoice_items
+            SET discount_amount = ?, invoice_discount_amount = ?,
+                net_amount = ?, tax_amount = ?, line_total = ?
+            WHERE id = ?
+        `, lr.LineDiscount, lr.InvoiceDiscount, lr.Net, lr.Tax, lr.Gross, id)
+        if err != nil {
+            return err
+        }
+        for _, t := range lr.Taxes {
+            _, err = tx.Exec(`
+                UPDATE invoice_item_taxes SET taxable_amount = ?, tax_amount = ?
+                WHERE invoice_item_id = ? AND tax_rate_id = ?
+            `, t.Taxable, t.Amount, id, t.ID)
+            if err != nil {
+                return err
+            }
+        }
+    }
+
+    _, err = tx.Exec(`
+        UPDATE invoices
+        SET line_discount_total = ?, discount_amount = ?,
+            subtotal = ?, tax_total = ?, total_amount = ?
+        WHERE id = ?
+    `, result.LineDiscountTotal, result.InvoiceDiscount, result.Subtotal, result.TaxTotal, result.Total, invoiceID)
+    return err
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/pdf"
+
+	"github.com/gorilla/mux"
+)
+
+func GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    invoice, customer, err := loadInvoiceDocument(database.DB, id)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Invoice not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    company, err := loadCompanyProfile(database.DB)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/pdf")
+    w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", documentFilename(invoice.InvoiceNumber, "pdf")))
+    writeInvoicePDF(w, invoice, customer, company)
+}
+
+// loadInvoiceDocument loads everything printed on an invoice.
+func loadInvoiceDocument(q queryer, id int) (models.InvoiceDetail, models.Customer, error) {
+    invoice, err := loadInvoiceDetail(q, id)
+    if err != nil {
+        return invoice, models.Customer{}, err
+    }
+
+    var c models.Customer
+    err = q.QueryRow(`
+        SELECT id, name, email, address FROM customers WHERE id = ?
+    `, invoice.CustomerID).Scan(&c.ID, &c.Name, &c.Email, &c.Address)
+    return invoice, c, err
+}
+
+// invoice table columns: the description starts at the left margin and
+// the figures are right-aligned on these x positions
+const (
+    pdfMargin    = 50.0
+    pdfRight     = pdf.A4Width - pdfMargin
+    colQuantity  = 215.0
+    colUnitPrice = 285.0
+    colDiscount  = 345.0
+    colNet       = 410.0
+    colTax       = 475.0
+    colTotal     = pdfRight
+)
+
+func writeInvoicePDF(w io.Writer, inv models.InvoiceDetail, customer models.Customer, s models.CompanyProfile) error {
+    doc := pdf.New()
+    doc.Title = "Invoice " + inv.InvoiceNumber
+    page := doc.AddPage()
+
+    y := documentHeader(page, s, "INVOICE", [][2]string{
+        {"Invoice no.", inv.InvoiceNumber},
+        {"Issue date", displayDate(inv.IssueDate)},
+        {"Due date", displayDate(inv.DueDate)},
+        {"Status", strings.ReplaceAll(string(inv.Status), "_", " ")},
+        {"Currency", inv.Currency},
+    })
+    y = customerBlock(page, y+20, "Bill to", customer)
+
+    // Line table, repeating the header on every page
+    y = invoiceTableHeader(page, y)
+    for _, line := range inv.Items {
+        desc := pdf.Wrap(pdf.Regular, 8, line.ItemName, colQuantity-pdfMargin-30)
+        height := float64(len(desc))*10 + 4
+        if y+height > pdf.A4Height-pdfMargin-20 {
+            page = doc.AddPage()
+            y = invoiceTableHeader(page, pdfMargin+10)
+        }
+
+        for i, text := range desc {
+            page.Text(pdfMargin, y+float64(i)*10, pdf.Regular, 8, text)
+        }
+        page.TextRight(colQuantity, y, pdf.Regular, 8, strconv.Itoa(line.Quantity))
+        page.TextRight(colUnitPrice, y, pdf.Regular, 8, line.UnitPrice.Format())
+        page.TextRight(colDiscount, y, pdf.Regular, 8, (line.DiscountAmount+line.InvoiceDiscountAmount).Format())
+        page.TextRight(colNet, y, pdf.Regular, 8, line.NetAmount.Format())
+        page.TextRight(colTax, y, pdf.Regular, 8, line.TaxAmount.Format())
+        page.TextRight(colTotal, y, pdf.Regular, 8, line.LineTotal.Format())
+        y += height
+    }
+    page.Line(pdfMargin, y-6, pdfRight, y-6, 0.5)
+
+    // Totals
+    totals := [][2]string{}
+    if inv.LineDiscountTotal > 0 {
+        totals = append(totals, [2]string{"Line discounts", "-" + inv.LineDiscountTotal.Format()})
+    }
+    if inv.DiscountAmount > 0 {
+        totals = append(totals, [2]string{"Invoice discount", "-" + inv.DiscountAmount.Format()})
+    }
+    totals = append(totals, [2]string{"Subtotal", inv.Subtotal.Format()})
+    for _, t := range inv.Taxes {
+        totals = append(totals, [2]string{fmt.Sprintf("%s (%s%%)", t.Name, t.Rate.Format()), t.TaxAmount.Format()})
+    }
+    totals = append(totals, [2]string{"Total " + inv.Currency, inv.TotalAmount.Format()})
+    if inv.AmountPaid != 0 {
+        totals = append(totals, [2]string{"Paid", "-" + inv.AmountPaid.Format()})
+    }
+    if inv.AmountCredited != 0 {
+        totals = append(totals, [2]string{"Credited", "-" + inv.AmountCredited.Format()})
+    }
+    totals = append(totals, [2]string{"Balance due", inv.BalanceDue.Format()})
+
+    if y+float64(len(totals))*14+20 > pdf.A4Height-pdfMargin-20 {
+        page = doc.AddPage()
+        y = pdfMargin + 10
+    }
+    y += 8
+    for i, t := range totals {
+        font := pdf.Regular
+        if i == len(totals)-1 || strings.HasPrefix(t[0], "Total") {
+            font = pdf.Bold
+        }
+        page.TextRight(colNet, y, font, 10, t[0])
+        page.TextRight(colTotal, y, font, 10, t[1])
+        y += 14
+    }
+
+    // Payment instructions
+    if s.BankDetails != "" {
+        lines := pdf.Wrap(pdf.Regular, 9, s.BankDetails, pdfRight-pdfMargin)
+        if y+float64(len(lines))*11+40 > pdf.A4Height-pdfMargin-20 {
+            page = doc.AddPage()
+            y = pdfMargin
+        }
+        y += 24
+        page.Text(pdfMargin, y, pdf.Bold, 10, "Payment instructions")
+        y += 13
+        for _, line := range lines {
+            page.Text(pdfMargin, y, pdf.Regular, 9, line)
+            y += 11
+        }
+    }
+
+    // Footer terms
+    if s.FooterTerms != "" {
+        lines := pdf.Wrap(pdf.Regular, 8, s.FooterTerms, pdfRight-pdfMargin)
+        if y+float64(len(lines))*10+30 > pdf.A4Height-pdfMargin-20 {
+            page = doc.AddPage()
+            y = pdfMargin
+        }
+        y += 20
+        page.Line(pdfMargin, y-10, pdfRight, y-10, 0.5)
+        for _, line := range lines {
+            page.Text(pdfMargin, y, pdf.Regular, 8, line)
+            y += 10
+        }
+    }
+
+    addPageNumbers(doc)
+    _, err := doc.WriteTo(w)
+    return err
+}
+
+// documentHeader prints the seller on the left and the document title with
+// its details on the right, returning the y position below both.
+func documentHeader(page *pdf.Page, s models.CompanyProfile, title string, meta [][2]string) float64 {
+    y := pdfMargin + 12
+    name := s.LegalName
+    if name == "" {
+        name = title[:1] + strings.ToLower(title[1:])
+    }
+    page.Text(pdfMargin, y, pdf.Bold, 14, name)
+    y += 14
+    for _, line := range pdf.Wrap(pdf.Regular, 9, s.Address, 250) {
+        page.Text(pdfMargin, y, pdf.Regular, 9, line)
+        y += 11
+    }
+    if s.TaxID != "" {
+        page.Text(pdfMargin, y, pdf.Regular, 9, "Tax ID: "+s.TaxID)
+        y += 11
+    }
+    if s.Email != "" {
+        page.Text(pdfMargin, y, pdf.Regular, 9, s.Email)
+        y += 11
+    }
+    if s.Phone != "" {
+        page.Text(pdfMargin, y, pdf.Regular, 9, s.Phone)
+        y += 11
+    }
+
+    page.TextRight(pdfRight, pdfMargin+14, pdf.Bold, 20, title)
+    my := pdfMargin + 36
+    for _, m := range meta {
+        page.TextRight(pdfRight-110, my, pdf.Bold, 9, m[0])
+        page.TextRight(pdfRight, my, pdf.Regular, 9, m[1])
+        my += 12
+    }
+    if my > y {
+        y = my
+    }
+    return y
+}
+
+func customerBlock(page *pdf.Page, y float64, heading string, customer models.Customer) float64 {
+    page.Text(pdfMargin, y, pdf.Bold, 10, heading)
+    y += 13
+    page.Text(pdfMargin, y, pdf.Regular, 10, customer.Name)
+    y += 12
+    for _, line := range pdf.Wrap(pdf.Regular, 9, customer.Address, 250) {
+        page.Text(pdfMargin, y, pdf.Regular, 9, line)
+        y += 11
+    }
+    page.Text(pdfMargin, y, pdf.Regular, 9, customer.Email)
+    return y + 25
+}
+
+func invoiceTableHeader(page *pdf.Page, y float64) float64 {
+    page.FillRect(pdfMargin-4, y-11, pdfRight-pdfMargin+8, 16, 0.9)
+    page.Text(pdfMargin, y, pdf.Bold, 8, "Description")
+    page.TextRight(colQuantity, y, pdf.Bold, 8, "Qty")
+    page.TextRight(colUnitPrice, y, pdf.Bold, 8, "Unit price")
+    page.TextRight(colDiscount, y, pdf.Bold, 8, "Discount")
+    page.TextRight(colNet, y, pdf.Bold, 8, "Net")
+    page.TextRight(colTax, y, pdf.Bold, 8, "Tax")
+    page.TextRight(colTotal, y, pdf.Bold, 8, "Total")
+    return y + 20
+}
+
+func addPageNumbers(doc *pdf.Document) {
+    pages := doc.Pages()
+    for i, page := range pages {
+        page.TextCenter(pdf.A4Width/2, pdf.A4Height-pdfMargin/2, pdf.Regular, 8,
+            fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+    }
+}
+
+// displayDate trims the time the driver adds to DATE columns.
+func displayDate(s string) string {
+    if len(s) > 10 {
+        return s[:10]
+    }
+    return s
+}
+
+// documentFilename turns a document number such as INV/2026/10/00042 into
+// a file name.
+func documentFilename(number, ext string) string {
+    return strings.NewReplacer("/", "-", "\\", "-", " ", "_").Replace(number) + "." + ext
+}
\ No newline at end of file
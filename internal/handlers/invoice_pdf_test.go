@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"invoice-system/internal/models"
+)
+
+// pdfPages returns the inflated content stream of every page.
+func pdfPages(t *testing.T, doc []byte) []string {
+    t.Helper()
+    var pages []string
+    header := regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+    for _, m := range header.FindAllSubmatchIndex(doc, -1) {
+        n, _ := strconv.Atoi(string(doc[m[2]:m[3]]))
+        zr, err := zlib.NewReader(bytes.NewReader(doc[m[1] : m[1]+n]))
+        if err != nil {
+            t.Fatal(err)
+        }
+        content, err := io.ReadAll(zr)
+        if err != nil {
+            t.Fatal(err)
+        }
+        pages = append(pages, string(content))
+    }
+    return pages
+}
+
+func TestInvoicePDFPagination(t *testing.T) {
+    var inv models.InvoiceDetail
+    inv.InvoiceNumber = "INV/2024/10/00042"
+    inv.IssueDate = "2024-10-01"
+    inv.DueDate = "2024-10-31"
+    inv.Status = models.InvoiceIssued
+    inv.Currency = "IDR"
+    for i := 1; i <= 120; i++ {
+        inv.Items = append(inv.Items, models.InvoiceLine{
+            ItemName:  fmt.Sprintf("Item %03d", i),
+            Quantity:  1,
+            UnitPrice: 100000,
+            NetAmount: 100000,
+            LineTotal: 100000,
+        })
+    }
+    // A description without spaces is broken to fit its column
+    inv.Items[0].ItemName = "Item 001 " + strings.Repeat("X", 80)
+    customer := models.Customer{Name: "Toko (Maju)", Address: `Jl. Sudirman 1\2`}
+    company := models.CompanyProfile{LegalName: "PT Contoh Indonesia", BankDetails: "BCA 1234567890"}
+
+    var buf bytes.Buffer
+    if err := writeInvoicePDF(&buf, inv, customer, company); err != nil {
+        t.Fatal(err)
+    }
+    pages := pdfPages(t, buf.Bytes())
+    if len(pages) < 3 {
+        t.Fatalf("120 lines fit on %d pages, want at least 3", len(pages))
+    }
+    if !bytes.Contains(buf.Bytes(), []byte(fmt.Sprintf("/Count %d", len(pages)))) {
+        t.Errorf("page tree does not count %d pages", len(pages))
+    }
+
+    all := strings.Join(pages, "")
+    for i, p := range pages {
+        if want := fmt.Sprintf("(Page %d of %d) Tj", i+1, len(pages)); !strings.Contains(p, want) {
+            t.Errorf("page %d has no %q", i+1, want)
+        }
+        if i < len(pages)-1 && !strings.Contains(p, "(Description) Tj") {
+            t.Errorf("page %d does not repeat the table header", i+1)
+        }
+    }
+    for i := 2; i <= 120; i++ {
+        if n := strings.Count(all, fmt.Sprintf("(Item %03d) Tj", i)); n != 1 {
+            t.Errorf("Item %03d is printed %d times", i, n)
+        }
+    }
+    if strings.Contains(all, strings.Repeat("X", 80)) {
+        t.Error("long description was not broken")
+    }
+    if strings.Count(all, "X") != 80 {
+        t.Error("long description lost characters")
+    }
+    if !strings.Contains(all, `(Toko \(Maju\)) Tj`) || !strings.Contains(all, `(Jl. Sudirman 1\\2) Tj`) {
+        t.Error("customer block is not escaped")
+    }
+    if !strings.Contains(pages[len(pages)-1], "(Balance due) Tj") {
+        t.Error("totals are not on the last page")
+    }
+}
\ No newline at end of file
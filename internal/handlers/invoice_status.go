@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+
+	"github.com/gorilla/mux"
+)
+
+func IssueInvoice(w http.ResponseWriter, r *http.Request) {
+    changeInvoiceStatus(w, r, models.InvoiceIssued, audit.ActionIssue)
+}
+
+// VoidInvoice cancels an invoice for good. The invoice keeps its number
+// and its lines, but nothing is owed on it any more. Invoices with payments
+// have to be refunded first.
+func VoidInvoice(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req struct {
+        Reason string `json:"reason" validate:"required,max=500"`
+    }
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    before, err := loadInvoiceDetail(tx, id)
+    if err == sql.ErrNoRows {
+        err = errInvoiceNotFound
+    }
+    if err == nil {
+        err = voidInvoice(tx, id, actorFromRequest(r), req.Reason)
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, id, audit.ActionVoid, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeInvoiceError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    writeInvoiceDetail(w, id)
+}
+
+func voidInvoice(tx *sql.Tx, id int, actor, reason string) error {
+    err := transitionInvoice(tx, id, models.InvoiceVoid, actor, reason)
+    if err != nil {
+        return err
+    }
+
+    var paid money.Amount
+    err = tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM payments WHERE invoice_id = ?", id).Scan(&paid)
+    if err != nil {
+        return err
+    }
+    if paid != 0 {
+        return errInvoiceHasPayments
+    }
+
+    now := time.Now()
+    _, err = tx.Exec(`
+        UPDATE invoices SET void_reason = ?, voided_at = ?, updated_at = ?
+        WHERE id = ?
+    `, reason, now, now, id)
+    return err
+}
+
+func GetInvoiceStatusHistory(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query(`
+        SELECT id, invoice_id, from_status, to_status, changed_by, note, created_at
+        FROM invoice_status_history
+        WHERE invoice_id = ?
+        ORDER BY id
+    `, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    history := []models.InvoiceStatusChange{}
+    for rows.Next() {
+        var c models.InvoiceStatusChange
+        err := rows.Scan(&c.ID, &c.InvoiceID, &c.FromStatus, &c.ToStatus, &c.ChangedBy, &c.Note, &c.CreatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        history = append(history, c)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(history)
+}
+
+// changeInvoiceStatus backs the status action endpoints. The request body is
+// optional and may carry a note for the status history.
+func changeInvoiceStatus(w http.ResponseWriter, r *http.Request, to models.InvoiceStatus, action string) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req struct {
+        Note string `json:"note"`
+    }
+    if r.ContentLength != 0 {
+        err = json.NewDecoder(r.Body).Decode(&req)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    before, err := loadInvoiceDetail(tx, id)
+    if err == sql.ErrNoRows {
+        err = errInvoiceNotFound
+    }
+    if err == nil {
+        err = transitionInvoice(tx, id, to, actorFromRequest(r), req.Note)
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, id, action, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeInvoiceError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    writeInvoiceDetail(w, id)
+}
+
+// transitionInvoice moves the invoice to status to if the transition table
+// allows it and records the change in the status history.
+func transitionInvoice(tx *sql.Tx, id int, to models.InvoiceStatus, actor, note string) error {
+    var from models.InvoiceStatus
+    err := tx.QueryRow(`
+        SELECT status FROM invoices WHERE id = ? AND deleted_at IS NULL FOR UPDATE
+    `, id).Scan(&from)
+    if err == sql.ErrNoRows {
+        return errInvoiceNotFound
+    } else if err != nil {
+        return err
+    }
+    if !from.CanTransitionTo(to) {
+        return errIllegalTransition
+    }
+
+    _, err = tx.Exec(`
+        UPDATE invoices SET status = ?, updated_at = ?
+        WHERE id = ?
+    `, to, time.Now(), id)
+    if err != nil {
+        return err
+    }
+
+    // Issuing books the invoice at the rate of its issue date
+    if to == models.InvoiceIssued {
+        err = fixExchangeRate(tx, id)
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err = tx.Exec(`
+        INSERT INTO invoice_status_history (invoice_id, from_status, to_status, changed_by, note)
+        VALUES (?, ?, ?, ?, ?)
+    `, id, from, to, actor, note)
+    return err
+}
+
+// maxActorLength matches the width of the changed_by and actor columns.
+const maxActorLength = 100
+
+// actorFromRequest identifies who performed a change. There is no
+// authentication yet, so callers identify themselves with X-Actor, which
+// is cut to fit the columns it is stored in.
+func actorFromRequest(r *http.Request) string {
+    actor := strings.TrimSpace(r.Header.Get("X-Actor"))
+    if actor == "" {
+        return "anonymous"
+    }
+    if runes := []rune(actor); len(runes) > maxActorLength {
+        actor = string(runes[:maxActorLength])
+    }
+    return actor
+}
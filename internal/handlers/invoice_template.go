@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/templates"
+
+	"github.com/gorilla/mux"
+)
+
+func GetInvoiceTemplates(w http.ResponseWriter, r *http.Request) {
+    list, err := templates.List(database.DB)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(list)
+}
+
+func GetInvoiceTemplate(w http.ResponseWriter, r *http.Request) {
+    t, err := templates.Load(database.DB, mux.Vars(r)["name"])
+    if err == templates.ErrNotFound {
+        http.Error(w, "Template not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(t)
+}
+
+// SaveInvoiceTemplate creates or replaces a layout in the database. The
+// body must compile, so a broken layout is refused before it is stored.
+func SaveInvoiceTemplate(w http.ResponseWriter, r *http.Request) {
+    name := mux.Vars(r)["name"]
+    if err := templates.ValidateName(name); err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    var req models.InvoiceTemplate
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    _, err = templates.Parse(name, req.Body)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    _, err = database.DB.Exec(`
+        INSERT INTO invoice_templates (name, body) VALUES (?, ?)
+        ON DUPLICATE KEY UPDATE body = VALUES(body)
+    `, name, req.Body)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    req.Name = name
+    req.Source = templates.SourceDatabase
+    req.UpdatedAt = time.Now()
+    json.NewEncoder(w).Encode(req)
+}
+
+// DeleteInvoiceTemplate removes a stored layout; a file or built-in layout
+// of the same name is used again afterwards.
+func DeleteInvoiceTemplate(w http.ResponseWriter, r *http.Request) {
+    res, err := database.DB.Exec("DELETE FROM invoice_templates WHERE name = ?", mux.Vars(r)["name"])
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    if n, _ := res.RowsAffected(); n == 0 {
+        http.Error(w, "Template not found", http.StatusNotFound)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func GetInvoiceHTML(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    name := r.URL.Query().Get("template")
+    if name == "" {
+        name = templates.DefaultName
+    }
+    t, err := templates.Load(database.DB, name)
+    if err == templates.ErrNotFound {
+        http.Error(w, "Template not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    data, err := invoiceTemplateData(database.DB, id)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Invoice not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    var buf bytes.Buffer
+    err = templates.Render(&buf, t, data)
+    if err != nil {
+        http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    buf.WriteTo(w)
+}
+
+// invoiceTemplateData loads what a layout is executed with.
+func invoiceTemplateData(q queryer, id int) (templates.Data, error) {
+    invoice, customer, err := loadInvoiceDocument(q, id)
+    if err != nil {
+        return templates.Data{}, err
+    }
+    company, err := loadCompanyProfile(q)
+    if err != nil {
+        return templates.Data{}, err
+    }
+
+    return templates.Data{
+        Invoice:  invoice,
+        Customer: customer,
+        Company:  company,
+    }, nil
+}
\ No newline at end of file
@@ -7,7 +7,9 @@ import (
 	"strconv"
 	"time"
 
+	"invoice-system/internal/audit"
 	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
 	"invoice-system/internal/models"
 
 	"github.com/gorilla/mux"
@@ -24,11 +26,16 @@ func GetItems(w http.ResponseWriter, r *http.Request) {
     }
     offset := (page - 1) * limit
 
-    rows, err := database.DB.Query(`
-        SELECT id, name, price, created_at, updated_at
+    query := `
+        SELECT id, name, price, currency, tax_rate_id, created_at, updated_at, deleted_at
         FROM items
-        LIMIT ? OFFSET ?
-    `, limit, offset)
+    `
+    if r.URL.Query().Get("include_deleted") != "true" {
+        query += " WHERE deleted_at IS NULL"
+    }
+    query += " LIMIT ? OFFSET ?"
+
+    rows, err := database.DB.Query(query, limit, offset)
     if err != nil {
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
@@ -38,7 +45,7 @@ func GetItems(w http.ResponseWriter, r *http.Request) {
     var items []models.Item
     for rows.Next() {
         var i models.Item
-        err := rows.Scan(&i.ID, &i.Name, &i.Price, &i.CreatedAt, &i.UpdatedAt)
+        err := rows.Scan(&i.ID, &i.Name, &i.Price, &i.Currency, &i.TaxRateID, &i.CreatedAt, &i.UpdatedAt, &i.DeletedAt)
         if err != nil {
             http.Error(w, "Scan error", http.StatusInternalServerError)
             return
@@ -63,23 +70,45 @@ func CreateItem(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
         return
     }
+    if req.Currency == "" {
+        req.Currency = fx.BaseCurrency
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
 
-    res, err := database.DB.Exec(`
-        INSERT INTO items (name, price)
-        VALUES (?, ?)
-    `, req.Name, req.Price)
+    res, err := tx.Exec(`
+        INSERT INTO items (name, price, currency, tax_rate_id)
+        VALUES (?, ?, ?, ?)
+    `, req.Name, req.Price, req.Currency, req.TaxRateID)
     if err != nil {
+        tx.Rollback()
         http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
         return
     }
 
     id, _ := res.LastInsertId()
-    req.ID = int(id)
-    req.CreatedAt = time.Now()
-    req.UpdatedAt = time.Now()
+    item, err := loadItem(tx, int(id))
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityItem, item.ID, audit.ActionCreate, nil, item)
+    }
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
 
     w.WriteHeader(http.StatusCreated)
-    json.NewEncoder(w).Encode(req)
+    json.NewEncoder(w).Encode(item)
 }
 
 func GetItem(w http.ResponseWriter, r *http.Request) {
@@ -90,18 +119,7 @@ func GetItem(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    var item models.Item
-    err = database.DB.QueryRow(`
-        SELECT id, name, price, created_at, updated_at
-        FROM items
-        WHERE id = ?
-    `, id).Scan(
-        &item.ID,
-        &item.Name,
-        &item.Price,
-        &item.CreatedAt,
-        &item.UpdatedAt,
-    )
+    item, err := loadItem(database.DB, id)
     if err == sql.ErrNoRows {
         http.Error(w, "Item not found", http.StatusNotFound)
         return
@@ -133,22 +151,59 @@ func UpdateItem(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
         return
     }
+    if req.Currency == "" {
+        req.Currency = fx.BaseCurrency
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    before, err := loadItem(tx, id)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        http.Error(w, "Item not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
 
-    _, err = database.DB.Exec(`
+    _, err = tx.Exec(`
         UPDATE items
-        SET name = ?, price = ?, updated_at = ?
+        SET name = ?, price = ?, currency = ?, tax_rate_id = ?, updated_at = ?
         WHERE id = ?
-    `, req.Name, req.Price, time.Now(), id)
+    `, req.Name, req.Price, req.Currency, req.TaxRateID, time.Now(), id)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    after, err := loadItem(tx, id)
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityItem, id, audit.ActionUpdate, before, after)
+    }
     if err != nil {
+        tx.Rollback()
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
     }
 
-    req.ID = id
-    req.UpdatedAt = time.Now()
-    json.NewEncoder(w).Encode(req)
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(after)
 }
 
+// DeleteItem takes the item out of the catalogue; lines already priced
+// with it are unaffected.
 func DeleteItem(w http.ResponseWriter, r *http.Request) {
     params := mux.Vars(r)
     id, err := strconv.Atoi(params["id"])
@@ -157,11 +212,83 @@ func DeleteItem(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    _, err = database.DB.Exec("DELETE FROM items WHERE id = ?", id)
-    if err != nil {
+    err = setItemDeleted(r, id, true)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Item not found", http.StatusNotFound)
+        return
+    } else if err != nil {
         http.Error(w, "Database error", http.StatusInternalServerError)
         return
     }
 
     w.WriteHeader(http.StatusNoContent)
+}
+
+func RestoreItem(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    err = setItemDeleted(r, id, false)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Item not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    GetItem(w, r)
+}
+
+func setItemDeleted(r *http.Request, id int, deleted bool) error {
+    tx, err := database.DB.Begin()
+    if err != nil {
+        return err
+    }
+
+    before, err := loadItem(tx, id)
+    if err == nil && (before.DeletedAt != nil) == deleted {
+        tx.Rollback()
+        return nil
+    }
+
+    var action string
+    var after models.Item
+    if err == nil {
+        action, err = setDeletedAt(tx, "items", id, deleted)
+    }
+    if err == nil {
+        after, err = loadItem(tx, id)
+    }
+    if err == nil {
+        err = recordAudit(tx, r, audit.EntityItem, id, action, before, after)
+    }
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+func loadItem(q queryer, id int) (models.Item, error) {
+    var item models.Item
+    err := q.QueryRow(`
+        SELECT id, name, price, currency, tax_rate_id, created_at, updated_at, deleted_at
+        FROM items
+        WHERE id = ?
+    `, id).Scan(
+        &item.ID,
+        &item.Name,
+        &item.Price,
+        &item.Currency,
+        &item.TaxRateID,
+        &item.CreatedAt,
+        &item.UpdatedAt,
+        &item.DeletedAt,
+    )
+    return item, err
 }
\ No newline at end of file
@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/latefee"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/terms"
+
+	"github.com/gorilla/mux"
+)
+
+const lateFeePolicyColumns = `
+    id, name, fee_type, amount, rate, grace_days, max_amount, apply_as, fee_item_id,
+    active, created_at, updated_at
+`
+
+func GetLateFeePolicies(w http.ResponseWriter, r *http.Request) {
+    query := "SELECT " + lateFeePolicyColumns + " FROM late_fee_policies"
+    if r.URL.Query().Get("active") == "true" {
+        query += " WHERE active = TRUE"
+    }
+    query += " ORDER BY id"
+
+    rows, err := database.DB.Query(query)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    policies := []models.LateFeePolicy{}
+    for rows.Next() {
+        var p models.LateFeePolicy
+        err := scanLateFeePolicy(rows, &p)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        policies = append(policies, p)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(policies)
+}
+
+func CreateLateFeePolicy(w http.ResponseWriter, r *http.Request) {
+    var req models.LateFeePolicy
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err == nil {
+        err = feePolicy(req).Validate()
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    res, err := database.DB.Exec(`
+        INSERT INTO late_fee_policies (name, fee_type, amount, rate, grace_days, max_amount,
+            apply_as, fee_item_id, active)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, TRUE)
+    `, req.Name, req.FeeType, req.Amount, req.Rate, req.GraceDays, req.MaxAmount, req.ApplyAs, req.FeeItemID)
+    if err != nil {
+        http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    id, _ := res.LastInsertId()
+    req.ID = int(id)
+    req.Active = true
+    req.CreatedAt = time.Now()
+    req.UpdatedAt = time.Now()
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(req)
+}
+
+func GetLateFeePolicy(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    p, err := loadLateFeePolicy(database.DB, id)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Late fee policy not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(p)
+}
+
+// UpdateLateFeePolicy changes the terms of future assessments; fees already
+// charged keep the terms they were computed with.
+func UpdateLateFeePolicy(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req models.LateFeePolicy
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err == nil {
+        err = feePolicy(req).Validate()
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    _, err = database.DB.Exec(`
+        UPDATE late_fee_policies
+        SET name = ?, fee_type = ?, amount = ?, rate = ?, grace_days = ?, max_amount = ?,
+            apply_as = ?, fee_item_id = ?, active = ?, updated_at = ?
+        WHERE id = ?
+    `, req.Name, req.FeeType, req.Amount, req.Rate, req.GraceDays, req.MaxAmount,
+        req.ApplyAs, req.FeeItemID, req.Active, time.Now(), id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    req.ID = id
+    req.UpdatedAt = time.Now()
+    json.NewEncoder(w).Encode(req)
+}
+
+func GetInvoiceLateFees(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query(`
+        SELECT id, invoice_id, policy_id, fee_type, amount, rate, grace_days, max_amount,
+            DATE_FORMAT(assessed_on, '%Y-%m-%d'), days_late, base_amount, fee_amount,
+            total_accrued, invoice_item_id, fee_invoice_id, created_at
+        FROM late_fees
+        WHERE invoice_id = ?
+        ORDER BY assessed_on
+    `, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    fees := []models.LateFee{}
+    for rows.Next() {
+        var f models.LateFee
+        err := rows.Scan(&f.ID, &f.InvoiceID, &f.PolicyID, &f.FeeType, &f.Amount, &f.Rate,
+            &f.GraceDays, &f.MaxAmount, &f.AssessedOn, &f.DaysLate, &f.BaseAmount,
+            &f.FeeAmount, &f.TotalAccrued, &f.InvoiceItemID, &f.FeeInvoiceID, &f.CreatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        fees = append(fees, f)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(fees)
+}
+
+// ProcessOverdueInvoices marks unpaid invoices past their due date as
+// overdue and then charges the late fees owed on overdue invoices.
+func ProcessOverdueInvoices(now time.Time) error {
+    today := now.Format("2006-01-02")
+
+    ids, err := queryIDs(database.DB, `
+        SELECT id FROM invoices
+        WHERE status IN ('issued', 'sent', 'partially_paid') AND due_date < ?
+        ORDER BY id
+    `, today)
+    if err != nil {
+        return err
+    }
+
+    var errs []error
+    for _, id := range ids {
+        if err := markInvoiceOverdue(id); err != nil {
+            errs = append(errs, fmt.Errorf("invoice %d: %w", id, err))
+        }
+    }
+
+    ids, err = queryIDs(database.DB, `
+        SELECT i.id FROM invoices i
+        WHERE i.status = 'overdue' AND i.late_fee_policy_id IS NOT NULL
+            AND NOT EXISTS (SELECT 1 FROM late_fees f WHERE f.invoice_id = i.id AND f.assessed_on = ?)
+        ORDER BY i.id
+    `, today)
+    if err != nil {
+        return errors.Join(append(errs, err)...)
+    }
+
+    for _, id := range ids {
+        if err := assessLateFee(id, today); err != nil {
+            errs = append(errs, fmt.Errorf("late fee on invoice %d: %w", id, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+func markInvoiceOverdue(id int) error {
+    tx, err := database.DB.Begin()
+    if err != nil {
+        return err
+    }
+
+    before, err := loadInvoiceDetail(tx, id)
+    if err == nil && before.BalanceDue > 0 && before.Status.CanTransitionTo(models.InvoiceOverdue) {
+        err = transitionInvoice(tx, id, models.InvoiceOverdue, audit.SystemActor, "past due date")
+        if err == nil {
+            err = auditInvoiceJob(tx, id, audit.ActionOverdue, before)
+        }
+    }
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+// assessLateFee charges what the invoice's policy has accrued on its unpaid
+// balance since the last assessment, either on a fee line of the invoice
+// itself or on a separate fee invoice.
+func assessLateFee(id int, today string) error {
+    tx, err := database.DB.Begin()
+    if err != nil {
+        return err
+    }
+
+    var dueDate string
+    err = tx.QueryRow("SELECT DATE_FORMAT(due_date, '%Y-%m-%d') FROM invoices WHERE id = ? FOR UPDATE", id).Scan(&dueDate)
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    before, err := loadInvoiceDetail(tx, id)
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    inv := before.Invoice
+    if inv.Status != models.InvoiceOverdue || inv.LateFeePolicyID == nil {
+        tx.Rollback()
+        return nil
+    }
+
+    policy, err := loadLateFeePolicy(tx, *inv.LateFeePolicyID)
+    if err != nil || !policy.Active {
+        tx.Rollback()
+        return err
+    }
+
+    // Fees on a fee line are part of the balance but do not accrue fees
+    var charged, lineCharged money.Amount
+    var since int
+    var feeLineID sql.NullInt64
+    err = tx.QueryRow(`
+        SELECT COALESCE(SUM(fee_amount), 0),
+            COALESCE(SUM(CASE WHEN invoice_item_id IS NOT NULL THEN fee_amount END), 0),
+            COALESCE(MAX(days_late), 0), MAX(invoice_item_id)
+        FROM late_fees WHERE invoice_id = ?
+    `, id).Scan(&charged, &lineCharged, &since, &feeLineID)
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+
+    due, _ := time.Parse("2006-01-02", dueDate)
+    day, _ := time.Parse("2006-01-02", today)
+    daysLate := int(day.Sub(due).Hours() / 24)
+    base := money.Max(inv.BalanceDue-lineCharged, 0)
+
+    fee := feePolicy(policy).Charge(base, daysLate, since, charged)
+    accrued := charged + fee
+    if fee <= 0 {
+        tx.Rollback()
+        return nil
+    }
+
+    var lineID, feeInvoiceID *int
+    switch policy.ApplyAs {
+    case latefee.ApplyAsLine:
+        lineID, err = chargeFeeLine(tx, id, policy, feeLineID, fee)
+    case latefee.ApplyAsInvoice:
+        feeInvoiceID, err = chargeFeeInvoice(tx, inv, policy, fee, today)
+    }
+    if err == nil && lineID != nil {
+        err = auditInvoiceJob(tx, id, audit.ActionLateFee, before)
+    }
+    if err == nil && feeInvoiceID != nil {
+        err = auditInvoiceJob(tx, *feeInvoiceID, audit.ActionCreate, nil)
+    }
+    if err == nil {
+        _, err = tx.Exec(`
+            INSERT INTO late_fees (invoice_id, policy_id, fee_type, amount, rate, grace_days,
+                max_amount, assessed_on, days_late, base_amount, fee_amount, total_accrued,
+                invoice_item_id, fee_invoice_id)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        `, id, policy.ID, policy.FeeType, policy.Amount, policy.Rate, policy.GraceDays,
+            policy.MaxAmount, today, daysLate, base, fee, accrued, lineID, feeInvoiceID)
+    }
+    if err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+// chargeFeeLine adds fee to the invoice's untaxed fee line, creating it on
+// the first assessment, without repricing the other lines.
+func chargeFeeLine(tx *sql.Tx, invoiceID int, policy models.LateFeePolicy, feeLineID sql.NullInt64, fee money.Amount) (*int, error) {
+    var lineID int
+    if feeLineID.Valid {
+        lineID = int(feeLineID.Int64)
+        _, err := tx.Exec(`
+            UPDATE invoice_items SET price = price + ?, updated_at = ?
+            WHERE id = ?
+        `, fee, time.Now(), lineID)
+        if err != nil {
+            return nil, err
+        }
+    } else {
+        var err error
+        lineID, err = insertInvoiceLine(tx, invoiceID, invoiceLineInput{
+            ItemID:     policy.FeeItemID,
+            Quantity:   1,
+            TaxRateIDs: []int{},
+            UnitPrice:  &fee,
+        })
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    // The fee goes onto the totals as it is. Repricing the invoice would run
+    // the invoice discount over the fee and over lines already issued.
+    _, err := tx.Exec(`
+        UPDATE invoice_items
+        SET discount_amount = 0, invoice_discount_amount = 0,
+            net_amount = price, tax_amount = 0, line_total = price
+        WHERE id = ?
+    `, lineID)
+    if err == nil {
+        _, err = tx.Exec(`
+            UPDATE invoices
+            SET subtotal = subtotal + ?, total_amount = total_amount + ?
+            WHERE id = ?
+        `, fee, fee, invoiceID)
+    }
+    return &lineID, err
+}
+
+// chargeFeeInvoice bills fee on a new issued invoice due immediately. Fee
+// invoices carry no late fee policy of their own.
+func chargeFeeInvoice(tx *sql.Tx, inv models.Invoice, policy models.LateFeePolicy, fee money.Amount, today string) (*int, error) {
+    feeInvoiceID, err := createInvoice(tx, invoiceInput{
+        CustomerID:   inv.CustomerID,
+        IssueDate:    today,
+        PaymentTerms: string(terms.DueOnReceipt),
+        Currency:     inv.Currency,
+        Items: []invoiceLineInput{{
+            ItemID:     policy.FeeItemID,
+            Quantity:   1,
+            TaxRateIDs: []int{},
+            UnitPrice:  &fee,
+        }},
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    _, err = tx.Exec("UPDATE invoices SET late_fee_policy_id = NULL WHERE id = ?", feeInvoiceID)
+    if err == nil {
+        err = transitionInvoice(tx, feeInvoiceID, models.InvoiceIssued, audit.SystemActor, "late fee for invoice "+inv.InvoiceNumber)
+    }
+    return &feeInvoiceID, err
+}
+
+func feePolicy(p models.LateFeePolicy) latefee.Policy {
+    return latefee.Policy{
+        Type:      p.FeeType,
+        Amount:    p.Amount,
+        Rate:      p.Rate,
+        GraceDays: p.GraceDays,
+        Cap:       p.MaxAmount,
+    }
+}
+
+func scanLateFeePolicy(row rowScanner, p *models.LateFeePolicy) error {
+    return row.Scan(
+        &p.ID,
+        &p.Name,
+        &p.FeeType,
+        &p.Amount,
+        &p.Rate,
+        &p.GraceDays,
+        &p.MaxAmount,
+        &p.ApplyAs,
+        &p.FeeItemID,
+        &p.Active,
+        &p.CreatedAt,
+        &p.UpdatedAt,
+    )
+}
+
+func loadLateFeePolicy(q queryer, id int) (models.LateFeePolicy, error) {
+    var p models.LateFeePolicy
+    err := scanLateFeePolicy(q.QueryRow("SELECT "+lateFeePolicyColumns+" FROM late_fee_policies WHERE id = ?", id), &p)
+    return p, err
+}
+
+// queryIDs returns the ids selected by a query.
+func queryIDs(q queryer, query string, args ...interface{}) ([]int, error) {
+    rows, err := q.Query(query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var ids []int
+    for rows.Next() {
+        var id int
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids = append(ids, id)
+    }
+    return ids, rows.Err()
+}
\ No newline at end of file
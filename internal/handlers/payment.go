@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
+	"invoice-system/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+    errInvoiceNotPayable = errors.New("invoice does not accept payments")
+    errOverpayment       = errors.New("payment exceeds balance due")
+)
+
+func GetPayments(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query(`
+        SELECT id, invoice_id, credit_note_id, amount, exchange_rate, paid_at, method, reference, created_at
+        FROM payments
+        WHERE invoice_id = ?
+        ORDER BY paid_at, id
+    `, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    payments := []models.Payment{}
+    for rows.Next() {
+        var p models.Payment
+        var creditNoteID sql.NullInt64
+        err := rows.Scan(&p.ID, &p.InvoiceID, &creditNoteID, &p.Amount, &p.ExchangeRate, &p.PaidAt, &p.Method, &p.Reference, &p.CreatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        if creditNoteID.Valid {
+            cn := int(creditNoteID.Int64)
+            p.CreditNoteID = &cn
+        }
+        payments = append(payments, p)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(payments)
+}
+
+func CreatePayment(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req models.Payment
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    if req.CreditNoteID != nil {
+        http.Error(w, "Validation error: record refunds through POST /api/credit-notes/{id}/refunds", http.StatusBadRequest)
+        return
+    }
+    if req.PaidAt == "" {
+        req.PaidAt = time.Now().Format("2006-01-02")
+    } else if _, err := time.Parse("2006-01-02", req.PaidAt); err != nil {
+        http.Error(w, "Validation error: paid_at must be YYYY-MM-DD", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    var paymentID int
+    before, err := loadInvoiceDetail(tx, id)
+    if err == sql.ErrNoRows {
+        err = errInvoiceNotFound
+    }
+    if err == nil {
+        paymentID, err = recordPayment(tx, id, &req, actorFromRequest(r))
+    }
+    if err == nil {
+        err = auditInvoiceChange(tx, r, id, audit.ActionPay, before)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeInvoiceError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    req.ID = paymentID
+    req.InvoiceID = id
+    req.CreatedAt = time.Now()
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(req)
+}
+
+// recordPayment adds p to the ledger of a payable invoice and derives the
+// invoice status from the new payment sum.
+func recordPayment(tx *sql.Tx, invoiceID int, p *models.Payment, actor string) (int, error) {
+    // Lock the invoice so concurrent payments cannot both fit the balance
+    var locked int
+    err := tx.QueryRow("SELECT id FROM invoices WHERE id = ? FOR UPDATE", invoiceID).Scan(&locked)
+    if err == sql.ErrNoRows {
+        return 0, errInvoiceNotFound
+    } else if err != nil {
+        return 0, err
+    }
+
+    inv, err := loadInvoice(tx, invoiceID)
+    if err != nil {
+        return 0, err
+    }
+    if !inv.Status.Payable() {
+        return 0, errInvoiceNotPayable
+    }
+    if p.Amount > inv.BalanceDue {
+        return 0, errOverpayment
+    }
+    err = paymentExchangeRate(tx, inv, p)
+    if err != nil {
+        return 0, err
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO payments (invoice_id, amount, exchange_rate, paid_at, method, reference)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, invoiceID, p.Amount, p.ExchangeRate, p.PaidAt, p.Method, p.Reference)
+    if err != nil {
+        return 0, err
+    }
+    paymentID, _ := res.LastInsertId()
+
+    err = settleInvoiceStatus(tx, invoiceID, actor)
+    return int(paymentID), err
+}
+
+// settleInvoiceStatus moves the invoice to paid or partially_paid according
+// to the amount paid and credited against its total. An invoice settled by
+// credit notes alone is closed as credited rather than paid.
+func settleInvoiceStatus(tx *sql.Tx, invoiceID int, actor string) error {
+    inv, err := loadInvoice(tx, invoiceID)
+    if err != nil {
+        return err
+    }
+
+    target, note := models.InvoiceStatus(""), "derived from payments"
+    switch {
+    case inv.BalanceDue <= 0 && inv.AmountPaid > 0:
+        target = models.InvoicePaid
+    case inv.BalanceDue <= 0:
+        target, note = models.InvoiceCredited, "credited"
+    case inv.AmountPaid > 0:
+        target = models.InvoicePartiallyPaid
+    default:
+        return nil
+    }
+    if inv.Status == target || !inv.Status.CanTransitionTo(target) {
+        return nil
+    }
+    return transitionInvoice(tx, invoiceID, target, actor, note)
+}
+
+// paymentExchangeRate looks up the rate of the invoice currency on the
+// payment date unless the payment states the rate it was settled at.
+func paymentExchangeRate(tx *sql.Tx, inv models.Invoice, p *models.Payment) error {
+    if p.ExchangeRate != 0 {
+        return nil
+    }
+    rate, err := fx.RateOn(tx, inv.Currency, p.PaidAt)
+    p.ExchangeRate = rate
+    return err
+}
\ No newline at end of file
@@ -0,0 +1,631 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/numbering"
+	"invoice-system/internal/pricing"
+
+	"github.com/gorilla/mux"
+)
+
+// quoteValidityDays is how long a quote stays open when the request leaves
+// out the expiry date.
+const quoteValidityDays = 30
+
+var (
+    errQuoteNotFound          = errors.New("quote not found")
+    errQuoteNotEditable       = errors.New("quote is not editable")
+    errIllegalQuoteTransition = errors.New("illegal quote status transition")
+    errQuoteNotAccepted       = errors.New("quote has not been accepted")
+    errQuoteExpired           = errors.New("quote has expired")
+    errQuoteConverted         = errors.New("quote has already been converted")
+)
+
+type quoteInput struct {
+    CustomerID       int                `json:"customer_id" validate:"required"`
+    IssueDate        string             `json:"issue_date" validate:"required"`
+    // ExpiryDate defaults to quoteValidityDays after the issue date.
+    ExpiryDate       string             `json:"expiry_date"`
+    Currency         string             `json:"currency" validate:"omitempty,iso4217"`
+    PricesIncludeTax bool               `json:"prices_include_tax"`
+    DiscountType     string             `json:"discount_type"`
+    DiscountValue    money.Rate         `json:"discount_value"`
+    Notes            string             `json:"notes"`
+    Items            []invoiceLineInput `json:"items" validate:"required,min=1,dive"`
+}
+
+func (in quoteInput) validate() error {
+    err := validate.Struct(in)
+    if err == nil {
+        err = pricing.Discount{Type: in.DiscountType, Value: in.DiscountValue}.Validate()
+    }
+    if err == nil {
+        err = validateLineDiscounts(in.Items)
+    }
+    return err
+}
+
+func GetQuotes(w http.ResponseWriter, r *http.Request) {
+    status := r.URL.Query().Get("status")
+    customerID := r.URL.Query().Get("customer_id")
+
+    page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+    if page < 1 {
+        page = 1
+    }
+    limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+    if limit < 1 {
+        limit = 10
+    }
+    offset := (page - 1) * limit
+
+    query := "SELECT " + quoteColumns + " FROM quotes q"
+    var args []interface{}
+    clauses := []string{}
+
+    if status != "" {
+        clauses = append(clauses, "q.status = ?")
+        args = append(args, status)
+    }
+    if customerID != "" {
+        clauses = append(clauses, "q.customer_id = ?")
+        args = append(args, customerID)
+    }
+
+    if len(clauses) > 0 {
+        query += " WHERE " + joinClauses(clauses, " AND ")
+    }
+
+    query += " ORDER BY q.id DESC LIMIT ? OFFSET ?"
+    args = append(args, limit, offset)
+
+    rows, err := database.DB.Query(query, args...)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    quotes := []models.Quote{}
+    for rows.Next() {
+        var q models.Quote
+        err := scanQuote(rows, &q)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        quotes = append(quotes, q)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(quotes)
+}
+
+func CreateQuote(w http.ResponseWriter, r *http.Request) {
+    var req quoteInput
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = req.validate()
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    id, err := createQuote(tx, req)
+    if err != nil {
+        tx.Rollback()
+        writeQuoteError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    quote, err := loadQuoteDetail(database.DB, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(quote)
+}
+
+// createQuote inserts a draft quote with its lines and totals and returns
+// its id.
+func createQuote(tx *sql.Tx, in quoteInput) (int, error) {
+    in, rate, err := resolveQuoteInput(tx, in)
+    if err != nil {
+        return 0, err
+    }
+
+    issued, _ := time.Parse("2006-01-02", in.IssueDate)
+    number, err := numbering.Next(tx, numbering.QuoteSequence, issued)
+    if err != nil {
+        return 0, err
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO quotes (quote_number, customer_id, issue_date, expiry_date, currency,
+            exchange_rate, prices_include_tax, discount_type, discount_value, notes)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, number, in.CustomerID, in.IssueDate, in.ExpiryDate, in.Currency,
+        rate, in.PricesIncludeTax, in.DiscountType, in.DiscountValue, in.Notes)
+    if err != nil {
+        return 0, err
+    }
+    id, _ := res.LastInsertId()
+
+    return int(id), insertQuoteLines(tx, int(id), in.Items)
+}
+
+func GetQuote(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    quote, err := loadQuoteDetail(database.DB, id)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Quote not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(quote)
+}
+
+// UpdateQuote replaces a draft quote, lines included. Lines are priced
+// again at the current item prices.
+func UpdateQuote(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req quoteInput
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = req.validate()
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    err = lockEditableQuote(tx, id)
+    if err == nil {
+        err = deleteQuoteLines(tx, id)
+    }
+    if err == nil {
+        err = updateQuote(tx, id, req)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeQuoteError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    writeQuoteDetail(w, id)
+}
+
+// updateQuote writes the request over a quote whose lines have been
+// deleted.
+func updateQuote(tx *sql.Tx, id int, in quoteInput) error {
+    in, rate, err := resolveQuoteInput(tx, in)
+    if err != nil {
+        return err
+    }
+
+    _, err = tx.Exec(`
+        UPDATE quotes
+        SET customer_id = ?, issue_date = ?, expiry_date = ?, currency = ?, exchange_rate = ?,
+            prices_include_tax = ?, discount_type = ?, discount_value = ?, notes = ?, updated_at = ?
+        WHERE id = ?
+    `, in.CustomerID, in.IssueDate, in.ExpiryDate, in.Currency, rate,
+        in.PricesIncludeTax, in.DiscountType, in.DiscountValue, in.Notes, time.Now(), id)
+    if err != nil {
+        return err
+    }
+    return insertQuoteLines(tx, id, in.Items)
+}
+
+// resolveQuoteInput fills in the defaults the request leaves out and
+// returns the rate of the quote currency on the issue date, which new
+// lines are priced at.
+func resolveQuoteInput(tx *sql.Tx, in quoteInput) (quoteInput, money.ExchangeRate, error) {
+    // Quote in the customer's currency unless the request chooses one
+    var currency string
+    err := tx.QueryRow(`
+        SELECT currency FROM customers WHERE id = ? AND deleted_at IS NULL
+    `, in.CustomerID).Scan(&currency)
+    if err == sql.ErrNoRows {
+        return in, 0, errCustomerNotFound
+    } else if err != nil {
+        return in, 0, err
+    }
+    if in.Currency == "" {
+        in.Currency = currency
+    }
+
+    in.ExpiryDate, err = quoteExpiryDate(in.IssueDate, in.ExpiryDate)
+    if err != nil {
+        return in, 0, err
+    }
+    rate, err := fx.RateOn(tx, in.Currency, in.IssueDate)
+    return in, rate, err
+}
+
+// quoteExpiryDate validates the quote dates, defaulting the expiry date to
+// quoteValidityDays after the issue date.
+func quoteExpiryDate(issueDate, expiryDate string) (string, error) {
+    issued, err := time.Parse("2006-01-02", issueDate)
+    if err != nil {
+        return "", errInvalidDates{errors.New("issue_date must be YYYY-MM-DD")}
+    }
+    if expiryDate == "" {
+        return issued.AddDate(0, 0, quoteValidityDays).Format("2006-01-02"), nil
+    }
+
+    expires, err := time.Parse("2006-01-02", expiryDate)
+    if err != nil {
+        return "", errInvalidDates{errors.New("expiry_date must be YYYY-MM-DD")}
+    }
+    if expires.Before(issued) {
+        return "", errInvalidDates{errors.New("expiry_date is before issue_date")}
+    }
+    return expiryDate, nil
+}
+
+// DeleteQuote removes a draft quote; quotes shown to the customer are
+// declined or left to expire instead.
+func DeleteQuote(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    err = lockEditableQuote(tx, id)
+    if err == nil {
+        err = deleteQuoteLines(tx, id)
+    }
+    if err == nil {
+        _, err = tx.Exec("DELETE FROM quotes WHERE id = ?", id)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeQuoteError(w, err)
+        return
+    }
+
+    tx.Commit()
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// SendQuote marks a quote as shown to the customer, which freezes it and
+// lets it expire.
+func SendQuote(w http.ResponseWriter, r *http.Request) {
+    changeQuoteStatus(w, r, models.QuoteSent)
+}
+
+func AcceptQuote(w http.ResponseWriter, r *http.Request) {
+    changeQuoteStatus(w, r, models.QuoteAccepted)
+}
+
+func DeclineQuote(w http.ResponseWriter, r *http.Request) {
+    changeQuoteStatus(w, r, models.QuoteDeclined)
+}
+
+func changeQuoteStatus(w http.ResponseWriter, r *http.Request, to models.QuoteStatus) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    err = transitionQuote(tx, id, to, time.Now())
+    if err != nil {
+        tx.Rollback()
+        writeQuoteError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    writeQuoteDetail(w, id)
+}
+
+// transitionQuote moves the quote to status to if the transition table
+// allows it. A quote past its expiry date can no longer be sent or
+// accepted, even before the expiry job has run.
+func transitionQuote(tx *sql.Tx, id int, to models.QuoteStatus, now time.Time) error {
+    var from models.QuoteStatus
+    var expiryDate string
+    err := tx.QueryRow(`
+        SELECT status, DATE_FORMAT(expiry_date, '%Y-%m-%d')
+        FROM quotes WHERE id = ? FOR UPDATE
+    `, id).Scan(&from, &expiryDate)
+    if err == sql.ErrNoRows {
+        return errQuoteNotFound
+    } else if err != nil {
+        return err
+    }
+    if from == models.QuoteConverted {
+        return errQuoteConverted
+    }
+    if !from.CanTransitionTo(to) {
+        return errIllegalQuoteTransition
+    }
+    if (to == models.QuoteSent || to == models.QuoteAccepted) && expiryDate < now.Format("2006-01-02") {
+        return errQuoteExpired
+    }
+
+    _, err = tx.Exec("UPDATE quotes SET status = ?, updated_at = ? WHERE id = ?", to, now, id)
+    return err
+}
+
+// ConvertQuote bills an accepted quote: the new draft invoice carries the
+// quoted lines at the quoted prices, and both documents point at each
+// other. The invoice is issued today unless issue_date says otherwise.
+func ConvertQuote(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req struct {
+        IssueDate string `json:"issue_date"`
+    }
+    if r.ContentLength != 0 {
+        err = json.NewDecoder(r.Body).Decode(&req)
+        if err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+    }
+    if req.IssueDate == "" {
+        req.IssueDate = time.Now().Format("2006-01-02")
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    invoiceID, err := convertQuote(tx, id, req.IssueDate)
+    if err == nil {
+        err = auditInvoiceChange(tx, r, invoiceID, audit.ActionCreate, nil)
+    }
+    if err != nil {
+        tx.Rollback()
+        writeQuoteError(w, err)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    invoice, err := loadInvoiceDetail(database.DB, invoiceID)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(invoice)
+}
+
+func convertQuote(tx *sql.Tx, id int, issueDate string) (int, error) {
+    err := transitionQuote(tx, id, models.QuoteConverted, time.Now())
+    if err == errIllegalQuoteTransition {
+        return 0, errQuoteNotAccepted
+    } else if err != nil {
+        return 0, err
+    }
+
+    quote, err := loadQuoteDetail(tx, id)
+    if err != nil {
+        return 0, err
+    }
+
+    in := invoiceInput{
+        CustomerID:       quote.CustomerID,
+        IssueDate:        issueDate,
+        Currency:         quote.Currency,
+        PricesIncludeTax: quote.PricesIncludeTax,
+        DiscountType:     quote.DiscountType,
+        DiscountValue:    quote.DiscountValue,
+    }
+    for _, line := range quote.Items {
+        price := line.UnitPrice
+        l := invoiceLineInput{
+            ItemID:        line.ItemID,
+            Quantity:      line.Quantity,
+            DiscountType:  line.DiscountType,
+            DiscountValue: line.DiscountValue,
+            TaxRateIDs:    []int{},
+            UnitPrice:     &price,
+        }
+        for _, t := range line.Taxes {
+            l.TaxRateIDs = append(l.TaxRateIDs, t.TaxRateID)
+        }
+        in.Items = append(in.Items, l)
+    }
+
+    invoiceID, err := createInvoice(tx, in)
+    if err != nil {
+        return 0, err
+    }
+
+    _, err = tx.Exec("UPDATE quotes SET invoice_id = ? WHERE id = ?", invoiceID, id)
+    return invoiceID, err
+}
+
+// ExpireQuotes closes sent quotes whose expiry date has passed. Drafts are
+// left alone: the customer has not seen them, they stay editable so the
+// expiry date can be moved, and transitionQuote refuses to send or accept
+// them while it lies in the past.
+func ExpireQuotes(now time.Time) error {
+    _, err := database.DB.Exec(`
+        UPDATE quotes SET status = 'expired', updated_at = ?
+        WHERE status = 'sent' AND expiry_date < ?
+    `, now, now.Format("2006-01-02"))
+    return err
+}
+
+func lockEditableQuote(tx *sql.Tx, id int) error {
+    var status models.QuoteStatus
+    err := tx.QueryRow("SELECT status FROM quotes WHERE id = ? FOR UPDATE", id).Scan(&status)
+    if err == sql.ErrNoRows {
+        return errQuoteNotFound
+    } else if err != nil {
+        return err
+    }
+    if !status.Editable() {
+        return errQuoteNotEditable
+    }
+    return nil
+}
+
+func writeQuoteDetail(w http.ResponseWriter, id int) {
+    detail, err := loadQuoteDetail(database.DB, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(detail)
+}
+
+// writeQuoteError reports the quote errors and leaves the rest, such as
+// pricing and customer errors, to writeInvoiceError.
+func writeQuoteError(w http.ResponseWriter, err error) {
+    switch err {
+    case errQuoteNotFound:
+        http.Error(w, "Quote not found", http.StatusNotFound)
+    case errQuoteNotEditable:
+        http.Error(w, "Only draft quotes can be edited", http.StatusConflict)
+    case errIllegalQuoteTransition:
+        http.Error(w, "Illegal quote status transition", http.StatusConflict)
+    case errQuoteNotAccepted:
+        http.Error(w, "Only accepted quotes can be converted", http.StatusConflict)
+    case errQuoteExpired:
+        http.Error(w, "Quote has expired", http.StatusConflict)
+    case errQuoteConverted:
+        http.Error(w, "Quote has already been converted", http.StatusConflict)
+    default:
+        writeInvoiceError(w, err)
+    }
+}
+
+const quoteColumns = `
+    q.id, q.quote_number, q.customer_id, q.issue_date, q.expiry_date,
+    q.currency, q.exchange_rate,
+    q.prices_include_tax, q.discount_type, q.discount_value, q.line_discount_total,
+    q.discount_amount, q.subtotal, q.tax_total, q.total_amount,
+    q.status, q.notes, q.invoice_id, q.created_at, q.updated_at
+`
+
+func scanQuote(row rowScanner, q *models.Quote) error {
+    return row.Scan(
+        &q.ID,
+        &q.QuoteNumber,
+        &q.CustomerID,
+        &q.IssueDate,
+        &q.ExpiryDate,
+        &q.Currency,
+        &q.ExchangeRate,
+        &q.PricesIncludeTax,
+        &q.DiscountType,
+        &q.DiscountValue,
+        &q.LineDiscountTotal,
+        &q.DiscountAmount,
+        &q.Subtotal,
+        &q.TaxTotal,
+        &q.TotalAmount,
+        &q.Status,
+        &q.Notes,
+        &q.InvoiceID,
+        &q.CreatedAt,
+        &q.UpdatedAt,
+    )
+}
+
+func loadQuote(q queryer, id int) (models.Quote, error) {
+    var quote models.Quote
+    err := scanQuote(q.QueryRow("SELECT "+quoteColumns+" FROM quotes q WHERE q.id = ?", id), &quote)
+    return quote, err
+}
\ No newline at end of file
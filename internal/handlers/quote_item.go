@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/pricing"
+)
+
+func getQuoteLines(q queryer, quoteID int) ([]models.QuoteLine, error) {
+    rows, err := q.Query(`
+        SELECT qi.id, qi.quote_id, qi.item_id, it.name, qi.quantity, qi.price,
+            qi.discount_type, qi.discount_value, qi.discount_amount, qi.quote_discount_amount,
+            qi.net_amount, qi.tax_amount, qi.line_total, qi.created_at, qi.updated_at
+        FROM quote_items qi
+        JOIN items it ON it.id = qi.item_id
+        WHERE qi.quote_id = ?
+        ORDER BY qi.id
+    `, quoteID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    lines := []models.QuoteLine{}
+    for rows.Next() {
+        var l models.QuoteLine
+        err := rows.Scan(
+            &l.ID,
+            &l.QuoteID,
+            &l.ItemID,
+            &l.ItemName,
+            &l.Quantity,
+            &l.UnitPrice,
+            &l.DiscountType,
+            &l.DiscountValue,
+            &l.DiscountAmount,
+            &l.QuoteDiscountAmount,
+            &l.NetAmount,
+            &l.TaxAmount,
+            &l.LineTotal,
+            &l.CreatedAt,
+            &l.UpdatedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+        lines = append(lines, l)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+    rows.Close()
+
+    taxes, err := getQuoteLineTaxes(q, quoteID)
+    if err != nil {
+        return nil, err
+    }
+    for i := range lines {
+        lines[i].Taxes = taxes[lines[i].ID]
+        if lines[i].Taxes == nil {
+            lines[i].Taxes = []models.InvoiceTax{}
+        }
+    }
+    return lines, nil
+}
+
+// getQuoteLineTaxes returns the taxes of every line of the quote keyed by
+// line id.
+func getQuoteLineTaxes(q queryer, quoteID int) (map[int][]models.InvoiceTax, error) {
+    rows, err := q.Query(`
+        SELECT t.quote_item_id, t.tax_rate_id, t.name, t.rate, t.compound,
+            t.taxable_amount, t.tax_amount
+        FROM quote_item_taxes t
+        JOIN quote_items qi ON qi.id = t.quote_item_id
+        WHERE qi.quote_id = ?
+        ORDER BY t.id
+    `, quoteID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    taxes := map[int][]models.InvoiceTax{}
+    for rows.Next() {
+        var lineID int
+        var t models.InvoiceTax
+        err := rows.Scan(&lineID, &t.TaxRateID, &t.Name, &t.Rate, &t.Compound, &t.TaxableAmount, &t.TaxAmount)
+        if err != nil {
+            return nil, err
+        }
+        taxes[lineID] = append(taxes[lineID], t)
+    }
+    return taxes, rows.Err()
+}
+
+// getQuoteTaxSummary totals the quote taxes per rate.
+func getQuoteTaxSummary(q queryer, quoteID int) ([]models.InvoiceTax, error) {
+    rows, err := q.Query(`
+        SELECT t.tax_rate_id, t.name, t.rate, t.compound,
+            SUM(t.taxable_amount), SUM(t.tax_amount)
+        FROM quote_item_taxes t
+        JOIN quote_items qi ON qi.id = t.quote_item_id
+        WHERE qi.quote_id = ?
+        GROUP BY t.tax_rate_id, t.name, t.rate, t.compound
+        ORDER BY MIN(t.id)
+    `, quoteID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    taxes := []models.InvoiceTax{}
+    for rows.Next() {
+        var t models.InvoiceTax
+        err := rows.Scan(&t.TaxRateID, &t.Name, &t.Rate, &t.Compound, &t.TaxableAmount, &t.TaxAmount)
+        if err != nil {
+            return nil, err
+        }
+        taxes = append(taxes, t)
+    }
+    return taxes, rows.Err()
+}
+
+func loadQuoteDetail(q queryer, id int) (models.QuoteDetail, error) {
+    quote, err := loadQuote(q, id)
+    if err != nil {
+        return models.QuoteDetail{}, err
+    }
+
+    lines, err := getQuoteLines(q, id)
+    if err != nil {
+        return models.QuoteDetail{}, err
+    }
+
+    taxes, err := getQuoteTaxSummary(q, id)
+    if err != nil {
+        return models.QuoteDetail{}, err
+    }
+
+    return models.QuoteDetail{Quote: quote, Items: lines, Taxes: taxes}, nil
+}
+
+// quoteItemPricing returns the current price of an item in the quote
+// currency and the item's default tax rate.
+func quoteItemPricing(tx *sql.Tx, quoteID, itemID int) (money.Amount, *int, error) {
+    var currency, issueDate string
+    var rate money.ExchangeRate
+    err := tx.QueryRow(`
+        SELECT currency, exchange_rate, DATE_FORMAT(issue_date, '%Y-%m-%d')
+        FROM quotes WHERE id = ?
+    `, quoteID).Scan(&currency, &rate, &issueDate)
+    if err != nil {
+        return 0, nil, err
+    }
+    return itemPrice(tx, itemID, currency, rate, issueDate)
+}
+
+func insertQuoteLine(tx *sql.Tx, quoteID int, in invoiceLineInput) error {
+    price, defaultRate, err := quoteItemPricing(tx, quoteID, in.ItemID)
+    if err != nil {
+        return err
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO quote_items (quote_id, item_id, quantity, price, discount_type, discount_value)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, quoteID, in.ItemID, in.Quantity, price, in.DiscountType, in.DiscountValue)
+    if err != nil {
+        return err
+    }
+    lineID, _ := res.LastInsertId()
+
+    return setQuoteLineTaxes(tx, int(lineID), lineTaxRateIDs(in.TaxRateIDs, defaultRate))
+}
+
+// setQuoteLineTaxes gives a new line snapshots of the given active rates.
+// Amounts are filled in by recalculateQuote.
+func setQuoteLineTaxes(tx *sql.Tx, lineID int, rateIDs []int) error {
+    seen := map[int]bool{}
+    for _, rateID := range rateIDs {
+        if seen[rateID] {
+            continue
+        }
+        seen[rateID] = true
+
+        res, err := tx.Exec(`
+            INSERT INTO quote_item_taxes (quote_item_id, tax_rate_id, name, rate, compound)
+            SELECT ?, id, name, rate, compound
+            FROM tax_rates
+            WHERE id = ? AND active = TRUE
+        `, lineID, rateID)
+        if err != nil {
+            return err
+        }
+        if n, _ := res.RowsAffected(); n == 0 {
+            return errTaxRateNotFound
+        }
+    }
+    return nil
+}
+
+// insertQuoteLines adds the lines to the quote and prices them.
+func insertQuoteLines(tx *sql.Tx, quoteID int, lines []invoiceLineInput) error {
+    for _, line := range lines {
+        err := insertQuoteLine(tx, quoteID, line)
+        if err != nil {
+            return err
+        }
+    }
+    return recalculateQuote(tx, quoteID)
+}
+
+func deleteQuoteLines(tx *sql.Tx, quoteID int) error {
+    _, err := tx.Exec(`
+        DELETE t FROM quote_item_taxes t
+        JOIN quote_items qi ON qi.id = t.quote_item_id
+        WHERE qi.quote_id = ?
+    `, quoteID)
+    if err != nil {
+        return err
+    }
+
+    _, err = tx.Exec("DELETE FROM quote_items WHERE quote_id = ?", quoteID)
+    return err
+}
+
+// recalculateQuote prices every line through the same discount and tax
+// rules as invoices and stores the line, tax and quote totals.
+func recalculateQuote(tx *sql.Tx, quoteID int) error {
+    var inclusive bool
+    var discount pricing.Discount
+    err := tx.QueryRow(`
+        SELECT prices_include_tax, discount_type, discount_value
+        FROM quotes WHERE id = ?
+    `, quoteID).Scan(&inclusive, &discount.Type, &discount.Value)
+    if err != nil {
+        return err
+    }
+
+    rows, err := tx.Query(`
+        SELECT id, price, quantity, discount_type, discount_value FROM quote_items
+        WHERE quote_id = ?
+        ORDER BY id
+    `, quoteID)
+    if err != nil {
+        return err
+    }
+    var lineIDs []int
+    var lines []pricing.Line
+    for rows.Next() {
+        var id int
+        var l pricing.Line
+        if err := rows.Scan(&id, &l.UnitPrice, &l.Quantity, &l.Discount.Type, &l.Discount.Value); err != nil {
+            rows.Close()
+            return err
+        }
+        lineIDs = append(lineIDs, id)
+        lines = append(lines, l)
+    }
+    rows.Close()
+
+    taxes, err := getQuoteLineTaxes(tx, quoteID)
+    if err != nil {
+        return err
+    }
+    for i, id := range lineIDs {
+        for _, t := range taxes[id] {
+            lines[i].Rates = append(lines[i].Rates, pricing.Rate{
+                ID:       t.TaxRateID,
+                Name:     t.Name,
+                Percent:  t.Rate,
+                Compound: t.Compound,
+            })
+        }
+    }
+
+    result := pricing.Calculate(lines, inclusive, discount)
+
+    for i, id := range lineIDs {
+        lr := result.Lines[i]
+        _, err = tx.Exec(`
+            UPDATE quote_items
+            SET discount_amount = ?, quote_discount_amount = ?,
+                net_amount = ?, tax_amount = ?, line_total = ?
+            WHERE id = ?
+        `, lr.LineDiscount, lr.InvoiceDiscount, lr.Net, lr.Tax, lr.Gross, id)
+        if err != nil {
+            return err
+        }
+        for _, t := range lr.Taxes {
+            _, err = tx.Exec(`
+                UPDATE quote_item_taxes SET taxable_amount = ?, tax_amount = ?
+                WHERE quote_item_id = ? AND tax_rate_id = ?
+            `, t.Taxable, t.Amount, id, t.ID)
+            if err != nil {
+                return err
+            }
+        }
+    }
+
+    _, err = tx.Exec(`
+        UPDATE quotes
+        SET line_discount_total = ?, discount_amount = ?,
+            subtotal = ?, tax_total = ?, total_amount = ?
+        WHERE id = ?
+    `, result.LineDiscountTotal, result.InvoiceDiscount, result.Subtotal, result.TaxTotal, result.Total, quoteID)
+    return err
+}
\ No newline at end of file
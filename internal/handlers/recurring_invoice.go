@@ -0,0 +1,612 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/audit"
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/pricing"
+	"invoice-system/internal/recurring"
+	"invoice-system/internal/terms"
+
+	"github.com/gorilla/mux"
+)
+
+var errRecurringInvoiceNotFound = errors.New("recurring invoice not found")
+
+func GetRecurringInvoices(w http.ResponseWriter, r *http.Request) {
+    page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+    if page < 1 {
+        page = 1
+    }
+    limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+    if limit < 1 {
+        limit = 10
+    }
+    offset := (page - 1) * limit
+
+    query := "SELECT " + recurringInvoiceColumns + " FROM recurring_invoices r"
+    var args []interface{}
+    if r.URL.Query().Get("active") == "true" {
+        query += " WHERE r.active = TRUE"
+    }
+    query += " ORDER BY r.id LIMIT ? OFFSET ?"
+    args = append(args, limit, offset)
+
+    rows, err := database.DB.Query(query, args...)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    templates := []models.RecurringInvoice{}
+    for rows.Next() {
+        var ri models.RecurringInvoice
+        err := scanRecurringInvoice(rows, &ri)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        templates = append(templates, ri)
+    }
+    rows.Close()
+
+    for i := range templates {
+        templates[i].Items, err = getRecurringInvoiceLines(database.DB, templates[i].ID)
+        if err != nil {
+            http.Error(w, "Database error", http.StatusInternalServerError)
+            return
+        }
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(templates)
+}
+
+func CreateRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+    var req models.RecurringInvoice
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    req.Active = true
+    next, err := validateRecurringInvoice(req, nil)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    res, err := tx.Exec(`
+        INSERT INTO recurring_invoices (customer_id, currency, prices_include_tax,
+            discount_type, discount_value, interval_unit, interval_count, day_of_month,
+            start_date, end_date, payment_terms, auto_issue, active, next_run_date)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, req.CustomerID, req.Currency, req.PricesIncludeTax, req.DiscountType, req.DiscountValue,
+        req.IntervalUnit, req.IntervalCount, req.DayOfMonth, req.StartDate, req.EndDate,
+        req.PaymentTerms, req.AutoIssue, req.Active, next)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+        return
+    }
+    id, _ := res.LastInsertId()
+
+    err = saveRecurringInvoiceLines(tx, int(id), req.Items)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    tx.Commit()
+    writeRecurringInvoice(w, int(id), http.StatusCreated)
+}
+
+func GetRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    writeRecurringInvoice(w, id, http.StatusOK)
+}
+
+// UpdateRecurringInvoice changes the fields present in the request. A
+// non-empty items list replaces every line of the template.
+func UpdateRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    req, err := lockRecurringInvoice(tx, id)
+    if err == errRecurringInvoiceNotFound {
+        tx.Rollback()
+        http.Error(w, "Recurring invoice not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    // Decode onto the stored template; lines are only replaced when sent
+    items := req.Items
+    req.Items = nil
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+    if req.Items == nil {
+        req.Items = items
+    }
+
+    next, err := validateRecurringInvoice(req, req.LastRunDate)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    _, err = tx.Exec(`
+        UPDATE recurring_invoices
+        SET customer_id = ?, currency = ?, prices_include_tax = ?, discount_type = ?,
+            discount_value = ?, interval_unit = ?, interval_count = ?, day_of_month = ?,
+            start_date = ?, end_date = ?, payment_terms = ?, auto_issue = ?, active = ?,
+            next_run_date = ?, updated_at = ?
+        WHERE id = ?
+    `, req.CustomerID, req.Currency, req.PricesIncludeTax, req.DiscountType, req.DiscountValue,
+        req.IntervalUnit, req.IntervalCount, req.DayOfMonth, req.StartDate, req.EndDate,
+        req.PaymentTerms, req.AutoIssue, req.Active, next, time.Now(), id)
+    if err == nil {
+        err = saveRecurringInvoiceLines(tx, id, req.Items)
+    }
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Update error", http.StatusInternalServerError)
+        return
+    }
+
+    tx.Commit()
+    writeRecurringInvoice(w, id, http.StatusOK)
+}
+
+func DeleteRecurringInvoice(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    var runs int
+    err = tx.QueryRow("SELECT COUNT(*) FROM recurring_invoice_runs WHERE recurring_invoice_id = ?", id).Scan(&runs)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    if runs > 0 {
+        tx.Rollback()
+        http.Error(w, "Recurring invoices that have generated invoices cannot be deleted; deactivate them instead", http.StatusConflict)
+        return
+    }
+
+    _, err = tx.Exec("DELETE FROM recurring_invoice_items WHERE recurring_invoice_id = ?", id)
+    if err == nil {
+        _, err = tx.Exec("DELETE FROM recurring_invoices WHERE id = ?", id)
+    }
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Recurring invoice deletion error", http.StatusInternalServerError)
+        return
+    }
+
+    tx.Commit()
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func GetRecurringInvoiceRuns(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query(`
+        SELECT id, recurring_invoice_id, DATE_FORMAT(run_date, '%Y-%m-%d'), invoice_id, created_at
+        FROM recurring_invoice_runs
+        WHERE recurring_invoice_id = ?
+        ORDER BY run_date
+    `, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    runs := []models.RecurringInvoiceRun{}
+    for rows.Next() {
+        var run models.RecurringInvoiceRun
+        err := rows.Scan(&run.ID, &run.RecurringInvoiceID, &run.RunDate, &run.InvoiceID, &run.CreatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        runs = append(runs, run)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(runs)
+}
+
+// GenerateRecurringInvoices creates the invoices of every active schedule
+// due on or before now, catching up on run dates missed while the process
+// was down. Each run commits on its own, so a restart resumes where the
+// previous process stopped.
+func GenerateRecurringInvoices(now time.Time) error {
+    today := now.Format("2006-01-02")
+
+    ids, err := queryIDs(database.DB, `
+        SELECT id FROM recurring_invoices
+        WHERE active = TRUE AND next_run_date <= ?
+        ORDER BY next_run_date, id
+    `, today)
+    if err != nil {
+        return err
+    }
+
+    var errs []error
+    for _, id := range ids {
+        for {
+            more, err := runRecurringInvoice(id, today)
+            if err != nil {
+                errs = append(errs, fmt.Errorf("recurring invoice %d: %w", id, err))
+                break
+            }
+            if !more {
+                break
+            }
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// runRecurringInvoice generates the invoice for the next run date of a
+// schedule and advances it. It reports whether another run is due.
+func runRecurringInvoice(id int, today string) (bool, error) {
+    tx, err := database.DB.Begin()
+    if err != nil {
+        return false, err
+    }
+
+    ri, err := lockRecurringInvoice(tx, id)
+    if err != nil {
+        tx.Rollback()
+        return false, err
+    }
+    if !ri.Active || ri.NextRunDate == nil || *ri.NextRunDate > today {
+        tx.Rollback()
+        return false, nil
+    }
+    runDate := *ri.NextRunDate
+
+    var done int
+    err = tx.QueryRow(`
+        SELECT COUNT(*) FROM recurring_invoice_runs
+        WHERE recurring_invoice_id = ? AND run_date = ?
+    `, id, runDate).Scan(&done)
+    if err != nil {
+        tx.Rollback()
+        return false, err
+    }
+
+    if done == 0 {
+        invoiceID, err := createInvoice(tx, recurringInvoiceInput(ri, runDate))
+        if err == nil && ri.AutoIssue {
+            err = transitionInvoice(tx, invoiceID, models.InvoiceIssued, audit.SystemActor, fmt.Sprintf("recurring invoice %d", id))
+        }
+        if err == nil {
+            err = auditInvoiceJob(tx, invoiceID, audit.ActionCreate, nil)
+        }
+        if err == nil {
+            _, err = tx.Exec(`
+                INSERT INTO recurring_invoice_runs (recurring_invoice_id, run_date, invoice_id)
+                VALUES (?, ?, ?)
+            `, id, runDate, invoiceID)
+        }
+        if err != nil {
+            tx.Rollback()
+            return false, err
+        }
+    }
+
+    next, err := nextRecurringRunDate(ri, &runDate)
+    if err == nil {
+        _, err = tx.Exec("UPDATE recurring_invoices SET next_run_date = ? WHERE id = ?", next, id)
+    }
+    if err != nil {
+        tx.Rollback()
+        return false, err
+    }
+
+    err = tx.Commit()
+    return err == nil && next != nil && *next <= today, err
+}
+
+// recurringInvoiceInput builds the invoice generated on runDate, due under
+// the template's payment terms or else the customer's.
+func recurringInvoiceInput(ri models.RecurringInvoice, runDate string) invoiceInput {
+    in := invoiceInput{
+        CustomerID:       ri.CustomerID,
+        IssueDate:        runDate,
+        PaymentTerms:     ri.PaymentTerms,
+        Currency:         ri.Currency,
+        PricesIncludeTax: ri.PricesIncludeTax,
+        DiscountType:     ri.DiscountType,
+        DiscountValue:    ri.DiscountValue,
+    }
+    for _, l := range ri.Items {
+        in.Items = append(in.Items, invoiceLineInput{
+            ItemID:        l.ItemID,
+            Quantity:      l.Quantity,
+            DiscountType:  l.DiscountType,
+            DiscountValue: l.DiscountValue,
+            TaxRateIDs:    l.TaxRateIDs,
+        })
+    }
+    return in
+}
+
+// validateRecurringInvoice checks a template and returns its next run date
+// after lastRun, or nil when the schedule has already ended.
+func validateRecurringInvoice(ri models.RecurringInvoice, lastRun *string) (*string, error) {
+    err := validate.Struct(ri)
+    if err == nil && ri.PaymentTerms != "" {
+        _, err = terms.Parse(ri.PaymentTerms)
+    }
+    if err == nil {
+        err = pricing.Discount{Type: ri.DiscountType, Value: ri.DiscountValue}.Validate()
+    }
+    for _, l := range ri.Items {
+        if err == nil {
+            err = pricing.Discount{Type: l.DiscountType, Value: l.DiscountValue}.Validate()
+        }
+    }
+    if err != nil {
+        return nil, err
+    }
+    return nextRecurringRunDate(ri, lastRun)
+}
+
+// nextRecurringRunDate returns the first run date after lastRun, or the
+// first run date from today on when the template has not run yet, so a
+// start_date in the past does not backfill the periods before it.
+func nextRecurringRunDate(ri models.RecurringInvoice, lastRun *string) (*string, error) {
+    s, err := recurringSchedule(ri)
+    if err != nil {
+        return nil, err
+    }
+
+    var next time.Time
+    var ok bool
+    if lastRun == nil {
+        today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+        next, ok = s.First()
+        if ok && next.Before(today) {
+            next, ok = s.Next(today.AddDate(0, 0, -1))
+        }
+    } else {
+        last, err := time.Parse("2006-01-02", *lastRun)
+        if err != nil {
+            return nil, err
+        }
+        next, ok = s.Next(last)
+    }
+    if !ok {
+        return nil, nil
+    }
+    date := next.Format("2006-01-02")
+    return &date, nil
+}
+
+func recurringSchedule(ri models.RecurringInvoice) (recurring.Schedule, error) {
+    s := recurring.Schedule{
+        Unit:       ri.IntervalUnit,
+        Every:      ri.IntervalCount,
+        DayOfMonth: ri.DayOfMonth,
+    }
+
+    var err error
+    s.Start, err = time.Parse("2006-01-02", ri.StartDate)
+    if err != nil {
+        return s, errors.New("start_date must be YYYY-MM-DD")
+    }
+    if ri.EndDate != nil {
+        end, err := time.Parse("2006-01-02", *ri.EndDate)
+        if err != nil {
+            return s, errors.New("end_date must be YYYY-MM-DD")
+        }
+        s.End = &end
+    }
+    return s, s.Validate()
+}
+
+// recurringInvoiceColumns selects a template aliased as r in the order
+// scanRecurringInvoice expects, including its latest run date.
+const recurringInvoiceColumns = `
+    r.id, r.customer_id, r.currency, r.prices_include_tax, r.discount_type, r.discount_value,
+    r.interval_unit, r.interval_count, r.day_of_month,
+    DATE_FORMAT(r.start_date, '%Y-%m-%d'), DATE_FORMAT(r.end_date, '%Y-%m-%d'),
+    r.payment_terms, r.auto_issue, r.active,
+    (SELECT DATE_FORMAT(MAX(run.run_date), '%Y-%m-%d') FROM recurring_invoice_runs run
+        WHERE run.recurring_invoice_id = r.id),
+    DATE_FORMAT(r.next_run_date, '%Y-%m-%d'),
+    r.created_at, r.updated_at
+`
+
+func scanRecurringInvoice(row rowScanner, ri *models.RecurringInvoice) error {
+    var endDate, lastRun, nextRun sql.NullString
+    err := row.Scan(
+        &ri.ID,
+        &ri.CustomerID,
+        &ri.Currency,
+        &ri.PricesIncludeTax,
+        &ri.DiscountType,
+        &ri.DiscountValue,
+        &ri.IntervalUnit,
+        &ri.IntervalCount,
+        &ri.DayOfMonth,
+        &ri.StartDate,
+        &endDate,
+        &ri.PaymentTerms,
+        &ri.AutoIssue,
+        &ri.Active,
+        &lastRun,
+        &nextRun,
+        &ri.CreatedAt,
+        &ri.UpdatedAt,
+    )
+    if err != nil {
+        return err
+    }
+    ri.EndDate = nullStringPtr(endDate)
+    ri.LastRunDate = nullStringPtr(lastRun)
+    ri.NextRunDate = nullStringPtr(nextRun)
+    return nil
+}
+
+func nullStringPtr(s sql.NullString) *string {
+    if !s.Valid {
+        return nil
+    }
+    return &s.String
+}
+
+func loadRecurringInvoice(q queryer, id int) (models.RecurringInvoice, error) {
+    var ri models.RecurringInvoice
+    err := scanRecurringInvoice(q.QueryRow("SELECT "+recurringInvoiceColumns+" FROM recurring_invoices r WHERE r.id = ?", id), &ri)
+    if err == sql.ErrNoRows {
+        return ri, errRecurringInvoiceNotFound
+    } else if err != nil {
+        return ri, err
+    }
+    ri.Items, err = getRecurringInvoiceLines(q, id)
+    return ri, err
+}
+
+// lockRecurringInvoice loads a template holding its row lock, which keeps
+// concurrent generator runs from billing the same run date.
+func lockRecurringInvoice(tx *sql.Tx, id int) (models.RecurringInvoice, error) {
+    var locked int
+    err := tx.QueryRow("SELECT id FROM recurring_invoices WHERE id = ? FOR UPDATE", id).Scan(&locked)
+    if err == sql.ErrNoRows {
+        return models.RecurringInvoice{}, errRecurringInvoiceNotFound
+    } else if err != nil {
+        return models.RecurringInvoice{}, err
+    }
+    return loadRecurringInvoice(tx, id)
+}
+
+func getRecurringInvoiceLines(q queryer, id int) ([]models.RecurringInvoiceLine, error) {
+    rows, err := q.Query(`
+        SELECT id, item_id, quantity, discount_type, discount_value, tax_rate_ids
+        FROM recurring_invoice_items
+        WHERE recurring_invoice_id = ?
+        ORDER BY id
+    `, id)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    lines := []models.RecurringInvoiceLine{}
+    for rows.Next() {
+        var l models.RecurringInvoiceLine
+        var taxRateIDs []byte
+        err := rows.Scan(&l.ID, &l.ItemID, &l.Quantity, &l.DiscountType, &l.DiscountValue, &taxRateIDs)
+        if err != nil {
+            return nil, err
+        }
+        if taxRateIDs != nil {
+            if err := json.Unmarshal(taxRateIDs, &l.TaxRateIDs); err != nil {
+                return nil, err
+            }
+        }
+        lines = append(lines, l)
+    }
+    return lines, rows.Err()
+}
+
+func saveRecurringInvoiceLines(tx *sql.Tx, id int, lines []models.RecurringInvoiceLine) error {
+    _, err := tx.Exec("DELETE FROM recurring_invoice_items WHERE recurring_invoice_id = ?", id)
+    if err != nil {
+        return err
+    }
+
+    for _, l := range lines {
+        var taxRateIDs []byte
+        if l.TaxRateIDs != nil {
+            taxRateIDs, _ = json.Marshal(l.TaxRateIDs)
+        }
+        _, err = tx.Exec(`
+            INSERT INTO recurring_invoice_items (recurring_invoice_id, item_id, quantity,
+                discount_type, discount_value, tax_rate_ids)
+            VALUES (?, ?, ?, ?, ?, ?)
+        `, id, l.ItemID, l.Quantity, l.DiscountType, l.DiscountValue, taxRateIDs)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func writeRecurringInvoice(w http.ResponseWriter, id int, status int) {
+    ri, err := loadRecurringInvoice(database.DB, id)
+    if err == errRecurringInvoiceNotFound {
+        http.Error(w, "Recurring invoice not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(ri)
+}
\ No newline at end of file
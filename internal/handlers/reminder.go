@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/dunning"
+	"invoice-system/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// maxReminderAttempts stops retrying a reminder step that keeps failing.
+const maxReminderAttempts = 5
+
+const reminderRuleColumns = "id, name, offset_days, subject, body, active, created_at, updated_at"
+
+func GetReminderRules(w http.ResponseWriter, r *http.Request) {
+    rules, err := loadReminderRules(r.URL.Query().Get("active") == "true")
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(rules)
+}
+
+func CreateReminderRule(w http.ResponseWriter, r *http.Request) {
+    var req models.ReminderRule
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err == nil {
+        err = dunning.Validate(req)
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    res, err := database.DB.Exec(`
+        INSERT INTO reminder_rules (name, offset_days, subject, body, active)
+        VALUES (?, ?, ?, ?, TRUE)
+    `, req.Name, req.OffsetDays, req.Subject, req.Body)
+    if err != nil {
+        http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    id, _ := res.LastInsertId()
+    req.ID = int(id)
+    req.Active = true
+    req.CreatedAt = time.Now()
+    req.UpdatedAt = time.Now()
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(req)
+}
+
+func GetReminderRule(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var rule models.ReminderRule
+    err = scanReminderRule(database.DB.QueryRow("SELECT "+reminderRuleColumns+" FROM reminder_rules WHERE id = ?", id), &rule)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Reminder rule not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(rule)
+}
+
+func UpdateReminderRule(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req models.ReminderRule
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err == nil {
+        err = dunning.Validate(req)
+    }
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    _, err = database.DB.Exec(`
+        UPDATE reminder_rules
+        SET name = ?, offset_days = ?, subject = ?, body = ?, active = ?, updated_at = ?
+        WHERE id = ?
+    `, req.Name, req.OffsetDays, req.Subject, req.Body, req.Active, time.Now(), id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    req.ID = id
+    req.UpdatedAt = time.Now()
+    json.NewEncoder(w).Encode(req)
+}
+
+func DeleteReminderRule(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var sent int
+    err = database.DB.QueryRow("SELECT COUNT(*) FROM payment_reminders WHERE rule_id = ?", id).Scan(&sent)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    if sent > 0 {
+        http.Error(w, "Reminder rules that have sent reminders cannot be deleted; deactivate them instead", http.StatusConflict)
+        return
+    }
+
+    _, err = database.DB.Exec("DELETE FROM reminder_rules WHERE id = ?", id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func GetInvoiceReminders(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    rows, err := database.DB.Query(`
+        SELECT id, invoice_id, rule_id, days_from_due, channel, recipient, subject, status,
+            error, attempts, created_at, updated_at
+        FROM payment_reminders
+        WHERE invoice_id = ?
+        ORDER BY id
+    `, id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    reminders := []models.PaymentReminder{}
+    for rows.Next() {
+        var p models.PaymentReminder
+        err := rows.Scan(&p.ID, &p.InvoiceID, &p.RuleID, &p.DaysFromDue, &p.Channel, &p.Recipient,
+            &p.Subject, &p.Status, &p.Error, &p.Attempts, &p.CreatedAt, &p.UpdatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        reminders = append(reminders, p)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(reminders)
+}
+
+// ProcessPaymentReminders sends every unpaid invoice the latest reminder
+// step it has reached, unless the customer opted out, that step has
+// already been delivered or its failed attempts have run out.
+func ProcessPaymentReminders(now time.Time) error {
+    notifier := dunning.Default
+    if notifier == nil {
+        return nil
+    }
+
+    rules, err := loadReminderRules(true)
+    if err != nil || len(rules) == 0 {
+        return err
+    }
+    earliest := rules[0].OffsetDays
+    for _, rule := range rules {
+        if rule.OffsetDays < earliest {
+            earliest = rule.OffsetDays
+        }
+    }
+
+    today := now.Format("2006-01-02")
+    rows, err := database.DB.Query(`
+        SELECT i.id, DATEDIFF(?, i.due_date)
+        FROM invoices i
+        JOIN customers c ON c.id = i.customer_id
+        WHERE i.status IN ('issued', 'sent', 'partially_paid', 'overdue')
+            AND c.reminders_opt_out = FALSE AND c.email <> ''
+            AND DATEDIFF(?, i.due_date) >= ?
+        ORDER BY i.id
+    `, today, today, earliest)
+    if err != nil {
+        return err
+    }
+    type candidate struct {
+        id          int
+        daysFromDue int
+    }
+    var candidates []candidate
+    for rows.Next() {
+        var c candidate
+        if err := rows.Scan(&c.id, &c.daysFromDue); err != nil {
+            rows.Close()
+            return err
+        }
+        candidates = append(candidates, c)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    var errs []error
+    for _, c := range candidates {
+        rule, ok := dunning.Select(rules, c.daysFromDue)
+        if !ok {
+            continue
+        }
+        if err := sendPaymentReminder(notifier, c.id, rule, c.daysFromDue); err != nil {
+            errs = append(errs, fmt.Errorf("reminder %d on invoice %d: %w", rule.ID, c.id, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// sendPaymentReminder delivers one reminder step and logs the attempt.
+func sendPaymentReminder(notifier dunning.Notifier, id int, rule models.ReminderRule, daysFromDue int) error {
+    // Failed attempts are retried after 1h, 2h, 4h and so on, up to
+    // maxReminderAttempts in all
+    var status string
+    var attempts int
+    var due bool
+    err := database.DB.QueryRow(`
+        SELECT status, attempts, updated_at <= NOW() - INTERVAL (1 << (attempts - 1)) HOUR
+        FROM payment_reminders WHERE invoice_id = ? AND rule_id = ?
+    `, id, rule.ID).Scan(&status, &attempts, &due)
+    if err == nil && (status == models.DeliverySent || attempts >= maxReminderAttempts || !due) {
+        return nil
+    } else if err != nil && err != sql.ErrNoRows {
+        return err
+    }
+
+    data, err := invoiceTemplateData(database.DB, id)
+    if err != nil {
+        return err
+    }
+    if data.Invoice.BalanceDue <= 0 {
+        return nil
+    }
+
+    entry := models.PaymentReminder{
+        InvoiceID:   id,
+        RuleID:      rule.ID,
+        DaysFromDue: daysFromDue,
+        Channel:     notifier.Channel(),
+        Recipient:   data.Customer.Email,
+        Status:      models.DeliverySent,
+    }
+    notice, sendErr := dunning.Render(rule, dunning.NewData(data, daysFromDue))
+    if sendErr == nil {
+        entry.Subject = notice.Subject
+        sendErr = notifier.Notify(notice)
+    }
+    if sendErr != nil {
+        entry.Status = models.DeliveryFailed
+        entry.Error = sendErr.Error()
+    }
+
+    _, err = database.DB.Exec(`
+        INSERT INTO payment_reminders (invoice_id, rule_id, days_from_due, channel, recipient,
+            subject, status, error)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE
+            days_from_due = VALUES(days_from_due), channel = VALUES(channel),
+            recipient = VALUES(recipient), subject = VALUES(subject), status = VALUES(status),
+            error = VALUES(error), attempts = attempts + 1
+    `, entry.InvoiceID, entry.RuleID, entry.DaysFromDue, entry.Channel, entry.Recipient,
+        entry.Subject, entry.Status, entry.Error)
+    if sendErr != nil {
+        return sendErr
+    }
+    return err
+}
+
+func loadReminderRules(activeOnly bool) ([]models.ReminderRule, error) {
+    query := "SELECT " + reminderRuleColumns + " FROM reminder_rules"
+    if activeOnly {
+        query += " WHERE active = TRUE"
+    }
+    query += " ORDER BY offset_days, id"
+
+    rows, err := database.DB.Query(query)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    rules := []models.ReminderRule{}
+    for rows.Next() {
+        var rule models.ReminderRule
+        if err := scanReminderRule(rows, &rule); err != nil {
+            return nil, err
+        }
+        rules = append(rules, rule)
+    }
+    return rules, rows.Err()
+}
+
+func scanReminderRule(row rowScanner, rule *models.ReminderRule) error {
+    return row.Scan(
+        &rule.ID,
+        &rule.Name,
+        &rule.OffsetDays,
+        &rule.Subject,
+        &rule.Body,
+        &rule.Active,
+        &rule.CreatedAt,
+        &rule.UpdatedAt,
+    )
+}
\ No newline at end of file
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/fx"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/reports"
+)
+
+// GetFXGainLoss reports the exchange gain or loss realised by payments on
+// foreign currency invoices, in the base currency.
+func GetFXGainLoss(w http.ResponseWriter, r *http.Request) {
+    startDate := r.URL.Query().Get("start_date")
+    endDate := r.URL.Query().Get("end_date")
+
+    query := `
+        SELECT p.id, p.invoice_id, i.invoice_number, i.currency, p.paid_at,
+            p.amount, i.exchange_rate, p.exchange_rate
+        FROM payments p
+        JOIN invoices i ON i.id = p.invoice_id
+    `
+    args := []interface{}{fx.BaseCurrency}
+    clauses := []string{"i.currency <> ?"}
+
+    if startDate != "" {
+        clauses = append(clauses, "p.paid_at >= ?")
+        args = append(args, startDate)
+    }
+    if endDate != "" {
+        clauses = append(clauses, "p.paid_at <= ?")
+        args = append(args, endDate)
+    }
+
+    query += " WHERE " + joinClauses(clauses, " AND ") + " ORDER BY p.paid_at, p.id"
+
+    rows, err := database.DB.Query(query, args...)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    report := struct {
+        BaseCurrency string              `json:"base_currency"`
+        Payments     []models.FXGainLoss `json:"payments"`
+        Total        money.Amount        `json:"total_gain_loss"`
+    }{BaseCurrency: fx.BaseCurrency, Payments: []models.FXGainLoss{}}
+
+    for rows.Next() {
+        var g models.FXGainLoss
+        err := rows.Scan(&g.PaymentID, &g.InvoiceID, &g.InvoiceNumber, &g.Currency, &g.PaidAt,
+            &g.Amount, &g.InvoiceRate, &g.PaymentRate)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        g.BaseAtInvoice = g.Amount.ToBase(g.InvoiceRate)
+        g.BaseAtPayment = g.Amount.ToBase(g.PaymentRate)
+        g.GainLoss = g.BaseAtPayment - g.BaseAtInvoice
+        report.Total += g.GainLoss
+        report.Payments = append(report.Payments, g)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(report)
+}
+
+// GetARAging buckets outstanding balances per customer by days past due on
+// as_of (default today). buckets sets the boundaries, 30,60,90 by default,
+// and format=csv returns the per-customer summary as CSV.
+func GetARAging(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+    asOf := q.Get("as_of")
+    if asOf == "" {
+        asOf = time.Now().Format("2006-01-02")
+    } else if _, err := time.Parse("2006-01-02", asOf); err != nil {
+        http.Error(w, "Validation error: as_of must be YYYY-MM-DD", http.StatusBadRequest)
+        return
+    }
+    boundaries, err := reports.ParseAgingBoundaries(q.Get("buckets"))
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    format := q.Get("format")
+    if format != "" && format != "json" && format != "csv" {
+        http.Error(w, "Validation error: format must be json or csv", http.StatusBadRequest)
+        return
+    }
+
+    report, err := reports.ARAging(database.DB, asOf, boundaries)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    if format == "csv" {
+        writeCSV(w, fmt.Sprintf("ar-aging-%s.csv", asOf), report.CSV())
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(report)
+}
+
+func writeCSV(w http.ResponseWriter, filename string, records [][]string) {
+    w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+    w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+    csv.NewWriter(w).WriteAll(records)
+}
+
+// GetRevenueReport groups revenue dated between start_date and end_date,
+// both inclusive as in GetInvoices. end_date defaults to today and
+// start_date to the first of January of that year.
+// basis is issued (default) or cash, group_by day, week, month (default),
+// quarter, customer or item, and compare=true adds the previous period.
+func GetRevenueReport(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+
+    end := time.Now()
+    if v := q.Get("end_date"); v != "" {
+        var err error
+        end, err = time.Parse("2006-01-02", v)
+        if err != nil {
+            http.Error(w, "Validation error: end_date must be YYYY-MM-DD", http.StatusBadRequest)
+            return
+        }
+    }
+    end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+    start := time.Date(end.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+    if v := q.Get("start_date"); v != "" {
+        var err error
+        start, err = time.Parse("2006-01-02", v)
+        if err != nil {
+            http.Error(w, "Validation error: start_date must be YYYY-MM-DD", http.StatusBadRequest)
+            return
+        }
+    }
+    if start.After(end) {
+        http.Error(w, "Validation error: start_date must not be after end_date", http.StatusBadRequest)
+        return
+    }
+
+    basis := q.Get("basis")
+    if basis == "" {
+        basis = reports.BasisIssued
+    }
+    if !reports.ValidBasis(basis) {
+        http.Error(w, "Validation error: basis must be issued or cash", http.StatusBadRequest)
+        return
+    }
+    groupBy := q.Get("group_by")
+    if groupBy == "" {
+        groupBy = reports.GroupMonth
+    }
+    if !reports.ValidGroup(groupBy) {
+        http.Error(w, "Validation error: group_by must be day, week, month, quarter, customer or item", http.StatusBadRequest)
+        return
+    }
+    format := q.Get("format")
+    if format != "" && format != "json" && format != "csv" {
+        http.Error(w, "Validation error: format must be json or csv", http.StatusBadRequest)
+        return
+    }
+
+    report, err := reports.Revenue(database.DB, basis, groupBy, start, end, q.Get("compare") == "true")
+    if errors.Is(err, reports.ErrTooManyPeriods) {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    if format == "csv" {
+        writeCSV(w, fmt.Sprintf("revenue-%s-%s-%s.csv", groupBy, report.StartDate, report.EndDate), report.CSV())
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(report)
+}
\ No newline at end of file
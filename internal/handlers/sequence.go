@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/numbering"
+
+	"github.com/gorilla/mux"
+)
+
+func GetSequences(w http.ResponseWriter, r *http.Request) {
+    rows, err := database.DB.Query(`
+        SELECT name, pattern, reset_policy, current_period, last_value, updated_at
+        FROM number_sequences
+        ORDER BY name
+    `)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    sequences := []models.NumberSequence{}
+    for rows.Next() {
+        var s models.NumberSequence
+        err := rows.Scan(&s.Name, &s.Pattern, &s.ResetPolicy, &s.CurrentPeriod, &s.LastValue, &s.UpdatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        sequences = append(sequences, s)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(sequences)
+}
+
+func UpdateSequence(w http.ResponseWriter, r *http.Request) {
+    name := mux.Vars(r)["name"]
+
+    var req models.NumberSequence
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    err = numbering.Validate(req.Pattern, req.ResetPolicy)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    tx, err := database.DB.Begin()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    // Once numbers have been issued a new pattern or reset period could
+    // repeat them, so the sequence is frozen
+    var pattern, policy string
+    var last int
+    err = tx.QueryRow(`
+        SELECT pattern, reset_policy, last_value FROM number_sequences
+        WHERE name = ?
+        FOR UPDATE
+    `, name).Scan(&pattern, &policy, &last)
+    if err == sql.ErrNoRows {
+        tx.Rollback()
+        http.Error(w, "Sequence not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    if last > 0 && (pattern != req.Pattern || policy != req.ResetPolicy) {
+        tx.Rollback()
+        http.Error(w, "Sequence has issued numbers; its pattern and reset policy can no longer change", http.StatusConflict)
+        return
+    }
+
+    _, err = tx.Exec(`
+        UPDATE number_sequences
+        SET pattern = ?, reset_policy = ?
+        WHERE name = ?
+    `, req.Pattern, req.ResetPolicy, name)
+    if err != nil {
+        tx.Rollback()
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        http.Error(w, "Transaction error", http.StatusInternalServerError)
+        return
+    }
+
+    var s models.NumberSequence
+    err = database.DB.QueryRow(`
+        SELECT name, pattern, reset_policy, current_period, last_value, updated_at
+        FROM number_sequences
+        WHERE name = ?
+    `, name).Scan(&s.Name, &s.Pattern, &s.ResetPolicy, &s.CurrentPeriod, &s.LastValue, &s.UpdatedAt)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(s)
+}
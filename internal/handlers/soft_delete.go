@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"database/sql"
+	"time"
+
+	"invoice-system/internal/audit"
+)
+
+// setDeletedAt hides a customer, item or invoice by stamping deleted_at,
+// keeping the row for the documents that refer to it, or brings it back.
+// It returns the audit action taken.
+func setDeletedAt(tx *sql.Tx, table string, id int, deleted bool) (string, error) {
+    if !deleted {
+        _, err := tx.Exec("UPDATE "+table+" SET deleted_at = NULL WHERE id = ?", id)
+        return audit.ActionRestore, err
+    }
+    _, err := tx.Exec("UPDATE "+table+" SET deleted_at = ? WHERE id = ?", time.Now(), id)
+    return audit.ActionDelete, err
+}
\ No newline at end of file
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+	"invoice-system/internal/templates"
+
+	"github.com/gorilla/mux"
+)
+
+// GetCustomerStatement lists the customer's invoices, payments, refunds and
+// credit notes between from and to with a running balance. from defaults to
+// the first day of the month of to, which defaults to today; format is
+// json (the default), html or pdf.
+func GetCustomerStatement(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    q := r.URL.Query()
+    to := q.Get("to")
+    if to == "" {
+        to = time.Now().Format("2006-01-02")
+    }
+    toDate, err := time.Parse("2006-01-02", to)
+    if err != nil {
+        http.Error(w, "Validation error: to must be YYYY-MM-DD", http.StatusBadRequest)
+        return
+    }
+    from := q.Get("from")
+    if from == "" {
+        from = toDate.AddDate(0, 0, 1-toDate.Day()).Format("2006-01-02")
+    }
+    fromDate, err := time.Parse("2006-01-02", from)
+    if err != nil {
+        http.Error(w, "Validation error: from must be YYYY-MM-DD", http.StatusBadRequest)
+        return
+    }
+    if fromDate.After(toDate) {
+        http.Error(w, "Validation error: from must not be after to", http.StatusBadRequest)
+        return
+    }
+
+    currency := strings.ToUpper(q.Get("currency"))
+    if currency != "" {
+        if err := validate.Var(currency, "iso4217"); err != nil {
+            http.Error(w, "Validation error: unknown currency "+currency, http.StatusBadRequest)
+            return
+        }
+    }
+
+    format := q.Get("format")
+    if format == "" {
+        format = "json"
+    }
+    if format != "json" && format != "html" && format != "pdf" {
+        http.Error(w, "Validation error: format must be json, html or pdf", http.StatusBadRequest)
+        return
+    }
+
+    st, err := buildStatement(database.DB, id, currency, from, to)
+    if err == errCustomerNotFound {
+        http.Error(w, "Customer not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    if format == "json" {
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(st)
+        return
+    }
+
+    company, err := loadCompanyProfile(database.DB)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    var buf bytes.Buffer
+    if format == "html" {
+        err = templates.RenderStatement(&buf, templates.StatementData{Statement: st, Company: company})
+        w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    } else {
+        err = writeStatementPDF(&buf, st, company)
+        w.Header().Set("Content-Type", "application/pdf")
+        w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", statementFilename(st)))
+    }
+    if err != nil {
+        w.Header().Del("Content-Disposition")
+        http.Error(w, "Statement rendering error", http.StatusInternalServerError)
+        return
+    }
+    buf.WriteTo(w)
+}
+
+// buildStatement collects the customer's documents in one currency, the
+// customer's own when currency is empty. Everything dated before from is
+// summed into the opening balance.
+func buildStatement(q queryer, customerID int, currency, from, to string) (models.Statement, error) {
+    st := models.Statement{From: from, To: to, Entries: []models.StatementEntry{}}
+
+    c := &st.Customer
+    err := q.QueryRow(`
+        SELECT id, name, email, address, currency, payment_terms FROM customers WHERE id = ?
+    `, customerID).Scan(&c.ID, &c.Name, &c.Email, &c.Address, &c.Currency, &c.PaymentTerms)
+    if err == sql.ErrNoRows {
+        return st, errCustomerNotFound
+    } else if err != nil {
+        return st, err
+    }
+    st.Currency = currency
+    if st.Currency == "" {
+        st.Currency = c.Currency
+    }
+
+    entries, err := statementEntries(q, customerID, st.Currency, to)
+    if err != nil {
+        return st, err
+    }
+
+    balance := st.OpeningBalance
+    for _, e := range entries {
+        balance += e.Debit - e.Credit
+        if e.Date < from {
+            st.OpeningBalance = balance
+            continue
+        }
+        e.Balance = balance
+        st.TotalDebits += e.Debit
+        st.TotalCredits += e.Credit
+        st.Entries = append(st.Entries, e)
+    }
+    st.ClosingBalance = balance
+    return st, nil
+}
+
+// statementOrder puts documents of the same day in the order they normally
+// happen: the invoice first, then what settles it.
+var statementOrder = map[string]int{
+    models.StatementInvoice:    0,
+    models.StatementCreditNote: 1,
+    models.StatementPayment:    2,
+    models.StatementRefund:     3,
+}
+
+// statementEntries returns every document up to and including to, oldest
+// first. Draft and void invoices never reached the customer and are left
+// out.
+func statementEntries(q queryer, customerID int, currency, to string) ([]models.StatementEntry, error) {
+    var entries []models.StatementEntry
+
+    rows, err := q.Query(`
+        SELECT id, invoice_number, DATE_FORMAT(issue_date, '%Y-%m-%d'),
+            DATE_FORMAT(due_date, '%Y-%m-%d'), total_amount
+        FROM invoices
+        WHERE customer_id = ? AND currency = ? AND status NOT IN ('draft', 'void') AND issue_date <= ?
+    `, customerID, currency, to)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        e := models.StatementEntry{Type: models.StatementInvoice}
+        var due string
+        if err := rows.Scan(&e.DocumentID, &e.Reference, &e.Date, &due, &e.Debit); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        e.InvoiceNumber = e.Reference
+        e.Description = "Invoice, due " + due
+        entries = append(entries, e)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    rows, err = q.Query(`
+        SELECT c.id, c.credit_note_number, DATE_FORMAT(c.issue_date, '%Y-%m-%d'), c.total_amount,
+            c.reason, i.invoice_number
+        FROM credit_notes c
+        JOIN invoices i ON i.id = c.invoice_id
+        WHERE i.customer_id = ? AND i.currency = ? AND c.issue_date <= ?
+    `, customerID, currency, to)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        e := models.StatementEntry{Type: models.StatementCreditNote}
+        var reason string
+        if err := rows.Scan(&e.DocumentID, &e.Reference, &e.Date, &e.Credit, &reason, &e.InvoiceNumber); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        e.Description = "Credit note: " + reason
+        entries = append(entries, e)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    // Refunds are stored as negative payments against a credit note
+    rows, err = q.Query(`
+        SELECT p.id, DATE_FORMAT(p.paid_at, '%Y-%m-%d'), p.amount, p.method, p.reference,
+            i.invoice_number, p.credit_note_id IS NOT NULL
+        FROM payments p
+        JOIN invoices i ON i.id = p.invoice_id
+        WHERE i.customer_id = ? AND i.currency = ? AND p.paid_at <= ?
+    `, customerID, currency, to)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var e models.StatementEntry
+        var amount money.Amount
+        var method string
+        var refund bool
+        if err := rows.Scan(&e.DocumentID, &e.Date, &amount, &method, &e.Reference, &e.InvoiceNumber, &refund); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        method = strings.ReplaceAll(method, "_", " ")
+        if refund {
+            e.Type = models.StatementRefund
+            e.Debit = -amount
+            e.Description = "Refund by " + method
+        } else {
+            e.Type = models.StatementPayment
+            e.Credit = amount
+            e.Description = "Payment by " + method
+        }
+        entries = append(entries, e)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    sort.SliceStable(entries, func(i, j int) bool {
+        a, b := entries[i], entries[j]
+        if a.Date != b.Date {
+            return a.Date < b.Date
+        }
+        if a.Type != b.Type {
+            return statementOrder[a.Type] < statementOrder[b.Type]
+        }
+        return a.DocumentID < b.DocumentID
+    })
+    return entries, nil
+}
+
+func statementFilename(st models.Statement) string {
+    return fmt.Sprintf("statement-%d-%s-%s.pdf", st.Customer.ID, st.From, st.To)
+}
\ No newline at end of file
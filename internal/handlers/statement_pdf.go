@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"io"
+
+	"invoice-system/internal/models"
+	"invoice-system/internal/pdf"
+)
+
+// statement table columns: date, reference and description are
+// left-aligned on their x position, the figures right-aligned
+const (
+    stmtReference   = 105.0
+    stmtDescription = 200.0
+    stmtDebit       = 405.0
+    stmtCredit      = 475.0
+    stmtBalance     = pdfRight
+)
+
+func writeStatementPDF(w io.Writer, st models.Statement, s models.CompanyProfile) error {
+    doc := pdf.New()
+    doc.Title = "Statement " + st.Customer.Name
+    page := doc.AddPage()
+
+    y := documentHeader(page, s, "STATEMENT", [][2]string{
+        {"Statement date", st.To},
+        {"Period", st.From + " to " + st.To},
+        {"Currency", st.Currency},
+        {"Amount due", st.ClosingBalance.Format()},
+    })
+    y = customerBlock(page, y+20, "Account", st.Customer)
+
+    y = statementTableHeader(page, y)
+    page.Text(pdfMargin, y, pdf.Bold, 8, st.From)
+    page.Text(stmtDescription, y, pdf.Bold, 8, "Opening balance")
+    page.TextRight(stmtBalance, y, pdf.Bold, 8, st.OpeningBalance.Format())
+    y += 14
+
+    for _, e := range st.Entries {
+        desc := pdf.Wrap(pdf.Regular, 8, e.Description, stmtDebit-stmtDescription-60)
+        ref := []string{e.Reference}
+        if e.InvoiceNumber != "" && e.InvoiceNumber != e.Reference {
+            ref = append(ref, e.InvoiceNumber)
+        }
+        lines := len(desc)
+        if len(ref) > lines {
+            lines = len(ref)
+        }
+        height := float64(lines)*10 + 4
+        if y+height > pdf.A4Height-pdfMargin-20 {
+            page = doc.AddPage()
+            y = statementTableHeader(page, pdfMargin+10)
+        }
+
+        page.Text(pdfMargin, y, pdf.Regular, 8, e.Date)
+        for i, text := range ref {
+            page.Text(stmtReference, y+float64(i)*10, pdf.Regular, 8, text)
+        }
+        for i, text := range desc {
+            page.Text(stmtDescription, y+float64(i)*10, pdf.Regular, 8, text)
+        }
+        if e.Debit != 0 {
+            page.TextRight(stmtDebit, y, pdf.Regular, 8, e.Debit.Format())
+        }
+        if e.Credit != 0 {
+            page.TextRight(stmtCredit, y, pdf.Regular, 8, e.Credit.Format())
+        }
+        page.TextRight(stmtBalance, y, pdf.Regular, 8, e.Balance.Format())
+        y += height
+    }
+
+    if y+40 > pdf.A4Height-pdfMargin-20 {
+        page = doc.AddPage()
+        y = pdfMargin + 10
+    }
+    page.Line(pdfMargin, y-6, pdfRight, y-6, 0.5)
+    y += 8
+    page.Text(pdfMargin, y, pdf.Bold, 8, st.To)
+    page.Text(stmtDescription, y, pdf.Bold, 8, "Closing balance")
+    page.TextRight(stmtDebit, y, pdf.Bold, 8, st.TotalDebits.Format())
+    page.TextRight(stmtCredit, y, pdf.Bold, 8, st.TotalCredits.Format())
+    page.TextRight(stmtBalance, y, pdf.Bold, 8, st.ClosingBalance.Format())
+    y += 14
+
+    // Payment instructions
+    if s.BankDetails != "" {
+        lines := pdf.Wrap(pdf.Regular, 9, s.BankDetails, pdfRight-pdfMargin)
+        if y+float64(len(lines))*11+40 > pdf.A4Height-pdfMargin-20 {
+            page = doc.AddPage()
+            y = pdfMargin
+        }
+        y += 24
+        page.Text(pdfMargin, y, pdf.Bold, 10, "Payment instructions")
+        y += 13
+        for _, line := range lines {
+            page.Text(pdfMargin, y, pdf.Regular, 9, line)
+            y += 11
+        }
+    }
+
+    addPageNumbers(doc)
+    _, err := doc.WriteTo(w)
+    return err
+}
+
+func statementTableHeader(page *pdf.Page, y float64) float64 {
+    page.FillRect(pdfMargin-4, y-11, pdfRight-pdfMargin+8, 16, 0.9)
+    page.Text(pdfMargin, y, pdf.Bold, 8, "Date")
+    page.Text(stmtReference, y, pdf.Bold, 8, "Reference")
+    page.Text(stmtDescription, y, pdf.Bold, 8, "Description")
+    page.TextRight(stmtDebit, y, pdf.Bold, 8, "Debit")
+    page.TextRight(stmtCredit, y, pdf.Bold, 8, "Credit")
+    page.TextRight(stmtBalance, y, pdf.Bold, 8, "Balance")
+    return y + 20
+}
\ No newline at end of file
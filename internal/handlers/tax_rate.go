@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/database"
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+
+	"github.com/gorilla/mux"
+)
+
+func GetTaxRates(w http.ResponseWriter, r *http.Request) {
+    query := `
+        SELECT id, name, rate, compound, active, created_at, updated_at
+        FROM tax_rates
+    `
+    if r.URL.Query().Get("active") == "true" {
+        query += " WHERE active = TRUE"
+    }
+    query += " ORDER BY id"
+
+    rows, err := database.DB.Query(query)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    rates := []models.TaxRate{}
+    for rows.Next() {
+        var t models.TaxRate
+        err := rows.Scan(&t.ID, &t.Name, &t.Rate, &t.Compound, &t.Active, &t.CreatedAt, &t.UpdatedAt)
+        if err != nil {
+            http.Error(w, "Scan error", http.StatusInternalServerError)
+            return
+        }
+        rates = append(rates, t)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(rates)
+}
+
+func CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+    var req models.TaxRate
+    err := json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    if req.Rate > 100*money.RateScale {
+        http.Error(w, "Validation error: rate must not exceed 100", http.StatusBadRequest)
+        return
+    }
+
+    res, err := database.DB.Exec(`
+        INSERT INTO tax_rates (name, rate, compound, active)
+        VALUES (?, ?, ?, TRUE)
+    `, req.Name, req.Rate, req.Compound)
+    if err != nil {
+        http.Error(w, "Database error: "+err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    id, _ := res.LastInsertId()
+    req.ID = int(id)
+    req.Active = true
+    req.CreatedAt = time.Now()
+    req.UpdatedAt = time.Now()
+
+    w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(req)
+}
+
+func GetTaxRate(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var t models.TaxRate
+    err = database.DB.QueryRow(`
+        SELECT id, name, rate, compound, active, created_at, updated_at
+        FROM tax_rates
+        WHERE id = ?
+    `, id).Scan(&t.ID, &t.Name, &t.Rate, &t.Compound, &t.Active, &t.CreatedAt, &t.UpdatedAt)
+    if err == sql.ErrNoRows {
+        http.Error(w, "Tax rate not found", http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    json.NewEncoder(w).Encode(t)
+}
+
+// UpdateTaxRate changes a rate for lines priced from now on; existing
+// invoice lines keep the snapshot they were priced with.
+func UpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+    params := mux.Vars(r)
+    id, err := strconv.Atoi(params["id"])
+    if err != nil {
+        http.Error(w, "Invalid ID", http.StatusBadRequest)
+        return
+    }
+
+    var req models.TaxRate
+    err = json.NewDecoder(r.Body).Decode(&req)
+    if err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    err = validate.Struct(req)
+    if err != nil {
+        http.Error(w, "Validation error: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+    if req.Rate > 100*money.RateScale {
+        http.Error(w, "Validation error: rate must not exceed 100", http.StatusBadRequest)
+        return
+    }
+
+    _, err = database.DB.Exec(`
+        UPDATE tax_rates
+        SET name = ?, rate = ?, compound = ?, active = ?, updated_at = ?
+        WHERE id = ?
+    `, req.Name, req.Rate, req.Compound, req.Active, time.Now(), id)
+    if err != nil {
+        http.Error(w, "Database error", http.StatusInternalServerError)
+        return
+    }
+
+    req.ID = id
+    req.UpdatedAt = time.Now()
+    json.NewEncoder(w).Encode(req)
+}
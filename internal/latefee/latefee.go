@@ -0,0 +1,86 @@
+// Package latefee computes the fees charged on invoices paid after their
+// due date.
+package latefee
+
+import (
+	"errors"
+
+	"invoice-system/internal/money"
+)
+
+const (
+    Flat          = "flat"
+    Percent       = "percent"
+    DailyInterest = "daily_interest"
+)
+
+// Fees are either appended as a line to the late invoice or billed on a
+// separate fee invoice.
+const (
+    ApplyAsLine    = "line"
+    ApplyAsInvoice = "invoice"
+)
+
+// Policy charges nothing until GraceDays have passed after the due date.
+// Flat charges Amount once, Percent charges Rate percent of the balance
+// once and DailyInterest accrues Rate percent of each day's balance for
+// every day late after the grace period. A non-zero Cap limits the total
+// fee.
+type Policy struct {
+    Type      string
+    Amount    money.Amount
+    Rate      money.Rate
+    GraceDays int
+    Cap       money.Amount
+}
+
+func (p Policy) Validate() error {
+    switch p.Type {
+    case Flat:
+        if p.Amount <= 0 {
+            return errors.New("flat fees need a positive amount")
+        }
+    case Percent, DailyInterest:
+        if p.Rate <= 0 {
+            return errors.New("percentage fees need a positive rate")
+        }
+    default:
+        return errors.New("fee_type must be flat, percent or daily_interest")
+    }
+    if p.GraceDays < 0 || p.Cap < 0 {
+        return errors.New("grace_days and max_amount cannot be negative")
+    }
+    return nil
+}
+
+// Charge returns the fee owed daysLate days after the due date on top of
+// the fees charged so far. since is the number of days late at the last
+// charge, so interest only accrues on balance for the days after it.
+func (p Policy) Charge(balance money.Amount, daysLate, since int, charged money.Amount) money.Amount {
+    if daysLate <= p.GraceDays || balance <= 0 {
+        return 0
+    }
+
+    var fee money.Amount
+    switch p.Type {
+    case Flat:
+        if charged == 0 {
+            fee = p.Amount
+        }
+    case Percent:
+        if charged == 0 {
+            fee = balance.Percent(p.Rate)
+        }
+    case DailyInterest:
+        if since < p.GraceDays {
+            since = p.GraceDays
+        }
+        if daysLate > since {
+            fee = balance.Percent(p.Rate * money.Rate(daysLate-since))
+        }
+    }
+    if p.Cap > 0 && charged+fee > p.Cap {
+        fee = money.Max(p.Cap-charged, 0)
+    }
+    return fee
+}
\ No newline at end of file
@@ -0,0 +1,60 @@
+package latefee
+
+import (
+	"testing"
+
+	"invoice-system/internal/money"
+)
+
+func TestCharge(t *testing.T) {
+    tests := []struct {
+        name     string
+        policy   Policy
+        balance  money.Amount
+        daysLate int
+        since    int
+        charged  money.Amount
+        want     money.Amount
+    }{
+        {"flat within grace", Policy{Type: Flat, Amount: 50000, GraceDays: 3}, 100000, 3, 0, 0, 0},
+        {"flat after grace", Policy{Type: Flat, Amount: 50000, GraceDays: 3}, 100000, 4, 0, 0, 50000},
+        {"flat charged once", Policy{Type: Flat, Amount: 50000}, 100000, 5, 4, 50000, 0},
+        {"flat on paid invoice", Policy{Type: Flat, Amount: 50000}, 0, 10, 0, 0, 0},
+        {"percent", Policy{Type: Percent, Rate: 20000}, 100000000, 1, 0, 0, 2000000},
+        {"percent rounds", Policy{Type: Percent, Rate: 15000}, 3333, 1, 0, 0, 50},
+        {"percent charged once", Policy{Type: Percent, Rate: 20000}, 200000000, 2, 1, 2000000, 0},
+        {"daily interest", Policy{Type: DailyInterest, Rate: 1000}, 100000, 10, 0, 0, 1000},
+        {"daily interest after grace", Policy{Type: DailyInterest, Rate: 1000, GraceDays: 5}, 100000, 10, 0, 0, 500},
+        {"daily interest since last charge", Policy{Type: DailyInterest, Rate: 1000}, 50000, 10, 8, 800, 100},
+        {"daily interest charged today", Policy{Type: DailyInterest, Rate: 1000}, 100000, 10, 10, 1000, 0},
+        {"capped", Policy{Type: DailyInterest, Rate: 10000, Cap: 2500}, 100000, 30, 0, 0, 2500},
+        {"cap reached over several charges", Policy{Type: DailyInterest, Rate: 10000, Cap: 2500}, 100000, 3, 2, 2000, 500},
+        {"not yet late", Policy{Type: Flat, Amount: 50000}, 100000, 0, 0, 0, 0},
+    }
+    for _, tt := range tests {
+        if got := tt.policy.Charge(tt.balance, tt.daysLate, tt.since, tt.charged); got != tt.want {
+            t.Errorf("%s: Charge(%s, %d, %d, %s) = %s, want %s", tt.name, tt.balance, tt.daysLate, tt.since, tt.charged, got, tt.want)
+        }
+    }
+}
+
+func TestValidate(t *testing.T) {
+    tests := []struct {
+        policy  Policy
+        wantErr bool
+    }{
+        {Policy{Type: Flat, Amount: 1}, false},
+        {Policy{Type: Percent, Rate: 1}, false},
+        {Policy{Type: DailyInterest, Rate: 1, GraceDays: 7, Cap: 100}, false},
+        {Policy{Type: Flat}, true},
+        {Policy{Type: Percent}, true},
+        {Policy{Type: Flat, Amount: 1, GraceDays: -1}, true},
+        {Policy{Type: Flat, Amount: 1, Cap: -1}, true},
+        {Policy{Type: "weekly"}, true},
+    }
+    for _, tt := range tests {
+        if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+            t.Errorf("%+v.Validate() = %v, want error %v", tt.policy, err, tt.wantErr)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,167 @@
+// Package mailer builds MIME messages and delivers them over SMTP.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+type Attachment struct {
+    Filename    string
+    ContentType string
+    Data        []byte
+}
+
+// Message is an email with an HTML body, an optional plain text
+// alternative and attachments.
+type Message struct {
+    From        string
+    To          []string
+    Subject     string
+    Text        string
+    HTML        string
+    Attachments []Attachment
+}
+
+// Mailer delivers messages. SMTP is the production implementation.
+type Mailer interface {
+    Send(msg Message) error
+}
+
+// Default delivers the email the API sends. It is set at start-up when
+// SMTP_HOST is configured and is nil otherwise.
+var Default Mailer
+
+// Bytes renders the message in RFC 5322 format.
+func (m Message) Bytes() ([]byte, error) {
+    if len(m.To) == 0 {
+        return nil, errors.New("message has no recipients")
+    }
+    from, err := mail.ParseAddress(m.From)
+    if err != nil {
+        return nil, fmt.Errorf("invalid sender %q: %w", m.From, err)
+    }
+    to := make([]string, len(m.To))
+    for i, addr := range m.To {
+        a, err := mail.ParseAddress(addr)
+        if err != nil {
+            return nil, fmt.Errorf("invalid recipient %q: %w", addr, err)
+        }
+        to[i] = a.String()
+    }
+
+    // The readable part: HTML with an optional plain text alternative
+    var readable bytes.Buffer
+    alt := multipart.NewWriter(&readable)
+    if m.Text != "" {
+        err = writeQuotedPrintable(alt, "text/plain; charset=utf-8", m.Text)
+        if err != nil {
+            return nil, err
+        }
+    }
+    err = writeQuotedPrintable(alt, "text/html; charset=utf-8", m.HTML)
+    if err != nil {
+        return nil, err
+    }
+    err = alt.Close()
+    if err != nil {
+        return nil, err
+    }
+
+    var body bytes.Buffer
+    mixed := multipart.NewWriter(&body)
+    part, err := mixed.CreatePart(textproto.MIMEHeader{
+        "Content-Type": {"multipart/alternative; boundary=" + alt.Boundary()},
+    })
+    if err != nil {
+        return nil, err
+    }
+    _, err = part.Write(readable.Bytes())
+    if err != nil {
+        return nil, err
+    }
+
+    for _, a := range m.Attachments {
+        part, err := mixed.CreatePart(textproto.MIMEHeader{
+            "Content-Type":              {a.ContentType},
+            "Content-Transfer-Encoding": {"base64"},
+            "Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+        })
+        if err != nil {
+            return nil, err
+        }
+        err = writeBase64(part, a.Data)
+        if err != nil {
+            return nil, err
+        }
+    }
+    err = mixed.Close()
+    if err != nil {
+        return nil, err
+    }
+
+    var out bytes.Buffer
+    header := func(k, v string) {
+        fmt.Fprintf(&out, "%s: %s\r\n", k, v)
+    }
+    header("From", from.String())
+    header("To", strings.Join(to, ", "))
+    header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+    header("Date", time.Now().Format(time.RFC1123Z))
+    header("Message-ID", messageID(from.Address))
+    header("MIME-Version", "1.0")
+    header("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+    out.WriteString("\r\n")
+    out.Write(body.Bytes())
+    return out.Bytes(), nil
+}
+
+func writeQuotedPrintable(w *multipart.Writer, contentType, s string) error {
+    part, err := w.CreatePart(textproto.MIMEHeader{
+        "Content-Type":              {contentType},
+        "Content-Transfer-Encoding": {"quoted-printable"},
+    })
+    if err != nil {
+        return err
+    }
+    qp := quotedprintable.NewWriter(part)
+    _, err = qp.Write([]byte(s))
+    if err != nil {
+        return err
+    }
+    return qp.Close()
+}
+
+// writeBase64 wraps the encoding at 76 characters as RFC 2045 requires.
+func writeBase64(w io.Writer, data []byte) error {
+    encoded := base64.StdEncoding.EncodeToString(data)
+    for len(encoded) > 76 {
+        if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:76]); err != nil {
+            return err
+        }
+        encoded = encoded[76:]
+    }
+    _, err := fmt.Fprintf(w, "%s\r\n", encoded)
+    return err
+}
+
+func messageID(from string) string {
+    domain := "localhost"
+    if i := strings.LastIndex(from, "@"); i >= 0 {
+        domain = from[i+1:]
+    }
+    b := make([]byte, 12)
+    rand.Read(b)
+    return fmt.Sprintf("<%d.%x@%s>", time.Now().UnixNano(), b, domain)
+}
\ No newline at end of file
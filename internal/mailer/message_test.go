@@ -0,0 +1,147 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestMessageBytes(t *testing.T) {
+    pdf := bytes.Repeat([]byte("%PDF-1.4 "), 20)
+    msg := Message{
+        From:    "PT Contoh Indonesia <billing@example.com>",
+        To:      []string{"Budi Santoso <budi@example.org>", "ap@example.org"},
+        Subject: "Invoice INV/2026/10/00042 – due soon",
+        Text:    "Please find invoice INV/2026/10/00042 attached.",
+        HTML:    "<p>Please find invoice <b>INV/2026/10/00042</b> attached. Total: Rp 1.234.567,00</p>",
+        Attachments: []Attachment{
+            {Filename: "INV 2026-10-00042.pdf", ContentType: "application/pdf", Data: pdf},
+        },
+    }
+
+    data, err := msg.Bytes()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, line := range strings.Split(string(data), "\r\n") {
+        if len(line) > 998 {
+            t.Fatalf("line longer than 998 characters: %.40q", line)
+        }
+    }
+
+    m, err := mail.ReadMessage(bytes.NewReader(data))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := m.Header.Get("From"); got != `"PT Contoh Indonesia" <billing@example.com>` {
+        t.Errorf("From = %q", got)
+    }
+    if got := m.Header.Get("To"); got != `"Budi Santoso" <budi@example.org>, <ap@example.org>` {
+        t.Errorf("To = %q", got)
+    }
+    subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+    if err != nil || subject != msg.Subject {
+        t.Errorf("Subject = %q (%v), want %q", subject, err, msg.Subject)
+    }
+    if got := m.Header.Get("MIME-Version"); got != "1.0" {
+        t.Errorf("MIME-Version = %q", got)
+    }
+    if got := m.Header.Get("Message-ID"); !strings.HasSuffix(got, "@example.com>") {
+        t.Errorf("Message-ID = %q", got)
+    }
+    if _, err := m.Header.Date(); err != nil {
+        t.Errorf("Date: %v", err)
+    }
+
+    mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+    if err != nil || mediaType != "multipart/mixed" {
+        t.Fatalf("Content-Type = %q (%v)", mediaType, err)
+    }
+    mixed := multipart.NewReader(m.Body, params["boundary"])
+
+    part, err := mixed.NextPart()
+    if err != nil {
+        t.Fatal(err)
+    }
+    mediaType, params, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+    if mediaType != "multipart/alternative" {
+        t.Fatalf("first part is %q, want multipart/alternative", mediaType)
+    }
+    alt := multipart.NewReader(part, params["boundary"])
+    for _, want := range []struct{ contentType, body string }{
+        {"text/plain; charset=utf-8", msg.Text},
+        {"text/html; charset=utf-8", msg.HTML},
+    } {
+        p, err := alt.NextPart()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := p.Header.Get("Content-Type"); got != want.contentType {
+            t.Errorf("alternative Content-Type = %q, want %q", got, want.contentType)
+        }
+        // The multipart reader decodes quoted-printable bodies itself
+        body, _ := io.ReadAll(p)
+        if string(body) != want.body {
+            t.Errorf("%s body = %q, want %q", want.contentType, body, want.body)
+        }
+    }
+    if _, err := alt.NextPart(); err != io.EOF {
+        t.Errorf("extra alternative part: %v", err)
+    }
+
+    part, err = mixed.NextPart()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := part.FileName(); got != "INV 2026-10-00042.pdf" {
+        t.Errorf("attachment file name = %q", got)
+    }
+    if got := part.Header.Get("Content-Transfer-Encoding"); got != "base64" {
+        t.Errorf("attachment encoding = %q", got)
+    }
+    encoded, _ := io.ReadAll(part)
+    for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+        if len(line) > 76 {
+            t.Errorf("base64 line of %d characters", len(line))
+        }
+    }
+    decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+    if err != nil || !bytes.Equal(decoded, pdf) {
+        t.Errorf("attachment does not round-trip: %v", err)
+    }
+
+    if _, err := mixed.NextPart(); err != io.EOF {
+        t.Errorf("extra part: %v", err)
+    }
+}
+
+func TestMessageBytesHTMLOnly(t *testing.T) {
+    data, err := Message{From: "a@example.com", To: []string{"b@example.com"}, HTML: "<p>Hi</p>"}.Bytes()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if strings.Contains(string(data), "text/plain") {
+        t.Error("message without text has a text/plain part")
+    }
+}
+
+func TestMessageBytesErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        msg  Message
+    }{
+        {"no recipients", Message{From: "a@example.com"}},
+        {"invalid sender", Message{From: "not an address", To: []string{"b@example.com"}}},
+        {"invalid recipient", Message{From: "a@example.com", To: []string{"b@"}}},
+    }
+    for _, tt := range tests {
+        if _, err := tt.msg.Bytes(); err == nil {
+            t.Errorf("%s: expected an error", tt.name)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,131 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Connection security modes for SMTP.
+const (
+    SecurityStartTLS = "starttls"
+    SecurityTLS      = "tls"
+    SecurityNone     = "none"
+)
+
+// SMTP sends messages through a relay. A local sink such as MailHog works
+// with Security set to none and no credentials.
+type SMTP struct {
+    Host     string
+    Port     int
+    Username string
+    Password string
+    // From is used for messages that do not set their own sender.
+    From     string
+    // Security is starttls (upgrade when the server offers it), tls for
+    // implicit TLS or none.
+    Security string
+    Timeout  time.Duration
+}
+
+func ParseSecurity(s string) (string, error) {
+    switch s {
+    case "", SecurityStartTLS:
+        return SecurityStartTLS, nil
+    case SecurityTLS, SecurityNone:
+        return s, nil
+    }
+    return "", fmt.Errorf("unknown SMTP security %q", s)
+}
+
+func (s *SMTP) Send(msg Message) error {
+    if msg.From == "" {
+        msg.From = s.From
+    }
+    data, err := msg.Bytes()
+    if err != nil {
+        return err
+    }
+    from, err := mail.ParseAddress(msg.From)
+    if err != nil {
+        return err
+    }
+
+    timeout := s.Timeout
+    if timeout == 0 {
+        timeout = 30 * time.Second
+    }
+    addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+    dialer := &net.Dialer{Timeout: timeout}
+    tlsConfig := &tls.Config{ServerName: s.Host}
+
+    var conn net.Conn
+    if s.Security == SecurityTLS {
+        conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+    } else {
+        conn, err = dialer.Dial("tcp", addr)
+    }
+    if err != nil {
+        return err
+    }
+    conn.SetDeadline(time.Now().Add(timeout))
+
+    c, err := smtp.NewClient(conn, s.Host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer c.Close()
+
+    if s.Security == SecurityStartTLS {
+        if ok, _ := c.Extension("STARTTLS"); ok {
+            err = c.StartTLS(tlsConfig)
+            if err != nil {
+                return err
+            }
+        }
+    }
+    if s.Username != "" {
+        if ok, _ := c.Extension("AUTH"); !ok {
+            return errors.New("smtp server does not support authentication")
+        }
+        err = c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host))
+        if err != nil {
+            return err
+        }
+    }
+
+    err = c.Mail(from.Address)
+    if err != nil {
+        return err
+    }
+    for _, to := range msg.To {
+        a, err := mail.ParseAddress(to)
+        if err != nil {
+            return err
+        }
+        err = c.Rcpt(a.Address)
+        if err != nil {
+            return err
+        }
+    }
+
+    wc, err := c.Data()
+    if err != nil {
+        return err
+    }
+    _, err = wc.Write(data)
+    if err != nil {
+        return err
+    }
+    err = wc.Close()
+    if err != nil {
+        return err
+    }
+    return c.Quit()
+}
\ No newline at end of file
@@ -0,0 +1,152 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sink is a minimal SMTP server in the spirit of MailHog that records the
+// envelope and data of what it receives.
+type sink struct {
+    addr       string
+    rejectRcpt bool
+    received   chan delivery
+}
+
+type delivery struct {
+    from string
+    to   []string
+    data string
+}
+
+func startSink(t *testing.T, rejectRcpt bool) *sink {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { ln.Close() })
+
+    s := &sink{addr: ln.Addr().String(), rejectRcpt: rejectRcpt, received: make(chan delivery, 1)}
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        s.serve(textproto.NewConn(conn))
+    }()
+    return s
+}
+
+func (s *sink) serve(c *textproto.Conn) {
+    var d delivery
+    c.PrintfLine("220 sink ready")
+    for {
+        line, err := c.ReadLine()
+        if err != nil {
+            return
+        }
+        verb, arg, _ := strings.Cut(line, " ")
+        switch strings.ToUpper(verb) {
+        case "EHLO", "HELO":
+            c.PrintfLine("250 sink")
+        case "MAIL":
+            d.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+            c.PrintfLine("250 OK")
+        case "RCPT":
+            if s.rejectRcpt {
+                c.PrintfLine("550 no such user")
+                continue
+            }
+            d.to = append(d.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+            c.PrintfLine("250 OK")
+        case "DATA":
+            c.PrintfLine("354 go ahead")
+            data, err := c.ReadDotBytes()
+            if err != nil {
+                return
+            }
+            d.data = string(data)
+            c.PrintfLine("250 queued")
+            s.received <- d
+        case "QUIT":
+            c.PrintfLine("221 bye")
+            return
+        default:
+            c.PrintfLine("502 not implemented")
+        }
+    }
+}
+
+func (s *sink) mailer(t *testing.T) *SMTP {
+    host, port, _ := net.SplitHostPort(s.addr)
+    p, err := strconv.Atoi(port)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return &SMTP{Host: host, Port: p, From: "Billing <billing@example.com>", Security: SecurityNone}
+}
+
+func TestSMTPSend(t *testing.T) {
+    s := startSink(t, false)
+    err := s.mailer(t).Send(Message{
+        To:      []string{"Budi <budi@example.org>", "ap@example.org"},
+        Subject: "Invoice INV-1",
+        HTML:    "<p>Invoice INV-1</p>",
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    d := <-s.received
+    if d.from != "billing@example.com" {
+        t.Errorf("MAIL FROM = %q", d.from)
+    }
+    if strings.Join(d.to, ",") != "budi@example.org,ap@example.org" {
+        t.Errorf("RCPT TO = %q", d.to)
+    }
+    r := textproto.NewReader(bufio.NewReader(strings.NewReader(d.data)))
+    header, err := r.ReadMIMEHeader()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := header.Get("From"); got != `"Billing" <billing@example.com>` {
+        t.Errorf("From header = %q", got)
+    }
+    if got := header.Get("Subject"); got != "Invoice INV-1" {
+        t.Errorf("Subject header = %q", got)
+    }
+}
+
+func TestSMTPSendRejectedRecipient(t *testing.T) {
+    s := startSink(t, true)
+    err := s.mailer(t).Send(Message{To: []string{"nobody@example.org"}, HTML: "<p>Hi</p>"})
+    if err == nil || !strings.Contains(err.Error(), "no such user") {
+        t.Errorf("Send = %v, want the server's rejection", err)
+    }
+}
+
+func TestParseSecurity(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    string
+        wantErr bool
+    }{
+        {"", SecurityStartTLS, false},
+        {"starttls", SecurityStartTLS, false},
+        {"tls", SecurityTLS, false},
+        {"none", SecurityNone, false},
+        {"ssl", "", true},
+    }
+    for _, tt := range tests {
+        got, err := ParseSecurity(tt.in)
+        if (err != nil) != tt.wantErr || got != tt.want {
+            t.Errorf("ParseSecurity(%q) = %q, %v, want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
\ No newline at end of file
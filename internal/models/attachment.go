@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Attachment is a file uploaded against an invoice or a customer. The
+// content type is sniffed from the file, not taken from the upload.
+type Attachment struct {
+    ID          int       `json:"id"`
+    EntityType  string    `json:"entity_type"`
+    EntityID    int       `json:"entity_id"`
+    FileName    string    `json:"file_name"`
+    ContentType string    `json:"content_type"`
+    Size        int64     `json:"size"`
+    SHA256      string    `json:"sha256"`
+    StorageKey  string    `json:"-"`
+    UploadedBy  string    `json:"uploaded_by"`
+    CreatedAt   time.Time `json:"created_at"`
+}
\ No newline at end of file
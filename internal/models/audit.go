@@ -0,0 +1,18 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntry struct {
+    ID         int             `json:"id"`
+    RequestID  string          `json:"request_id"`
+    Actor      string          `json:"actor"`
+    EntityType string          `json:"entity_type"`
+    EntityID   int             `json:"entity_id"`
+    Action     string          `json:"action"`
+    Before     json.RawMessage `json:"before"`
+    After      json.RawMessage `json:"after"`
+    CreatedAt  time.Time       `json:"created_at"`
+}
\ No newline at end of file
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CompanyProfile is the seller printed on every invoice. There is a single
+// profile, stored with id 1.
+type CompanyProfile struct {
+    LegalName   string    `json:"legal_name" validate:"required,max=255"`
+    Address     string    `json:"address"`
+    TaxID       string    `json:"tax_id" validate:"max=50"`
+    Email       string    `json:"email" validate:"omitempty,email"`
+    Phone       string    `json:"phone" validate:"max=50"`
+    LogoURL     string    `json:"logo_url" validate:"omitempty,url,max=500"`
+    BankDetails string    `json:"bank_details"`
+    FooterTerms string    `json:"footer_terms"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
\ No newline at end of file
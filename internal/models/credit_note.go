@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+type CreditNote struct {
+    ID               int          `json:"id"`
+    CreditNoteNumber string       `json:"credit_note_number"`
+    InvoiceID        int          `json:"invoice_id"`
+    IssueDate        string       `json:"issue_date"`
+    Reason           string       `json:"reason"`
+    TotalAmount      money.Amount `json:"total_amount"`
+    AmountRefunded   money.Amount `json:"amount_refunded"`
+    CreatedAt        time.Time    `json:"created_at"`
+    UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+type CreditNoteLine struct {
+    ID            int          `json:"id"`
+    CreditNoteID  int          `json:"credit_note_id"`
+    InvoiceItemID int          `json:"invoice_item_id"`
+    ItemID        int          `json:"item_id"`
+    ItemName      string       `json:"item_name"`
+    Quantity      int          `json:"quantity"`
+    UnitPrice     money.Amount `json:"unit_price"`
+    LineTotal     money.Amount `json:"line_total"`
+}
+
+type CreditNoteDetail struct {
+    CreditNote
+    Items []CreditNoteLine `json:"items"`
+}
@@ -3,10 +3,17 @@ package models
 import "time"
 
 type Customer struct {
-    ID        int       `json:"id"`
-    Name      string    `json:"name"`
-    Email     string    `json:"email"`
-    Address   string    `json:"address"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
+    ID              int        `json:"id"`
+    Name            string     `json:"name"`
+    Email           string     `json:"email"`
+    Address         string     `json:"address"`
+    Currency        string     `json:"currency" validate:"omitempty,iso4217"`
+    LateFeePolicyID *int       `json:"late_fee_policy_id"`
+    // PaymentTerms empty means the system default terms.
+    PaymentTerms    string     `json:"payment_terms"`
+    // RemindersOptOut stops payment reminders; invoices are still sent.
+    RemindersOptOut bool       `json:"reminders_opt_out"`
+    CreatedAt       time.Time  `json:"created_at"`
+    UpdatedAt       time.Time  `json:"updated_at"`
+    DeletedAt       *time.Time `json:"deleted_at"`
 }
\ No newline at end of file
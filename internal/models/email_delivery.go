@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+    DeliverySent   = "sent"
+    DeliveryFailed = "failed"
+)
+
+// EmailDelivery records one attempt to email an invoice.
+type EmailDelivery struct {
+    ID        int       `json:"id"`
+    InvoiceID int       `json:"invoice_id"`
+    Recipient string    `json:"recipient"`
+    Subject   string    `json:"subject"`
+    Template  string    `json:"template"`
+    Status    string    `json:"status"`
+    Error     string    `json:"error"`
+    SentBy    string    `json:"sent_by"`
+    CreatedAt time.Time `json:"created_at"`
+}
\ No newline at end of file
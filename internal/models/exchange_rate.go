@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+// ExchangeRate is the value of one unit of Currency in the base currency
+// from RateDate until the next rate.
+type ExchangeRate struct {
+    ID        int                `json:"id"`
+    Currency  string             `json:"currency" validate:"required,iso4217"`
+    RateDate  string             `json:"rate_date" validate:"required"`
+    Rate      money.ExchangeRate `json:"rate" validate:"gt=0"`
+    Source    string             `json:"source"`
+    CreatedAt time.Time          `json:"created_at"`
+}
+
+// FXGainLoss is the realised exchange difference of one payment: what it
+// is worth in the base currency at the payment rate compared with the
+// invoice rate.
+type FXGainLoss struct {
+    PaymentID     int                `json:"payment_id"`
+    InvoiceID     int                `json:"invoice_id"`
+    InvoiceNumber string             `json:"invoice_number"`
+    Currency      string             `json:"currency"`
+    PaidAt        string             `json:"paid_at"`
+    Amount        money.Amount       `json:"amount"`
+    InvoiceRate   money.ExchangeRate `json:"invoice_rate"`
+    PaymentRate   money.ExchangeRate `json:"payment_rate"`
+    BaseAtInvoice money.Amount       `json:"base_at_invoice"`
+    BaseAtPayment money.Amount       `json:"base_at_payment"`
+    GainLoss      money.Amount       `json:"gain_loss"`
+}
\ No newline at end of file
@@ -1,15 +1,63 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
 
 type Invoice struct {
-    ID             int       `json:"id"`
-    InvoiceNumber  string    `json:"invoice_number"`
-    CustomerID     int       `json:"customer_id"`
-    IssueDate      string    `json:"issue_date"`
-    DueDate        string    `json:"due_date"`
-    TotalAmount    float64   `json:"total_amount"`
-    Status         string    `json:"status"`
-    CreatedAt      time.Time `json:"created_at"`
-    UpdatedAt      time.Time `json:"updated_at"`
-}
\ No newline at end of file
+    ID                int                `json:"id"`
+    InvoiceNumber     string             `json:"invoice_number"`
+    CustomerID        int                `json:"customer_id"`
+    IssueDate         string             `json:"issue_date"`
+    DueDate           string             `json:"due_date"`
+    PaymentTerms      string             `json:"payment_terms"`
+    Currency          string             `json:"currency"`
+    ExchangeRate      money.ExchangeRate `json:"exchange_rate"`
+    PricesIncludeTax  bool               `json:"prices_include_tax"`
+    DiscountType      string             `json:"discount_type"`
+    DiscountValue     money.Rate         `json:"discount_value"`
+    LineDiscountTotal money.Amount       `json:"line_discount_total"`
+    DiscountAmount    money.Amount       `json:"discount_amount"`
+    Subtotal          money.Amount       `json:"subtotal"`
+    TaxTotal          money.Amount       `json:"tax_total"`
+    TotalAmount       money.Amount       `json:"total_amount"`
+    AmountPaid        money.Amount       `json:"amount_paid"`
+    AmountCredited    money.Amount       `json:"amount_credited"`
+    BalanceDue        money.Amount       `json:"balance_due"`
+    BaseTotalAmount   money.Amount       `json:"base_total_amount"`
+    Status            InvoiceStatus      `json:"status"`
+    LateFeePolicyID   *int               `json:"late_fee_policy_id"`
+    QuoteID           *int               `json:"quote_id"`
+    VoidReason        string             `json:"void_reason"`
+    VoidedAt          *time.Time         `json:"voided_at"`
+    CreatedAt         time.Time          `json:"created_at"`
+    UpdatedAt         time.Time          `json:"updated_at"`
+    DeletedAt         *time.Time         `json:"deleted_at"`
+}
+
+type InvoiceLine struct {
+    ID                    int          `json:"id"`
+    InvoiceID             int          `json:"invoice_id"`
+    ItemID                int          `json:"item_id"`
+    ItemName              string       `json:"item_name"`
+    Quantity              int          `json:"quantity"`
+    UnitPrice             money.Amount `json:"unit_price"`
+    DiscountType          string       `json:"discount_type"`
+    DiscountValue         money.Rate   `json:"discount_value"`
+    DiscountAmount        money.Amount `json:"discount_amount"`
+    InvoiceDiscountAmount money.Amount `json:"invoice_discount_amount"`
+    NetAmount             money.Amount `json:"net_amount"`
+    TaxAmount             money.Amount `json:"tax_amount"`
+    LineTotal             money.Amount `json:"line_total"`
+    Taxes                 []InvoiceTax `json:"taxes"`
+    CreatedAt             time.Time    `json:"created_at"`
+    UpdatedAt             time.Time    `json:"updated_at"`
+}
+
+type InvoiceDetail struct {
+    Invoice
+    Items []InvoiceLine `json:"items"`
+    Taxes []InvoiceTax  `json:"taxes"`
+}
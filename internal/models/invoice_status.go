@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+type InvoiceStatus string
+
+const (
+    InvoiceDraft         InvoiceStatus = "draft"
+    InvoiceIssued        InvoiceStatus = "issued"
+    InvoiceSent          InvoiceStatus = "sent"
+    InvoicePartiallyPaid InvoiceStatus = "partially_paid"
+    InvoicePaid          InvoiceStatus = "paid"
+    InvoiceOverdue       InvoiceStatus = "overdue"
+    // InvoiceCredited closes an invoice settled by credit notes alone.
+    InvoiceCredited      InvoiceStatus = "credited"
+    InvoiceVoid          InvoiceStatus = "void"
+)
+
+// invoiceTransitions lists, for every status, the statuses it may move to.
+var invoiceTransitions = map[InvoiceStatus][]InvoiceStatus{
+    InvoiceDraft:         {InvoiceIssued, InvoiceVoid},
+    InvoiceIssued:        {InvoiceSent, InvoicePartiallyPaid, InvoicePaid, InvoiceCredited, InvoiceOverdue, InvoiceVoid},
+    InvoiceSent:          {InvoicePartiallyPaid, InvoicePaid, InvoiceCredited, InvoiceOverdue, InvoiceVoid},
+    InvoicePartiallyPaid: {InvoicePaid, InvoiceCredited, InvoiceOverdue},
+    // Overdue invoices stay overdue through partial payments until settled
+    InvoiceOverdue:       {InvoicePaid, InvoiceCredited, InvoiceVoid},
+    InvoicePaid:          {},
+    InvoiceCredited:      {},
+    InvoiceVoid:          {},
+}
+
+func (s InvoiceStatus) Valid() bool {
+    _, ok := invoiceTransitions[s]
+    return ok
+}
+
+func (s InvoiceStatus) CanTransitionTo(next InvoiceStatus) bool {
+    for _, allowed := range invoiceTransitions[s] {
+        if allowed == next {
+            return true
+        }
+    }
+    return false
+}
+
+// Editable reports whether the invoice lines may still be changed.
+func (s InvoiceStatus) Editable() bool {
+    return s == InvoiceDraft
+}
+
+// Payable reports whether payments may be recorded against the invoice.
+func (s InvoiceStatus) Payable() bool {
+    switch s {
+    case InvoiceIssued, InvoiceSent, InvoicePartiallyPaid, InvoiceOverdue:
+        return true
+    }
+    return false
+}
+
+// Creditable reports whether credit notes may be raised against the invoice.
+func (s InvoiceStatus) Creditable() bool {
+    return s != InvoiceDraft && s != InvoiceVoid
+}
+
+type InvoiceStatusChange struct {
+    ID         int           `json:"id"`
+    InvoiceID  int           `json:"invoice_id"`
+    FromStatus InvoiceStatus `json:"from_status"`
+    ToStatus   InvoiceStatus `json:"to_status"`
+    ChangedBy  string        `json:"changed_by"`
+    Note       string        `json:"note"`
+    CreatedAt  time.Time     `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type InvoiceTemplate struct {
+    Name      string    `json:"name"`
+    // Source is database, directory or builtin.
+    Source    string    `json:"source"`
+    Body      string    `json:"body,omitempty" validate:"required"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
\ No newline at end of file
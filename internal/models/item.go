@@ -1,11 +1,18 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
 
 type Item struct {
-    ID        int       `json:"id"`
-    Name      string    `json:"name"`
-    Price     float64   `json:"price"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
+    ID        int          `json:"id"`
+    Name      string       `json:"name"`
+    Price     money.Amount `json:"price"`
+    Currency  string       `json:"currency" validate:"omitempty,iso4217"`
+    TaxRateID *int         `json:"tax_rate_id"`
+    CreatedAt time.Time    `json:"created_at"`
+    UpdatedAt time.Time    `json:"updated_at"`
+    DeletedAt *time.Time   `json:"deleted_at"`
 }
\ No newline at end of file
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+// LateFeePolicy amounts are in the currency of the invoice they apply to.
+// A zero MaxAmount leaves the fee uncapped.
+type LateFeePolicy struct {
+    ID        int          `json:"id"`
+    Name      string       `json:"name" validate:"required,max=100"`
+    FeeType   string       `json:"fee_type" validate:"required,oneof=flat percent daily_interest"`
+    Amount    money.Amount `json:"amount" validate:"gte=0"`
+    Rate      money.Rate   `json:"rate" validate:"gte=0"`
+    GraceDays int          `json:"grace_days" validate:"min=0"`
+    MaxAmount money.Amount `json:"max_amount" validate:"gte=0"`
+    ApplyAs   string       `json:"apply_as" validate:"required,oneof=line invoice"`
+    FeeItemID int          `json:"fee_item_id" validate:"required"`
+    Active    bool         `json:"active"`
+    CreatedAt time.Time    `json:"created_at"`
+    UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// LateFee records one assessment together with the policy terms it was
+// computed with, so the fee can be reproduced after the policy changes.
+type LateFee struct {
+    ID            int          `json:"id"`
+    InvoiceID     int          `json:"invoice_id"`
+    PolicyID      int          `json:"policy_id"`
+    FeeType       string       `json:"fee_type"`
+    Amount        money.Amount `json:"amount"`
+    Rate          money.Rate   `json:"rate"`
+    GraceDays     int          `json:"grace_days"`
+    MaxAmount     money.Amount `json:"max_amount"`
+    AssessedOn    string       `json:"assessed_on"`
+    DaysLate      int          `json:"days_late"`
+    BaseAmount    money.Amount `json:"base_amount"`
+    FeeAmount     money.Amount `json:"fee_amount"`
+    TotalAccrued  money.Amount `json:"total_accrued"`
+    InvoiceItemID *int         `json:"invoice_item_id"`
+    FeeInvoiceID  *int         `json:"fee_invoice_id"`
+    CreatedAt     time.Time    `json:"created_at"`
+}
\ No newline at end of file
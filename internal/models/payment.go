@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+type Payment struct {
+    ID           int                `json:"id"`
+    InvoiceID    int                `json:"invoice_id"`
+    // CreditNoteID is set on refunds, which are stored as negative amounts.
+    CreditNoteID *int               `json:"credit_note_id,omitempty"`
+    Amount       money.Amount       `json:"amount" validate:"required,gt=0"`
+    // ExchangeRate is looked up on paid_at when the payment leaves it out.
+    ExchangeRate money.ExchangeRate `json:"exchange_rate" validate:"gte=0"`
+    PaidAt       string             `json:"paid_at"`
+    Method       string             `json:"method" validate:"required,oneof=cash bank_transfer card e_wallet cheque other"`
+    Reference    string             `json:"reference" validate:"max=100"`
+    CreatedAt    time.Time          `json:"created_at"`
+}
\ No newline at end of file
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+type QuoteStatus string
+
+const (
+    QuoteDraft     QuoteStatus = "draft"
+    QuoteSent      QuoteStatus = "sent"
+    QuoteAccepted  QuoteStatus = "accepted"
+    QuoteDeclined  QuoteStatus = "declined"
+    QuoteExpired   QuoteStatus = "expired"
+    QuoteConverted QuoteStatus = "converted"
+)
+
+// quoteTransitions lists, for every status, the statuses it may move to.
+var quoteTransitions = map[QuoteStatus][]QuoteStatus{
+    QuoteDraft:     {QuoteSent, QuoteAccepted, QuoteDeclined},
+    QuoteSent:      {QuoteAccepted, QuoteDeclined, QuoteExpired},
+    QuoteAccepted:  {QuoteConverted},
+    QuoteDeclined:  {},
+    QuoteExpired:   {},
+    QuoteConverted: {},
+}
+
+func (s QuoteStatus) Valid() bool {
+    _, ok := quoteTransitions[s]
+    return ok
+}
+
+func (s QuoteStatus) CanTransitionTo(next QuoteStatus) bool {
+    for _, allowed := range quoteTransitions[s] {
+        if allowed == next {
+            return true
+        }
+    }
+    return false
+}
+
+// Editable reports whether the quote and its lines may still be changed.
+func (s QuoteStatus) Editable() bool {
+    return s == QuoteDraft
+}
+
+type Quote struct {
+    ID                int                `json:"id"`
+    QuoteNumber       string             `json:"quote_number"`
+    CustomerID        int                `json:"customer_id"`
+    IssueDate         string             `json:"issue_date"`
+    ExpiryDate        string             `json:"expiry_date"`
+    Currency          string             `json:"currency"`
+    ExchangeRate      money.ExchangeRate `json:"exchange_rate"`
+    PricesIncludeTax  bool               `json:"prices_include_tax"`
+    DiscountType      string             `json:"discount_type"`
+    DiscountValue     money.Rate         `json:"discount_value"`
+    LineDiscountTotal money.Amount       `json:"line_discount_total"`
+    DiscountAmount    money.Amount       `json:"discount_amount"`
+    Subtotal          money.Amount       `json:"subtotal"`
+    TaxTotal          money.Amount       `json:"tax_total"`
+    TotalAmount       money.Amount       `json:"total_amount"`
+    Status            QuoteStatus        `json:"status"`
+    Notes             string             `json:"notes"`
+    // InvoiceID is the invoice the quote was converted into.
+    InvoiceID         *int               `json:"invoice_id"`
+    CreatedAt         time.Time          `json:"created_at"`
+    UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+// QuoteLine is priced exactly like an invoice line, so a converted quote
+// bills the amounts the customer accepted.
+type QuoteLine struct {
+    ID                  int          `json:"id"`
+    QuoteID             int          `json:"quote_id"`
+    ItemID              int          `json:"item_id"`
+    ItemName            string       `json:"item_name"`
+    Quantity            int          `json:"quantity"`
+    UnitPrice           money.Amount `json:"unit_price"`
+    DiscountType        string       `json:"discount_type"`
+    DiscountValue       money.Rate   `json:"discount_value"`
+    DiscountAmount      money.Amount `json:"discount_amount"`
+    QuoteDiscountAmount money.Amount `json:"quote_discount_amount"`
+    NetAmount           money.Amount `json:"net_amount"`
+    TaxAmount           money.Amount `json:"tax_amount"`
+    LineTotal           money.Amount `json:"line_total"`
+    Taxes               []InvoiceTax `json:"taxes"`
+    CreatedAt           time.Time    `json:"created_at"`
+    UpdatedAt           time.Time    `json:"updated_at"`
+}
+
+type QuoteDetail struct {
+    Quote
+    Items []QuoteLine  `json:"items"`
+    Taxes []InvoiceTax `json:"taxes"`
+}
\ No newline at end of file
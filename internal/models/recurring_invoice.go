@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+// RecurringInvoice is a template that generates an invoice on every run
+// date of its schedule.
+type RecurringInvoice struct {
+    ID               int                    `json:"id"`
+    CustomerID       int                    `json:"customer_id" validate:"required"`
+    Currency         string                 `json:"currency" validate:"omitempty,iso4217"`
+    PricesIncludeTax bool                   `json:"prices_include_tax"`
+    DiscountType     string                 `json:"discount_type"`
+    DiscountValue    money.Rate             `json:"discount_value"`
+    IntervalUnit     string                 `json:"interval_unit" validate:"required,oneof=week month year"`
+    IntervalCount    int                    `json:"interval_count" validate:"min=1"`
+    DayOfMonth       int                    `json:"day_of_month" validate:"min=-1,max=31"`
+    StartDate        string                 `json:"start_date" validate:"required"`
+    EndDate          *string                `json:"end_date"`
+    // PaymentTerms empty bills on the customer's payment terms.
+    PaymentTerms     string                 `json:"payment_terms"`
+    AutoIssue        bool                   `json:"auto_issue"`
+    Active           bool                   `json:"active"`
+    LastRunDate      *string                `json:"last_run_date"`
+    NextRunDate      *string                `json:"next_run_date"`
+    Items            []RecurringInvoiceLine `json:"items" validate:"required,min=1,dive"`
+    CreatedAt        time.Time              `json:"created_at"`
+    UpdatedAt        time.Time              `json:"updated_at"`
+}
+
+type RecurringInvoiceLine struct {
+    ID            int        `json:"id"`
+    ItemID        int        `json:"item_id" validate:"required"`
+    Quantity      int        `json:"quantity" validate:"required,min=1"`
+    DiscountType  string     `json:"discount_type"`
+    DiscountValue money.Rate `json:"discount_value"`
+    TaxRateIDs    []int      `json:"tax_rate_ids"`
+}
+
+// RecurringInvoiceRun records the invoice generated for one run date.
+type RecurringInvoiceRun struct {
+    ID                 int       `json:"id"`
+    RecurringInvoiceID int       `json:"recurring_invoice_id"`
+    RunDate            string    `json:"run_date"`
+    InvoiceID          int       `json:"invoice_id"`
+    CreatedAt          time.Time `json:"created_at"`
+}
\ No newline at end of file
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ReminderRule sends a payment reminder OffsetDays from the due date: -3 is
+// three days before, 7 a week after. Subject and Body are templates; empty
+// ones fall back to the built-in reminder.
+type ReminderRule struct {
+    ID         int       `json:"id"`
+    Name       string    `json:"name" validate:"required,max=100"`
+    OffsetDays int       `json:"offset_days" validate:"min=-365,max=365"`
+    Subject    string    `json:"subject" validate:"max=255"`
+    Body       string    `json:"body"`
+    Active     bool      `json:"active"`
+    CreatedAt  time.Time `json:"created_at"`
+    UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// PaymentReminder is the log entry for one rule on one invoice. A failed
+// reminder is retried on the next run until it is delivered.
+type PaymentReminder struct {
+    ID          int       `json:"id"`
+    InvoiceID   int       `json:"invoice_id"`
+    RuleID      int       `json:"rule_id"`
+    DaysFromDue int       `json:"days_from_due"`
+    Channel     string    `json:"channel"`
+    Recipient   string    `json:"recipient"`
+    Subject     string    `json:"subject"`
+    Status      string    `json:"status"`
+    Error       string    `json:"error"`
+    Attempts    int       `json:"attempts"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
\ No newline at end of file
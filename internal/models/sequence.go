@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type NumberSequence struct {
+    Name          string    `json:"name"`
+    Pattern       string    `json:"pattern" validate:"required,max=50"`
+    ResetPolicy   string    `json:"reset_policy" validate:"required,oneof=never yearly monthly"`
+    CurrentPeriod string    `json:"current_period"`
+    LastValue     int       `json:"last_value"`
+    UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "invoice-system/internal/money"
+
+const (
+    StatementInvoice    = "invoice"
+    StatementPayment    = "payment"
+    StatementRefund     = "refund"
+    StatementCreditNote = "credit_note"
+)
+
+// StatementEntry is one document on a statement of account. Debits raise
+// what the customer owes and credits lower it.
+type StatementEntry struct {
+    Date          string       `json:"date"`
+    Type          string       `json:"type"`
+    DocumentID    int          `json:"document_id"`
+    Reference     string       `json:"reference"`
+    InvoiceNumber string       `json:"invoice_number"`
+    Description   string       `json:"description"`
+    Debit         money.Amount `json:"debit"`
+    Credit        money.Amount `json:"credit"`
+    Balance       money.Amount `json:"balance"`
+}
+
+// Statement lists a customer's documents in one currency between From and
+// To, both inclusive.
+type Statement struct {
+    Customer       Customer         `json:"customer"`
+    Currency       string           `json:"currency"`
+    From           string           `json:"from"`
+    To             string           `json:"to"`
+    OpeningBalance money.Amount     `json:"opening_balance"`
+    Entries        []StatementEntry `json:"entries"`
+    TotalDebits    money.Amount     `json:"total_debits"`
+    TotalCredits   money.Amount     `json:"total_credits"`
+    ClosingBalance money.Amount     `json:"closing_balance"`
+}
\ No newline at end of file
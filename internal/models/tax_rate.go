@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+type TaxRate struct {
+    ID        int        `json:"id"`
+    Name      string     `json:"name" validate:"required,max=100"`
+    Rate      money.Rate `json:"rate" validate:"gte=0"`
+    Compound  bool       `json:"compound"`
+    Active    bool       `json:"active"`
+    CreatedAt time.Time  `json:"created_at"`
+    UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// InvoiceTax is a tax charged on a line, or the total of one rate across
+// an invoice. Name, rate and compound are snapshots taken when the line was
+// priced.
+type InvoiceTax struct {
+    TaxRateID     int          `json:"tax_rate_id"`
+    Name          string       `json:"name"`
+    Rate          money.Rate   `json:"rate"`
+    Compound      bool         `json:"compound"`
+    TaxableAmount money.Amount `json:"taxable_amount"`
+    TaxAmount     money.Amount `json:"tax_amount"`
+}
@@ -0,0 +1,101 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// parseScaled parses a decimal string into an integer scaled by 10^places.
+// It is used for input, so digits beyond places are an error rather than
+// rounded away.
+func parseScaled(s string, places int) (int64, error) {
+    r, err := scaledRat(s, places)
+    if err != nil {
+        return 0, err
+    }
+    if !r.IsInt() {
+        return 0, fmt.Errorf("decimal %q has more than %d decimal places", strings.TrimSpace(s), places)
+    }
+    return ratInt64(r, s)
+}
+
+// roundScaled is parseScaled for computed values, rounding any extra digits
+// with the configured rounding mode.
+func roundScaled(s string, places int) (int64, error) {
+    r, err := scaledRat(s, places)
+    if err != nil {
+        return 0, err
+    }
+    return ratInt64(new(big.Rat).SetInt(roundRat(r, Rounding)), s)
+}
+
+// scaledRat returns the value of s multiplied by 10^places.
+func scaledRat(s string, places int) (*big.Rat, error) {
+    s = strings.TrimSpace(s)
+    if s == "" {
+        return nil, errors.New("empty decimal")
+    }
+
+    r, ok := new(big.Rat).SetString(s)
+    if !ok || strings.ContainsAny(s, "/eE") {
+        return nil, fmt.Errorf("invalid decimal %q", s)
+    }
+    return r.Mul(r, new(big.Rat).SetInt(pow10(places))), nil
+}
+
+func ratInt64(r *big.Rat, s string) (int64, error) {
+    n := r.Num()
+    if !n.IsInt64() {
+        return 0, fmt.Errorf("decimal %q out of range", strings.TrimSpace(s))
+    }
+    return n.Int64(), nil
+}
+
+// formatScaled renders n / 10^places with exactly places decimals.
+func formatScaled(n int64, places int) string {
+    sign := ""
+    u := new(big.Int).SetInt64(n)
+    if n < 0 {
+        sign = "-"
+        u.Neg(u)
+    }
+    digits := u.String()
+    if len(digits) <= places {
+        digits = strings.Repeat("0", places-len(digits)+1) + digits
+    }
+    return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+}
+
+// scanScaled converts a database value into a scaled integer. Columns may
+// hold computed values, such as averages, so extra digits are rounded.
+func scanScaled(src interface{}, places int) (int64, error) {
+    switch v := src.(type) {
+    case nil:
+        return 0, nil
+    case []byte:
+        return roundScaled(string(v), places)
+    case string:
+        return roundScaled(v, places)
+    case int64:
+        return roundScaled(fmt.Sprint(v), places)
+    case float64:
+        return roundScaled(fmt.Sprint(v), places)
+    }
+    return 0, fmt.Errorf("cannot scan %T into a decimal", src)
+}
+
+// unmarshalScaled accepts a JSON number or a quoted decimal string.
+func unmarshalScaled(b []byte, places int) (int64, error) {
+    s := string(b)
+    if s == "null" {
+        return 0, nil
+    }
+    s = strings.Trim(s, `"`)
+    return parseScaled(s, places)
+}
+
+func pow10(places int) *big.Int {
+    return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+}
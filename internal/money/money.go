@@ -0,0 +1,201 @@
+// Package money implements exact fixed-point arithmetic for amounts and
+// rates so totals never drift the way float64 sums do.
+package money
+
+import (
+	"database/sql/driver"
+	"math/big"
+	"strings"
+)
+
+// Amount is a monetary amount in minor units (hundredths), matching the
+// DECIMAL(15,2) columns it is stored in.
+type Amount int64
+
+const amountPlaces = 2
+
+func ParseAmount(s string) (Amount, error) {
+    n, err := parseScaled(s, amountPlaces)
+    return Amount(n), err
+}
+
+func (a Amount) String() string {
+    return formatScaled(int64(a), amountPlaces)
+}
+
+// Format groups thousands for printed documents: 1,234,567.89.
+func (a Amount) Format() string {
+    s := a.String()
+    sign := ""
+    if strings.HasPrefix(s, "-") {
+        sign, s = "-", s[1:]
+    }
+    whole, frac, _ := strings.Cut(s, ".")
+    for i := len(whole) - 3; i > 0; i -= 3 {
+        whole = whole[:i] + "," + whole[i:]
+    }
+    return sign + whole + "." + frac
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+    return []byte(a.String()), nil
+}
+
+func (a *Amount) UnmarshalJSON(b []byte) error {
+    n, err := unmarshalScaled(b, amountPlaces)
+    *a = Amount(n)
+    return err
+}
+
+func (a *Amount) Scan(src interface{}) error {
+    n, err := scanScaled(src, amountPlaces)
+    *a = Amount(n)
+    return err
+}
+
+func (a Amount) Value() (driver.Value, error) {
+    return a.String(), nil
+}
+
+func (a Amount) Mul(quantity int) Amount {
+    return a * Amount(quantity)
+}
+
+// MulRat multiplies by an exact fraction and rounds the result.
+func (a Amount) MulRat(r *big.Rat) Amount {
+    x := new(big.Rat).SetInt64(int64(a))
+    x.Mul(x, r)
+    return Amount(roundRat(x, Rounding).Int64())
+}
+
+// Percent returns p percent of a, rounded.
+func (a Amount) Percent(p Rate) Amount {
+    return a.MulRat(new(big.Rat).Quo(p.Rat(), big.NewRat(100, 1)))
+}
+
+// Share returns a * part / whole, rounded. It is zero when whole is zero.
+func (a Amount) Share(part, whole Amount) Amount {
+    if whole == 0 {
+        return 0
+    }
+    return a.MulRat(big.NewRat(int64(part), int64(whole)))
+}
+
+func Min(a, b Amount) Amount {
+    if a < b {
+        return a
+    }
+    return b
+}
+
+func Max(a, b Amount) Amount {
+    if a > b {
+        return a
+    }
+    return b
+}
+
+// Rate is a fixed-point number with four decimal places, used for
+// percentages such as tax rates and percentage discounts.
+type Rate int64
+
+const ratePlaces = 4
+
+// RateScale is the integer value of a Rate equal to 1.
+const RateScale Rate = 10000
+
+func ParseRate(s string) (Rate, error) {
+    n, err := parseScaled(s, ratePlaces)
+    return Rate(n), err
+}
+
+func (r Rate) String() string {
+    return formatScaled(int64(r), ratePlaces)
+}
+
+// Format drops the trailing zeros of a rate for printed documents: 11, 2.5.
+func (r Rate) Format() string {
+    s := strings.TrimRight(r.String(), "0")
+    return strings.TrimSuffix(s, ".")
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+    return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(b []byte) error {
+    n, err := unmarshalScaled(b, ratePlaces)
+    *r = Rate(n)
+    return err
+}
+
+func (r *Rate) Scan(src interface{}) error {
+    n, err := scanScaled(src, ratePlaces)
+    *r = Rate(n)
+    return err
+}
+
+func (r Rate) Value() (driver.Value, error) {
+    return r.String(), nil
+}
+
+// Rat returns the exact value of r.
+func (r Rate) Rat() *big.Rat {
+    return big.NewRat(int64(r), int64(RateScale))
+}
+
+// Amount converts r to an amount, rounding away the extra decimals.
+func (r Rate) Amount() Amount {
+    return Amount(roundRat(big.NewRat(int64(r), 100), Rounding).Int64())
+}
+
+// ExchangeRate is the value of one unit of a currency in the base currency,
+// with eight decimal places.
+type ExchangeRate int64
+
+const exchangeRatePlaces = 8
+
+// One is the rate of the base currency to itself.
+const One ExchangeRate = 100000000
+
+func ParseExchangeRate(s string) (ExchangeRate, error) {
+    n, err := parseScaled(s, exchangeRatePlaces)
+    return ExchangeRate(n), err
+}
+
+func (r ExchangeRate) String() string {
+    return formatScaled(int64(r), exchangeRatePlaces)
+}
+
+func (r ExchangeRate) MarshalJSON() ([]byte, error) {
+    return []byte(r.String()), nil
+}
+
+func (r *ExchangeRate) UnmarshalJSON(b []byte) error {
+    n, err := unmarshalScaled(b, exchangeRatePlaces)
+    *r = ExchangeRate(n)
+    return err
+}
+
+func (r *ExchangeRate) Scan(src interface{}) error {
+    n, err := scanScaled(src, exchangeRatePlaces)
+    *r = ExchangeRate(n)
+    return err
+}
+
+func (r ExchangeRate) Value() (driver.Value, error) {
+    return r.String(), nil
+}
+
+// ToBase converts a into the base currency at rate r.
+func (a Amount) ToBase(r ExchangeRate) Amount {
+    return a.MulRat(big.NewRat(int64(r), int64(One)))
+}
+
+// Convert converts a from a currency quoted at from into one quoted at to.
+func (a Amount) Convert(from, to ExchangeRate) Amount {
+    if from == to {
+        return a
+    }
+    return a.MulRat(big.NewRat(int64(from), int64(to)))
+}
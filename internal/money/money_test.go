@@ -0,0 +1,216 @@
+package money
+
+import "testing"
+
+func withRounding(t *testing.T, mode RoundingMode) {
+    t.Helper()
+    saved := Rounding
+    Rounding = mode
+    t.Cleanup(func() { Rounding = saved })
+}
+
+func TestParseAmount(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    Amount
+        wantErr bool
+    }{
+        {in: "12.34", want: 1234},
+        {in: " 7 ", want: 700},
+        {in: "-0.5", want: -50},
+        {in: "1.500", want: 150},
+        {in: "1.005", wantErr: true},
+        {in: "", wantErr: true},
+        {in: "abc", wantErr: true},
+        {in: "1e3", wantErr: true},
+        {in: "1/3", wantErr: true},
+        {in: "999999999999999999999", wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := ParseAmount(tt.in)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("ParseAmount(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && got != tt.want {
+            t.Errorf("ParseAmount(%q) = %d, want %d", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestParseRate(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    Rate
+        wantErr bool
+    }{
+        {in: "11", want: 110000},
+        {in: "0.1234", want: 1234},
+        {in: "0.123456", wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := ParseRate(tt.in)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("ParseRate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && got != tt.want {
+            t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestUnmarshalAmount(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    Amount
+        wantErr bool
+    }{
+        {in: `12.5`, want: 1250},
+        {in: `"12.50"`, want: 1250},
+        {in: `null`, want: 0},
+        {in: `1.005`, wantErr: true},
+        {in: `"1.005"`, wantErr: true},
+    }
+    for _, tt := range tests {
+        var got Amount
+        err := got.UnmarshalJSON([]byte(tt.in))
+        if (err != nil) != tt.wantErr {
+            t.Errorf("UnmarshalJSON(%s) error = %v, want error %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && got != tt.want {
+            t.Errorf("UnmarshalJSON(%s) = %d, want %d", tt.in, got, tt.want)
+        }
+    }
+}
+
+// Computed database values, such as averages, are rounded on scan.
+func TestScanAmountRounds(t *testing.T) {
+    tests := []struct {
+        in   interface{}
+        mode RoundingMode
+        want Amount
+    }{
+        {in: "12.34", want: 1234},
+        {in: []byte("1.005"), mode: HalfUp, want: 101},
+        {in: "-1.005", mode: HalfUp, want: -101},
+        {in: "1.005", mode: HalfEven, want: 100},
+        {in: "1.015", mode: HalfEven, want: 102},
+        {in: "1.009", mode: Down, want: 100},
+        {in: "-1.009", mode: Down, want: -100},
+        {in: int64(3), want: 300},
+        {in: nil, want: 0},
+    }
+    for _, tt := range tests {
+        withRounding(t, tt.mode)
+        var got Amount
+        err := got.Scan(tt.in)
+        if err != nil {
+            t.Errorf("Scan(%v) error = %v", tt.in, err)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("Scan(%v) with mode %d = %d, want %d", tt.in, tt.mode, got, tt.want)
+        }
+    }
+}
+
+func TestAmountString(t *testing.T) {
+    tests := []struct {
+        in     Amount
+        str    string
+        format string
+    }{
+        {0, "0.00", "0.00"},
+        {5, "0.05", "0.05"},
+        {-5, "-0.05", "-0.05"},
+        {123456, "1234.56", "1,234.56"},
+        {-123456789, "-1234567.89", "-1,234,567.89"},
+    }
+    for _, tt := range tests {
+        if got := tt.in.String(); got != tt.str {
+            t.Errorf("Amount(%d).String() = %q, want %q", tt.in, got, tt.str)
+        }
+        if got := tt.in.Format(); got != tt.format {
+            t.Errorf("Amount(%d).Format() = %q, want %q", tt.in, got, tt.format)
+        }
+    }
+}
+
+func TestPercent(t *testing.T) {
+    tests := []struct {
+        amount Amount
+        rate   Rate
+        mode   RoundingMode
+        want   Amount
+    }{
+        {amount: 10000, rate: 110000, want: 1100},
+        {amount: 1005, rate: 100000, mode: HalfUp, want: 101},
+        {amount: 1005, rate: 100000, mode: HalfEven, want: 100},
+        {amount: 1015, rate: 100000, mode: HalfEven, want: 102},
+        {amount: 1009, rate: 100000, mode: Down, want: 100},
+        {amount: -1005, rate: 100000, mode: HalfUp, want: -101},
+        {amount: 333, rate: 25000, mode: HalfUp, want: 8},
+    }
+    for _, tt := range tests {
+        withRounding(t, tt.mode)
+        if got := tt.amount.Percent(tt.rate); got != tt.want {
+            t.Errorf("%s.Percent(%s) with mode %d = %s, want %s", tt.amount, tt.rate, tt.mode, got, tt.want)
+        }
+    }
+}
+
+func TestShare(t *testing.T) {
+    tests := []struct {
+        amount      Amount
+        part, whole Amount
+        want        Amount
+    }{
+        {10000, 1, 3, 3333},
+        {10000, 2, 3, 6667},
+        {10000, 3, 3, 10000},
+        {10000, 1, 0, 0},
+        {-10000, 1, 3, -3333},
+    }
+    for _, tt := range tests {
+        if got := tt.amount.Share(tt.part, tt.whole); got != tt.want {
+            t.Errorf("%s.Share(%d, %d) = %s, want %s", tt.amount, tt.part, tt.whole, got, tt.want)
+        }
+    }
+}
+
+func TestConvert(t *testing.T) {
+    eur, _ := ParseExchangeRate("17250.5")
+    usd, _ := ParseExchangeRate("15800")
+
+    if got := Amount(1000).ToBase(eur); got != 17250500 {
+        t.Errorf("ToBase = %s, want 172505.00", got)
+    }
+    if got := Amount(1000).Convert(eur, eur); got != 1000 {
+        t.Errorf("Convert to the same currency = %s, want 10.00", got)
+    }
+    if got := Amount(1000).Convert(eur, usd); got != 1092 {
+        t.Errorf("Convert = %s, want 10.92", got)
+    }
+}
+
+func TestParseRoundingMode(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    RoundingMode
+        wantErr bool
+    }{
+        {"", HalfUp, false},
+        {"half_up", HalfUp, false},
+        {"half_even", HalfEven, false},
+        {"down", Down, false},
+        {"ceiling", HalfUp, true},
+    }
+    for _, tt := range tests {
+        got, err := ParseRoundingMode(tt.in)
+        if (err != nil) != tt.wantErr || got != tt.want {
+            t.Errorf("ParseRoundingMode(%q) = %d, %v, want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
\ No newline at end of file
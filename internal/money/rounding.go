@@ -0,0 +1,59 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+type RoundingMode int
+
+const (
+    // HalfUp rounds halves away from zero, as on paper invoices.
+    HalfUp RoundingMode = iota
+    // HalfEven rounds halves to the nearest even digit (banker's rounding).
+    HalfEven
+    // Down truncates towards zero.
+    Down
+)
+
+// Rounding is the mode used whenever a calculation has to drop digits.
+// It is set once at start-up from MONEY_ROUNDING.
+var Rounding = HalfUp
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+    switch s {
+    case "", "half_up":
+        return HalfUp, nil
+    case "half_even":
+        return HalfEven, nil
+    case "down":
+        return Down, nil
+    }
+    return HalfUp, fmt.Errorf("unknown rounding mode %q", s)
+}
+
+// roundRat rounds r to an integer using mode.
+func roundRat(r *big.Rat, mode RoundingMode) *big.Int {
+    num := new(big.Int).Set(r.Num())
+    den := r.Denom()
+
+    q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+    if m.Sign() == 0 || mode == Down {
+        return q
+    }
+
+    // Compare twice the remainder with the denominator to find the half
+    twice := new(big.Int).Abs(m)
+    twice.Lsh(twice, 1)
+    cmp := twice.Cmp(den)
+
+    away := cmp > 0 || (cmp == 0 && (mode == HalfUp || q.Bit(0) == 1))
+    if away {
+        if num.Sign() < 0 {
+            q.Sub(q, big.NewInt(1))
+        } else {
+            q.Add(q, big.NewInt(1))
+        }
+    }
+    return q
+}
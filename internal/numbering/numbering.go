@@ -0,0 +1,138 @@
+package numbering
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+    InvoiceSequence    = "invoice"
+    CreditNoteSequence = "credit_note"
+    QuoteSequence      = "quote"
+
+    ResetNever   = "never"
+    ResetYearly  = "yearly"
+    ResetMonthly = "monthly"
+
+    // MaxLength matches the width of the document number columns.
+    MaxLength = 50
+)
+
+var (
+    ErrSequenceNotFound = errors.New("number sequence not found")
+
+    tokenPattern = regexp.MustCompile(`\{(YYYY|YY|MM|DD|SEQ(?::(\d+))?)\}`)
+    bracePattern = regexp.MustCompile(`\{[^{}]*\}`)
+)
+
+// Next allocates the next number of the named sequence. The sequence row
+// stays locked until tx finishes, so concurrent callers are serialised and a
+// rolled back transaction gives its number back, leaving no gaps.
+func Next(tx *sql.Tx, name string, now time.Time) (string, error) {
+    var pattern, policy, period string
+    var last int
+    err := tx.QueryRow(`
+        SELECT pattern, reset_policy, current_period, last_value
+        FROM number_sequences
+        WHERE name = ?
+        FOR UPDATE
+    `, name).Scan(&pattern, &policy, &period, &last)
+    if err == sql.ErrNoRows {
+        return "", ErrSequenceNotFound
+    } else if err != nil {
+        return "", err
+    }
+
+    current := Period(policy, now)
+    if current != period {
+        last = 0
+    }
+    last++
+
+    _, err = tx.Exec(`
+        UPDATE number_sequences
+        SET current_period = ?, last_value = ?
+        WHERE name = ?
+    `, current, last, name)
+    if err != nil {
+        return "", err
+    }
+
+    return Format(pattern, now, last), nil
+}
+
+// Period returns the key identifying the reset window that t falls in.
+func Period(policy string, t time.Time) string {
+    switch policy {
+    case ResetYearly:
+        return t.Format("2006")
+    case ResetMonthly:
+        return t.Format("2006-01")
+    }
+    return ""
+}
+
+// Format expands the {YYYY}, {YY}, {MM}, {DD} and {SEQ[:width]} tokens of
+// pattern, e.g. "INV/{YYYY}/{MM}/{SEQ:5}" becomes "INV/2026/10/00042".
+func Format(pattern string, t time.Time, seq int) string {
+    return tokenPattern.ReplaceAllStringFunc(pattern, func(token string) string {
+        m := tokenPattern.FindStringSubmatch(token)
+        switch m[1] {
+        case "YYYY":
+            return t.Format("2006")
+        case "YY":
+            return t.Format("06")
+        case "MM":
+            return t.Format("01")
+        case "DD":
+            return t.Format("02")
+        }
+        width, _ := strconv.Atoi(m[2])
+        return fmt.Sprintf("%0*d", width, seq)
+    })
+}
+
+// Validate checks that pattern can produce unique numbers under policy.
+func Validate(pattern, policy string) error {
+    seqs := 0
+    for _, token := range bracePattern.FindAllString(pattern, -1) {
+        m := tokenPattern.FindStringSubmatch(token)
+        if m == nil || m[0] != token {
+            return fmt.Errorf("unknown token %s", token)
+        }
+        if strings.HasPrefix(m[1], "SEQ") {
+            if width, err := strconv.Atoi(m[2]); m[2] != "" && (err != nil || width > MaxLength) {
+                return fmt.Errorf("{SEQ} width must be at most %d", MaxLength)
+            }
+            seqs++
+        }
+    }
+    if seqs != 1 {
+        return errors.New("pattern must contain exactly one {SEQ} or {SEQ:n} token")
+    }
+    hasYear := strings.Contains(pattern, "{YYYY}") || strings.Contains(pattern, "{YY}")
+    switch policy {
+    case ResetNever:
+    case ResetYearly:
+        if !hasYear {
+            return errors.New("yearly reset requires a {YYYY} or {YY} token")
+        }
+    case ResetMonthly:
+        if !hasYear || !strings.Contains(pattern, "{MM}") {
+            return errors.New("monthly reset requires year and {MM} tokens")
+        }
+    default:
+        return errors.New("reset policy must be never, yearly or monthly")
+    }
+
+    sample := Format(pattern, time.Date(2000, 12, 31, 0, 0, 0, 0, time.UTC), 999999999)
+    if len(sample) > MaxLength {
+        return fmt.Errorf("pattern expands beyond %d characters", MaxLength)
+    }
+    return nil
+}
@@ -0,0 +1,73 @@
+package numbering
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+    at := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+    tests := []struct {
+        pattern string
+        seq     int
+        want    string
+    }{
+        {"INV/{YYYY}/{MM}/{SEQ:5}", 42, "INV/2026/03/00042"},
+        {"INV-{YY}{MM}{DD}-{SEQ}", 7, "INV-260307-7"},
+        {"CN{SEQ:3}", 12345, "CN12345"},
+        {"{SEQ:0}", 9, "9"},
+        {"Q-{FOO}-{SEQ:2}", 1, "Q-{FOO}-01"},
+    }
+    for _, tt := range tests {
+        if got := Format(tt.pattern, at, tt.seq); got != tt.want {
+            t.Errorf("Format(%q, %d) = %q, want %q", tt.pattern, tt.seq, got, tt.want)
+        }
+    }
+}
+
+func TestValidate(t *testing.T) {
+    tests := []struct {
+        pattern string
+        policy  string
+        wantErr string
+    }{
+        {"INV/{YYYY}/{MM}/{SEQ:5}", ResetMonthly, ""},
+        {"INV/{YY}/{SEQ}", ResetYearly, ""},
+        {"INV-{SEQ:6}", ResetNever, ""},
+        {"INV{YYYY}-{SEQ:x}", ResetYearly, "unknown token {SEQ:x}"},
+        {"INV-{FOO}-{SEQ}", ResetNever, "unknown token {FOO}"},
+        {"INV-{yyyy}-{SEQ}", ResetNever, "unknown token {yyyy}"},
+        {"INV-{SEQ}-{SEQ:2}", ResetNever, "exactly one"},
+        {"INV-{YYYY}", ResetNever, "exactly one"},
+        {"INV-{SEQ", ResetNever, "exactly one"},
+        {"INV-{SEQ:99}", ResetNever, "width"},
+        {"INV-{SEQ}", ResetYearly, "yearly reset"},
+        {"INV-{YYYY}-{SEQ}", ResetMonthly, "monthly reset"},
+        {"INV-{SEQ}", "weekly", "reset policy"},
+        {strings.Repeat("X", 45) + "{SEQ}", ResetNever, "beyond"},
+    }
+    for _, tt := range tests {
+        err := Validate(tt.pattern, tt.policy)
+        switch {
+        case tt.wantErr == "" && err != nil:
+            t.Errorf("Validate(%q, %q) = %v, want nil", tt.pattern, tt.policy, err)
+        case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+            t.Errorf("Validate(%q, %q) = %v, want error containing %q", tt.pattern, tt.policy, err, tt.wantErr)
+        }
+    }
+}
+
+func TestPeriod(t *testing.T) {
+    at := time.Date(2026, 11, 30, 23, 0, 0, 0, time.UTC)
+    tests := map[string]string{
+        ResetNever:   "",
+        ResetYearly:  "2026",
+        ResetMonthly: "2026-11",
+    }
+    for policy, want := range tests {
+        if got := Period(policy, at); got != want {
+            t.Errorf("Period(%q) = %q, want %q", policy, got, want)
+        }
+    }
+}
\ No newline at end of file
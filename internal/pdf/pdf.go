@@ -0,0 +1,230 @@
+// Package pdf writes simple PDF documents of text, lines and boxes using
+// the standard Helvetica fonts, so no fonts or external tools are needed.
+//
+// Coordinates are in points from the top-left corner of the page, with y
+// growing downwards.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size in points.
+const (
+    A4Width  = 595.28
+    A4Height = 841.89
+)
+
+type Font int
+
+const (
+    Regular Font = iota
+    Bold
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold"}
+
+type Document struct {
+    Width  float64
+    Height float64
+    Title  string
+    pages  []*Page
+}
+
+type Page struct {
+    doc     *Document
+    content bytes.Buffer
+}
+
+func New() *Document {
+    return &Document{Width: A4Width, Height: A4Height}
+}
+
+func (d *Document) AddPage() *Page {
+    p := &Page{doc: d}
+    d.pages = append(d.pages, p)
+    return p
+}
+
+func (d *Document) Pages() []*Page {
+    return d.pages
+}
+
+// Text draws s with its baseline starting at x, y.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+    fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+        font+1, size, x, p.doc.Height-y, escape(encode(s)))
+}
+
+// TextRight draws s so that it ends at x.
+func (p *Page) TextRight(x, y float64, font Font, size float64, s string) {
+    p.Text(x-TextWidth(font, size, s), y, font, size, s)
+}
+
+// TextCenter draws s centred on x.
+func (p *Page) TextCenter(x, y float64, font Font, size float64, s string) {
+    p.Text(x-TextWidth(font, size, s)/2, y, font, size, s)
+}
+
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+    fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n",
+        width, x1, p.doc.Height-y1, x2, p.doc.Height-y2)
+}
+
+// FillRect fills a box with a grey level between 0 (black) and 1 (white).
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+    fmt.Fprintf(&p.content, "q %.3f g %.2f %.2f %.2f %.2f re f Q\n",
+        gray, x, p.doc.Height-y-h, w, h)
+}
+
+// TextWidth returns the width of s in points.
+func TextWidth(font Font, size float64, s string) float64 {
+    widths := helveticaWidths
+    if font == Bold {
+        widths = helveticaBoldWidths
+    }
+
+    var total int
+    for _, c := range encode(s) {
+        if c >= 32 && int(c-32) < len(widths) {
+            total += widths[c-32]
+        } else {
+            total += 556
+        }
+    }
+    return float64(total) * size / 1000
+}
+
+// Wrap breaks s into lines no wider than width, keeping explicit line
+// breaks. Words longer than a line are broken between characters.
+func Wrap(font Font, size float64, s string, width float64) []string {
+    var lines []string
+    for _, paragraph := range strings.Split(s, "\n") {
+        line := ""
+        for _, word := range strings.Fields(paragraph) {
+            candidate := word
+            if line != "" {
+                candidate = line + " " + word
+            }
+            if TextWidth(font, size, candidate) <= width {
+                line = candidate
+                continue
+            }
+            if line != "" {
+                lines = append(lines, line)
+            }
+            pieces := breakWord(font, size, word, width)
+            lines = append(lines, pieces[:len(pieces)-1]...)
+            line = pieces[len(pieces)-1]
+        }
+        lines = append(lines, line)
+    }
+    return lines
+}
+
+// breakWord cuts word into pieces no wider than width, each holding at
+// least one character.
+func breakWord(font Font, size float64, word string, width float64) []string {
+    var pieces []string
+    piece := ""
+    for _, r := range word {
+        if piece != "" && TextWidth(font, size, piece+string(r)) > width {
+            pieces = append(pieces, piece)
+            piece = ""
+        }
+        piece += string(r)
+    }
+    return append(pieces, piece)
+}
+
+// WriteTo writes the document in PDF 1.4 format.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+    if len(d.pages) == 0 {
+        d.AddPage()
+    }
+
+    var buf bytes.Buffer
+    var offsets []int
+    object := func(body string) {
+        offsets = append(offsets, buf.Len())
+        fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+    }
+
+    buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+    // Objects 1-4 are the catalog, page tree, fonts; each page then takes
+    // a page object followed by its content stream.
+    const firstPage = 5
+    kids := make([]string, len(d.pages))
+    for i := range d.pages {
+        kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+    }
+
+    object("<< /Type /Catalog /Pages 2 0 R >>")
+    object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+    for _, name := range fontNames {
+        object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+    }
+
+    for i, p := range d.pages {
+        object(fmt.Sprintf(
+            "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+            d.Width, d.Height, firstPage+2*i+1))
+
+        var stream bytes.Buffer
+        zw := zlib.NewWriter(&stream)
+        zw.Write(p.content.Bytes())
+        zw.Close()
+        object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()))
+    }
+
+    info := len(offsets) + 1
+    object(fmt.Sprintf("<< /Producer (invoice-system) /Title (%s) >>", escape(encode(d.Title))))
+
+    xref := buf.Len()
+    fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+    for _, off := range offsets {
+        fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+    }
+    fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+        len(offsets)+1, info, xref)
+
+    return buf.WriteTo(w)
+}
+
+// encode converts s to WinAnsi, which matches Latin-1 for the characters
+// it shares with it. Anything else becomes a question mark.
+func encode(s string) []byte {
+    out := make([]byte, 0, len(s))
+    for _, r := range s {
+        switch {
+        case r == '\t':
+            out = append(out, ' ')
+        case r >= 32 && r < 127, r >= 160 && r < 256:
+            out = append(out, byte(r))
+        case r == '€':
+            out = append(out, 0x80)
+        default:
+            out = append(out, '?')
+        }
+    }
+    return out
+}
+
+func escape(b []byte) string {
+    var sb strings.Builder
+    for _, c := range b {
+        switch c {
+        case '\\', '(', ')':
+            sb.WriteByte('\\')
+            sb.WriteByte(c)
+        default:
+            sb.WriteByte(c)
+        }
+    }
+    return sb.String()
+}
\ No newline at end of file
@@ -0,0 +1,177 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+    tests := []struct {
+        name  string
+        in    string
+        width float64
+        want  []string
+    }{
+        {"empty", "", 100, []string{""}},
+        {"fits", "Consulting services", 200, []string{"Consulting services"}},
+        {"breaks between words", "Consulting services for October", 80, []string{"Consulting services", "for October"}},
+        {"keeps line breaks", "Jl. Sudirman 1\n\nJakarta", 200, []string{"Jl. Sudirman 1", "", "Jakarta"}},
+        {"collapses spaces", "  a   b  ", 200, []string{"a b"}},
+        {"breaks long words", "SKU-ABCDEFGHIJKLMNOP", 40, []string{"SKU-ABC", "DEFGHIJK", "LMNOP"}},
+        {"long word after a short one", "Ref ABCDEFGHIJKLMNOP end", 40, []string{"Ref", "ABCDEFG", "HIJKLMN", "OP end"}},
+        {"narrower than one character", "WW", 1, []string{"W", "W"}},
+    }
+    for _, tt := range tests {
+        got := Wrap(Regular, 8, tt.in, tt.width)
+        if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+            t.Errorf("%s: Wrap(%q, %v) = %q, want %q", tt.name, tt.in, tt.width, got, tt.want)
+        }
+    }
+}
+
+func TestWrapWidth(t *testing.T) {
+    s := "https://example.com/orders/2024/10/PO-0000000000000000000000042 delivered to the warehouse"
+    for _, width := range []float64{30, 60, 120, 200} {
+        var joined string
+        for _, line := range Wrap(Regular, 8, s, width) {
+            if w := TextWidth(Regular, 8, line); w > width {
+                t.Errorf("Wrap at %v: %q is %v wide", width, line, w)
+            }
+            joined += line
+        }
+        if want := strings.ReplaceAll(s, " ", ""); strings.ReplaceAll(joined, " ", "") != want {
+            t.Errorf("Wrap at %v lost characters: %q", width, joined)
+        }
+    }
+}
+
+func TestTextWidth(t *testing.T) {
+    tests := []struct {
+        font Font
+        size float64
+        s    string
+        want float64
+    }{
+        {Regular, 10, "", 0},
+        {Regular, 10, "A", 6.67},
+        {Bold, 10, "A", 7.22},
+        {Regular, 12, "ii", 5.328},
+        // Characters outside the metrics table count as a digit
+        {Regular, 10, "€", 5.56},
+    }
+    for _, tt := range tests {
+        if got := TextWidth(tt.font, tt.size, tt.s); got < tt.want-1e-9 || got > tt.want+1e-9 {
+            t.Errorf("TextWidth(%d, %v, %q) = %v, want %v", tt.font, tt.size, tt.s, got, tt.want)
+        }
+    }
+}
+
+func TestTextEscaping(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+    }{
+        {"Total (net)", `(Total \(net\)) Tj`},
+        {`C:\invoices`, `(C:\\invoices) Tj`},
+        {`\)(`, `(\\\)\() Tj`},
+        {"Café\tLatte", "(Caf\xe9 Latte) Tj"},
+        {"€ 10", "(\x80 10) Tj"},
+        {"東京", "(??) Tj"},
+    }
+    for _, tt := range tests {
+        p := New().AddPage()
+        p.Text(10, 10, Regular, 8, tt.in)
+        if got := p.content.String(); !strings.Contains(got, tt.want) {
+            t.Errorf("Text(%q) = %q, want it to contain %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+// pageContents returns the inflated content stream of every page of a
+// PDF written by WriteTo.
+func pageContents(t *testing.T, doc []byte) []string {
+    t.Helper()
+    var contents []string
+    header := regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+    for _, m := range header.FindAllSubmatchIndex(doc, -1) {
+        n, _ := strconv.Atoi(string(doc[m[2]:m[3]]))
+        zr, err := zlib.NewReader(bytes.NewReader(doc[m[1] : m[1]+n]))
+        if err != nil {
+            t.Fatal(err)
+        }
+        content, err := io.ReadAll(zr)
+        if err != nil {
+            t.Fatal(err)
+        }
+        contents = append(contents, string(content))
+    }
+    return contents
+}
+
+func TestWriteTo(t *testing.T) {
+    doc := New()
+    doc.Title = "Invoice (draft)"
+    for _, s := range []string{"first", "second", "third"} {
+        doc.AddPage().Text(50, 50, Bold, 10, s)
+    }
+    var buf bytes.Buffer
+    if _, err := doc.WriteTo(&buf); err != nil {
+        t.Fatal(err)
+    }
+    out := buf.Bytes()
+
+    if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+        t.Error("missing PDF header or trailer")
+    }
+    if !bytes.Contains(out, []byte("/Kids [5 0 R 7 0 R 9 0 R] /Count 3")) {
+        t.Error("page tree does not list three pages")
+    }
+    if !bytes.Contains(out, []byte(`/Title (Invoice \(draft\))`)) {
+        t.Error("title is not escaped")
+    }
+
+    contents := pageContents(t, out)
+    if len(contents) != 3 {
+        t.Fatalf("found %d content streams, want 3", len(contents))
+    }
+    for i, want := range []string{"first", "second", "third"} {
+        if !strings.Contains(contents[i], "("+want+") Tj") {
+            t.Errorf("page %d content = %q, want %q", i+1, contents[i], want)
+        }
+    }
+
+    // Every xref entry points at the start of its object
+    m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+    if m == nil {
+        t.Fatal("no startxref")
+    }
+    xref, _ := strconv.Atoi(string(m[1]))
+    if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+        t.Fatalf("startxref %d does not point at the xref table", xref)
+    }
+    entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(out[xref:], -1)
+    if len(entries) != 11 {
+        t.Errorf("xref has %d objects, want 11", len(entries))
+    }
+    for i, e := range entries {
+        off, _ := strconv.Atoi(string(e[1]))
+        if want := strconv.Itoa(i+1) + " 0 obj\n"; !bytes.HasPrefix(out[off:], []byte(want)) {
+            t.Errorf("xref entry %d points at %q", i+1, out[off:off+10])
+        }
+    }
+}
+
+func TestWriteToEmpty(t *testing.T) {
+    var buf bytes.Buffer
+    if _, err := New().WriteTo(&buf); err != nil {
+        t.Fatal(err)
+    }
+    if !bytes.Contains(buf.Bytes(), []byte("/Count 1")) {
+        t.Error("an empty document should still have one page")
+    }
+}
\ No newline at end of file
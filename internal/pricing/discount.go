@@ -0,0 +1,79 @@
+package pricing
+
+import (
+	"errors"
+
+	"invoice-system/internal/money"
+)
+
+const (
+    DiscountPercent = "percent"
+    DiscountAmount  = "amount"
+)
+
+// Discount is either a percentage of an amount or a fixed amount taken off
+// it. The zero value is no discount.
+type Discount struct {
+    Type  string
+    Value money.Rate
+}
+
+func (d Discount) Validate() error {
+    switch d.Type {
+    case "":
+        if d.Value != 0 {
+            return errors.New("discount value requires a discount type")
+        }
+    case DiscountPercent:
+        if d.Value < 0 || d.Value > 100*money.RateScale {
+            return errors.New("percentage discount must be between 0 and 100")
+        }
+    case DiscountAmount:
+        if d.Value < 0 {
+            return errors.New("discount amount must not be negative")
+        }
+    default:
+        return errors.New("discount type must be percent or amount")
+    }
+    return nil
+}
+
+// Of returns how much is taken off amount, never more than amount itself.
+func (d Discount) Of(amount money.Amount) money.Amount {
+    var off money.Amount
+    switch d.Type {
+    case DiscountPercent:
+        off = amount.Percent(d.Value)
+    case DiscountAmount:
+        off = d.Value.Amount()
+    }
+    return money.Max(0, money.Min(off, amount))
+}
+
+// allocate spreads total over amounts pro rata. The last non-zero amount
+// absorbs the rounding difference so the shares always add up to total.
+func allocate(total money.Amount, amounts []money.Amount) []money.Amount {
+    shares := make([]money.Amount, len(amounts))
+    var base money.Amount
+    last := -1
+    for i, a := range amounts {
+        base += a
+        if a > 0 {
+            last = i
+        }
+    }
+    if total == 0 || base == 0 {
+        return shares
+    }
+
+    var allocated money.Amount
+    for i, a := range amounts {
+        if i == last {
+            shares[i] = total - allocated
+            break
+        }
+        shares[i] = total.Share(a, base)
+        allocated += shares[i]
+    }
+    return shares
+}
@@ -0,0 +1,167 @@
+package pricing
+
+import (
+	"testing"
+
+	"invoice-system/internal/money"
+)
+
+var (
+    vat     = Rate{ID: 1, Name: "VAT", Percent: 110000}
+    service = Rate{ID: 2, Name: "Service", Percent: 100000}
+    luxury  = Rate{ID: 3, Name: "Luxury", Percent: 50000, Compound: true}
+)
+
+func TestCalculate(t *testing.T) {
+    tests := []struct {
+        name      string
+        lines     []Line
+        inclusive bool
+        discount  Discount
+        mode      money.RoundingMode
+        wantNets  []money.Amount
+        wantTaxes []money.Amount
+        subtotal  money.Amount
+        taxTotal  money.Amount
+        total     money.Amount
+    }{
+        {
+            name:      "exclusive tax",
+            lines:     []Line{{UnitPrice: 1000, Quantity: 3, Rates: []Rate{vat}}},
+            wantNets:  []money.Amount{3000},
+            wantTaxes: []money.Amount{330},
+            subtotal:  3000, taxTotal: 330, total: 3330,
+        },
+        {
+            name: "fixed invoice discount keeps its remainder on the last line",
+            lines: []Line{
+                {UnitPrice: 3333, Quantity: 1},
+                {UnitPrice: 3333, Quantity: 1},
+                {UnitPrice: 3333, Quantity: 1},
+            },
+            discount:  Discount{Type: DiscountAmount, Value: 100000},
+            wantNets:  []money.Amount{3000, 3000, 2999},
+            wantTaxes: []money.Amount{0, 0, 0},
+            subtotal:  8999, total: 8999,
+        },
+        {
+            name:      "line discount comes off before the invoice discount",
+            lines:     []Line{{UnitPrice: 10000, Quantity: 1, Discount: Discount{Type: DiscountPercent, Value: 500000}}},
+            discount:  Discount{Type: DiscountPercent, Value: 100000},
+            wantNets:  []money.Amount{4500},
+            wantTaxes: []money.Amount{0},
+            subtotal:  4500, total: 4500,
+        },
+        {
+            name:      "inclusive prices keep the gross and round the net",
+            lines:     []Line{{UnitPrice: 10000, Quantity: 1, Rates: []Rate{vat}}},
+            inclusive: true,
+            wantNets:  []money.Amount{9009},
+            wantTaxes: []money.Amount{991},
+            subtotal:  9009, taxTotal: 991, total: 10000,
+        },
+        {
+            name:      "compound rates are charged after simple ones",
+            lines:     []Line{{UnitPrice: 10000, Quantity: 1, Rates: []Rate{luxury, service}}},
+            wantNets:  []money.Amount{10000},
+            wantTaxes: []money.Amount{1550},
+            subtotal:  10000, taxTotal: 1550, total: 11550,
+        },
+        {
+            name:      "half up tax",
+            lines:     []Line{{UnitPrice: 1005, Quantity: 1, Rates: []Rate{service}}},
+            mode:      money.HalfUp,
+            wantNets:  []money.Amount{1005},
+            wantTaxes: []money.Amount{101},
+            subtotal:  1005, taxTotal: 101, total: 1106,
+        },
+        {
+            name:      "half even tax",
+            lines:     []Line{{UnitPrice: 1005, Quantity: 1, Rates: []Rate{service}}},
+            mode:      money.HalfEven,
+            wantNets:  []money.Amount{1005},
+            wantTaxes: []money.Amount{100},
+            subtotal:  1005, taxTotal: 100, total: 1105,
+        },
+        {
+            name:      "truncated tax",
+            lines:     []Line{{UnitPrice: 1009, Quantity: 1, Rates: []Rate{service}}},
+            mode:      money.Down,
+            wantNets:  []money.Amount{1009},
+            wantTaxes: []money.Amount{100},
+            subtotal:  1009, taxTotal: 100, total: 1109,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            saved := money.Rounding
+            money.Rounding = tt.mode
+            defer func() { money.Rounding = saved }()
+
+            res := Calculate(tt.lines, tt.inclusive, tt.discount)
+            for i, lr := range res.Lines {
+                if lr.Net != tt.wantNets[i] || lr.Tax != tt.wantTaxes[i] {
+                    t.Errorf("line %d: net %s tax %s, want net %s tax %s", i, lr.Net, lr.Tax, tt.wantNets[i], tt.wantTaxes[i])
+                }
+                if lr.Gross != lr.Net+lr.Tax {
+                    t.Errorf("line %d: gross %s is not net plus tax", i, lr.Gross)
+                }
+            }
+            if res.Subtotal != tt.subtotal || res.TaxTotal != tt.taxTotal || res.Total != tt.total {
+                t.Errorf("totals %s + %s = %s, want %s + %s = %s",
+                    res.Subtotal, res.TaxTotal, res.Total, tt.subtotal, tt.taxTotal, tt.total)
+            }
+        })
+    }
+}
+
+func TestCalculateTaxSummary(t *testing.T) {
+    res := Calculate([]Line{
+        {UnitPrice: 1000, Quantity: 1, Rates: []Rate{vat}},
+        {UnitPrice: 2000, Quantity: 1, Rates: []Rate{service}},
+        {UnitPrice: 3000, Quantity: 1, Rates: []Rate{vat}},
+    }, false, Discount{})
+
+    want := []TaxAmount{
+        {Rate: vat, Taxable: 4000, Amount: 440},
+        {Rate: service, Taxable: 2000, Amount: 200},
+    }
+    if len(res.Taxes) != len(want) {
+        t.Fatalf("got %d tax rates, want %d", len(res.Taxes), len(want))
+    }
+    for i := range want {
+        if res.Taxes[i] != want[i] {
+            t.Errorf("tax %d = %+v, want %+v", i, res.Taxes[i], want[i])
+        }
+    }
+}
+
+func TestDiscount(t *testing.T) {
+    tests := []struct {
+        discount Discount
+        amount   money.Amount
+        want     money.Amount
+        wantErr  bool
+    }{
+        {discount: Discount{}, amount: 1000, want: 0},
+        {discount: Discount{Type: DiscountPercent, Value: 125000}, amount: 1000, want: 125},
+        {discount: Discount{Type: DiscountAmount, Value: 50000}, amount: 1000, want: 500},
+        {discount: Discount{Type: DiscountAmount, Value: 500000}, amount: 1000, want: 1000},
+        {discount: Discount{Value: 10000}, wantErr: true},
+        {discount: Discount{Type: DiscountPercent, Value: 1010000}, wantErr: true},
+        {discount: Discount{Type: DiscountAmount, Value: -1}, wantErr: true},
+        {discount: Discount{Type: "free"}, wantErr: true},
+    }
+    for _, tt := range tests {
+        err := tt.discount.Validate()
+        if (err != nil) != tt.wantErr {
+            t.Errorf("%+v.Validate() = %v, want error %v", tt.discount, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr {
+            if got := tt.discount.Of(tt.amount); got != tt.want {
+                t.Errorf("%+v.Of(%s) = %s, want %s", tt.discount, tt.amount, got, tt.want)
+            }
+        }
+    }
+}
\ No newline at end of file
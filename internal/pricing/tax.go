@@ -0,0 +1,167 @@
+package pricing
+
+import (
+	"math/big"
+
+	"invoice-system/internal/money"
+)
+
+// Rate is a tax rate applied to a line. Simple rates are charged on the net
+// amount; compound rates are charged on the net amount plus every tax
+// computed before them.
+type Rate struct {
+    ID       int
+    Name     string
+    Percent  money.Rate
+    Compound bool
+}
+
+type Line struct {
+    UnitPrice money.Amount
+    Quantity  int
+    Discount  Discount
+    Rates     []Rate
+}
+
+type TaxAmount struct {
+    Rate
+    Taxable money.Amount
+    Amount  money.Amount
+}
+
+type LineResult struct {
+    // Amount is unit price times quantity, before any discount.
+    Amount          money.Amount
+    LineDiscount    money.Amount
+    InvoiceDiscount money.Amount
+    Net             money.Amount
+    Tax             money.Amount
+    Gross           money.Amount
+    Taxes           []TaxAmount
+}
+
+type Result struct {
+    Lines             []LineResult
+    LineDiscountTotal money.Amount
+    InvoiceDiscount   money.Amount
+    // Subtotal is the net amount after discounts and before tax.
+    Subtotal money.Amount
+    TaxTotal money.Amount
+    Total    money.Amount
+    // Taxes holds one entry per rate, in order of first use.
+    Taxes []TaxAmount
+}
+
+// Calculate prices every line and totals the invoice. Line discounts come
+// off first, then the invoice discount is spread over the discounted lines
+// pro rata, and tax is charged on what remains. When inclusive is set unit
+// prices already contain tax and the net amount is backed out of them.
+// Rounding happens per line and tax, so totals are exact sums of what is
+// shown on the lines.
+func Calculate(lines []Line, inclusive bool, discount Discount) Result {
+    var res Result
+    byRate := map[int]int{}
+
+    discounted := make([]money.Amount, len(lines))
+    amounts := make([]money.Amount, len(lines))
+    lineDiscounts := make([]money.Amount, len(lines))
+    var base money.Amount
+    for i, l := range lines {
+        amounts[i] = l.UnitPrice.Mul(l.Quantity)
+        lineDiscounts[i] = l.Discount.Of(amounts[i])
+        discounted[i] = amounts[i] - lineDiscounts[i]
+        res.LineDiscountTotal += lineDiscounts[i]
+        base += discounted[i]
+    }
+
+    res.InvoiceDiscount = discount.Of(base)
+    shares := allocate(res.InvoiceDiscount, discounted)
+
+    for i, l := range lines {
+        lr := priceLine(discounted[i]-shares[i], l.Rates, inclusive)
+        lr.Amount = amounts[i]
+        lr.LineDiscount = lineDiscounts[i]
+        lr.InvoiceDiscount = shares[i]
+        res.Lines = append(res.Lines, lr)
+        res.Subtotal += lr.Net
+        res.TaxTotal += lr.Tax
+
+        for _, t := range lr.Taxes {
+            j, ok := byRate[t.ID]
+            if !ok {
+                j = len(res.Taxes)
+                byRate[t.ID] = j
+                res.Taxes = append(res.Taxes, TaxAmount{Rate: t.Rate})
+            }
+            res.Taxes[j].Taxable += t.Taxable
+            res.Taxes[j].Amount += t.Amount
+        }
+    }
+
+    res.Total = res.Subtotal + res.TaxTotal
+    return res
+}
+
+func priceLine(amount money.Amount, rates []Rate, inclusive bool) LineResult {
+    ordered := orderRates(rates)
+
+    net := amount
+    if inclusive {
+        net = amount.MulRat(new(big.Rat).Inv(multiplier(ordered)))
+    }
+
+    var lr LineResult
+    var running money.Amount
+    for _, r := range ordered {
+        taxable := net
+        if r.Compound {
+            taxable = net + running
+        }
+        tax := taxable.Percent(r.Percent)
+        running += tax
+        lr.Taxes = append(lr.Taxes, TaxAmount{Rate: r, Taxable: taxable, Amount: tax})
+    }
+
+    lr.Tax = running
+    if inclusive {
+        // The gross price is fixed, so rounding differences land on the net
+        lr.Gross = amount
+        lr.Net = amount - lr.Tax
+    } else {
+        lr.Net = net
+        lr.Gross = net + lr.Tax
+    }
+    return lr
+}
+
+// orderRates puts simple rates before compound ones, keeping their order.
+func orderRates(rates []Rate) []Rate {
+    ordered := make([]Rate, 0, len(rates))
+    for _, r := range rates {
+        if !r.Compound {
+            ordered = append(ordered, r)
+        }
+    }
+    for _, r := range rates {
+        if r.Compound {
+            ordered = append(ordered, r)
+        }
+    }
+    return ordered
+}
+
+// multiplier is gross/net for the given rates.
+func multiplier(rates []Rate) *big.Rat {
+    hundred := big.NewRat(100, 1)
+    simple := big.NewRat(1, 1)
+    compound := big.NewRat(1, 1)
+    for _, r := range rates {
+        pct := new(big.Rat).Quo(r.Percent.Rat(), hundred)
+        if r.Compound {
+            compound.Mul(compound, pct.Add(pct, big.NewRat(1, 1)))
+        } else {
+            simple.Add(simple, pct)
+        }
+    }
+    return simple.Mul(simple, compound)
+}
@@ -0,0 +1,89 @@
+// Package recurring computes the run dates of recurring invoice schedules.
+package recurring
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+    UnitWeek  = "week"
+    UnitMonth = "month"
+    UnitYear  = "year"
+)
+
+// LastDay as DayOfMonth runs on the last day of every month.
+const LastDay = -1
+
+// Schedule repeats every Every units from Start until End. Monthly and
+// yearly schedules run on DayOfMonth, or on the day of Start when it is
+// zero; days past the end of a short month fall on its last day.
+type Schedule struct {
+    Unit       string
+    Every      int
+    DayOfMonth int
+    Start      time.Time
+    End        *time.Time
+}
+
+func (s Schedule) Validate() error {
+    switch s.Unit {
+    case UnitWeek, UnitMonth, UnitYear:
+    default:
+        return errors.New("interval_unit must be week, month or year")
+    }
+    if s.Every < 1 {
+        return errors.New("interval_count must be at least 1")
+    }
+    if s.DayOfMonth < LastDay || s.DayOfMonth > 31 {
+        return errors.New("day_of_month must be between 1 and 31, 0 for the start day or -1 for the last day")
+    }
+    if s.End != nil && s.End.Before(s.Start) {
+        return errors.New("end_date is before start_date")
+    }
+    return nil
+}
+
+// Occurrence returns the n-th run date counting from the period of Start.
+// Computing every date from Start keeps month-end dates from drifting.
+func (s Schedule) Occurrence(n int) time.Time {
+    if s.Unit == UnitWeek {
+        return s.Start.AddDate(0, 0, 7*s.Every*n)
+    }
+
+    months := s.Every * n
+    if s.Unit == UnitYear {
+        months *= 12
+    }
+    first := time.Date(s.Start.Year(), s.Start.Month()+time.Month(months), 1, 0, 0, 0, 0, s.Start.Location())
+    last := first.AddDate(0, 1, -1).Day()
+
+    day := s.DayOfMonth
+    if day == 0 {
+        day = s.Start.Day()
+    }
+    if day == LastDay || day > last {
+        day = last
+    }
+    return first.AddDate(0, 0, day-1)
+}
+
+// Next returns the first run date after the given day. It reports false
+// once the schedule has ended.
+func (s Schedule) Next(after time.Time) (time.Time, bool) {
+    for n := 0; ; n++ {
+        t := s.Occurrence(n)
+        if s.End != nil && t.After(*s.End) {
+            return time.Time{}, false
+        }
+        if t.Before(s.Start) || !t.After(after) {
+            continue
+        }
+        return t, true
+    }
+}
+
+// First returns the first run date on or after Start.
+func (s Schedule) First() (time.Time, bool) {
+    return s.Next(s.Start.AddDate(0, 0, -1))
+}
\ No newline at end of file
@@ -0,0 +1,93 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+    t, err := time.Parse("2006-01-02", s)
+    if err != nil {
+        panic(err)
+    }
+    return t
+}
+
+func TestOccurrences(t *testing.T) {
+    tests := []struct {
+        name     string
+        schedule Schedule
+        want     []string
+    }{
+        {
+            name:     "month end does not drift",
+            schedule: Schedule{Unit: UnitMonth, Every: 1, Start: date("2026-01-31")},
+            want:     []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"},
+        },
+        {
+            name:     "last day",
+            schedule: Schedule{Unit: UnitMonth, Every: 1, DayOfMonth: LastDay, Start: date("2028-01-05")},
+            want:     []string{"2028-01-31", "2028-02-29", "2028-03-31"},
+        },
+        {
+            name:     "every two weeks",
+            schedule: Schedule{Unit: UnitWeek, Every: 2, Start: date("2026-12-21")},
+            want:     []string{"2026-12-21", "2027-01-04", "2027-01-18"},
+        },
+        {
+            name:     "quarterly on a fixed day",
+            schedule: Schedule{Unit: UnitMonth, Every: 3, DayOfMonth: 15, Start: date("2026-11-01")},
+            want:     []string{"2026-11-15", "2027-02-15", "2027-05-15"},
+        },
+        {
+            name:     "leap day yearly",
+            schedule: Schedule{Unit: UnitYear, Every: 1, Start: date("2028-02-29")},
+            want:     []string{"2028-02-29", "2029-02-28", "2030-02-28", "2031-02-28", "2032-02-29"},
+        },
+    }
+    for _, tt := range tests {
+        for n, want := range tt.want {
+            if got := tt.schedule.Occurrence(n).Format("2006-01-02"); got != want {
+                t.Errorf("%s: occurrence %d = %s, want %s", tt.name, n, got, want)
+            }
+        }
+    }
+}
+
+func TestNext(t *testing.T) {
+    end := date("2026-03-31")
+    s := Schedule{Unit: UnitMonth, Every: 1, DayOfMonth: 10, Start: date("2026-01-15"), End: &end}
+
+    first, ok := s.First()
+    if !ok || first != date("2026-02-10") {
+        t.Errorf("First() = %s, %v, want 2026-02-10", first.Format("2006-01-02"), ok)
+    }
+    next, ok := s.Next(first)
+    if !ok || next != date("2026-03-10") {
+        t.Errorf("Next(2026-02-10) = %s, %v, want 2026-03-10", next.Format("2006-01-02"), ok)
+    }
+    if next, ok := s.Next(date("2026-03-10")); ok {
+        t.Errorf("Next after the end date = %s, want none", next.Format("2006-01-02"))
+    }
+}
+
+func TestValidate(t *testing.T) {
+    end := date("2025-12-31")
+    tests := []struct {
+        schedule Schedule
+        wantErr  bool
+    }{
+        {Schedule{Unit: UnitWeek, Every: 1, Start: date("2026-01-01")}, false},
+        {Schedule{Unit: UnitMonth, Every: 1, DayOfMonth: LastDay, Start: date("2026-01-01")}, false},
+        {Schedule{Unit: "day", Every: 1, Start: date("2026-01-01")}, true},
+        {Schedule{Unit: UnitMonth, Every: 0, Start: date("2026-01-01")}, true},
+        {Schedule{Unit: UnitMonth, Every: 1, DayOfMonth: 32, Start: date("2026-01-01")}, true},
+        {Schedule{Unit: UnitMonth, Every: 1, DayOfMonth: -2, Start: date("2026-01-01")}, true},
+        {Schedule{Unit: UnitMonth, Every: 1, Start: date("2026-01-01"), End: &end}, true},
+    }
+    for _, tt := range tests {
+        if err := tt.schedule.Validate(); (err != nil) != tt.wantErr {
+            t.Errorf("%+v.Validate() = %v, want error %v", tt.schedule, err, tt.wantErr)
+        }
+    }
+}
\ No newline at end of file
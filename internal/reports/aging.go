@@ -0,0 +1,204 @@
+// Package reports computes the financial reports served under
+// /api/reports.
+package reports
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"invoice-system/internal/fx"
+	"invoice-system/internal/money"
+)
+
+type queryer interface {
+    Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// DefaultAgingBoundaries gives the buckets current, 1-30, 31-60, 61-90 and
+// 90+.
+var DefaultAgingBoundaries = []int{30, 60, 90}
+
+// ParseAgingBoundaries reads ascending day counts such as "30,60,90".
+func ParseAgingBoundaries(s string) ([]int, error) {
+    if s == "" {
+        return DefaultAgingBoundaries, nil
+    }
+    parts := strings.Split(s, ",")
+    if len(parts) > 12 {
+        return nil, errors.New("at most 12 bucket boundaries are allowed")
+    }
+    boundaries := make([]int, len(parts))
+    for i, p := range parts {
+        n, err := strconv.Atoi(strings.TrimSpace(p))
+        if err != nil || n < 1 {
+            return nil, fmt.Errorf("invalid bucket boundary %q", p)
+        }
+        if i > 0 && n <= boundaries[i-1] {
+            return nil, errors.New("bucket boundaries must be ascending")
+        }
+        boundaries[i] = n
+    }
+    return boundaries, nil
+}
+
+// AgingLabels names the buckets made by boundaries: current, then one per
+// boundary, then everything beyond the last one.
+func AgingLabels(boundaries []int) []string {
+    labels := []string{"current"}
+    from := 1
+    for _, b := range boundaries {
+        labels = append(labels, fmt.Sprintf("%d-%d", from, b))
+        from = b + 1
+    }
+    return append(labels, fmt.Sprintf("%d+", from-1))
+}
+
+// agingBucket returns the index of the bucket for an invoice daysPastDue
+// days past its due date.
+func agingBucket(boundaries []int, daysPastDue int) int {
+    if daysPastDue <= 0 {
+        return 0
+    }
+    for i, b := range boundaries {
+        if daysPastDue <= b {
+            return i + 1
+        }
+    }
+    return len(boundaries) + 1
+}
+
+type BucketAmount struct {
+    Bucket string       `json:"bucket"`
+    Amount money.Amount `json:"amount"`
+}
+
+// AgingInvoice is one invoice with a balance on the as-of date. Balance is
+// in the invoice currency and BaseBalance in the base currency at the rate
+// the invoice was booked at.
+type AgingInvoice struct {
+    InvoiceID     int                `json:"invoice_id"`
+    InvoiceNumber string             `json:"invoice_number"`
+    DueDate       string             `json:"due_date"`
+    DaysPastDue   int                `json:"days_past_due"`
+    Bucket        string             `json:"bucket"`
+    Currency      string             `json:"currency"`
+    ExchangeRate  money.ExchangeRate `json:"exchange_rate"`
+    Balance       money.Amount       `json:"balance"`
+    BaseBalance   money.Amount       `json:"base_balance"`
+}
+
+type AgingCustomer struct {
+    CustomerID int            `json:"customer_id"`
+    Name       string         `json:"name"`
+    Buckets    []BucketAmount `json:"buckets"`
+    Total      money.Amount   `json:"total"`
+    Invoices   []AgingInvoice `json:"invoices"`
+}
+
+// AgingReport buckets what customers owed on AsOf by how far past due it
+// was. All amounts except the invoice balances are in BaseCurrency.
+type AgingReport struct {
+    AsOf         string          `json:"as_of"`
+    BaseCurrency string          `json:"base_currency"`
+    Customers    []AgingCustomer `json:"customers"`
+    Totals       []BucketAmount  `json:"totals"`
+    Total        money.Amount    `json:"total"`
+}
+
+func newBuckets(labels []string) []BucketAmount {
+    buckets := make([]BucketAmount, len(labels))
+    for i, l := range labels {
+        buckets[i].Bucket = l
+    }
+    return buckets
+}
+
+// ARAging computes the balance of every issued invoice as it stood on asOf
+// (YYYY-MM-DD): payments and credit notes dated later are not deducted.
+func ARAging(q queryer, asOf string, boundaries []int) (AgingReport, error) {
+    labels := AgingLabels(boundaries)
+    report := AgingReport{
+        AsOf:         asOf,
+        BaseCurrency: fx.BaseCurrency,
+        Customers:    []AgingCustomer{},
+        Totals:       newBuckets(labels),
+    }
+
+    rows, err := q.Query(`
+        SELECT c.id, c.name, i.id, i.invoice_number, DATE_FORMAT(i.due_date, '%Y-%m-%d'),
+            DATEDIFF(?, i.due_date), i.currency, i.exchange_rate,
+            i.total_amount
+                - COALESCE((SELECT SUM(p.amount) FROM payments p
+                    WHERE p.invoice_id = i.id AND p.paid_at <= ?), 0)
+                - COALESCE((SELECT SUM(cn.total_amount) FROM credit_notes cn
+                    WHERE cn.invoice_id = i.id AND cn.issue_date <= ?), 0)
+        FROM invoices i
+        JOIN customers c ON c.id = i.customer_id
+        WHERE i.status NOT IN ('draft', 'void') AND i.issue_date <= ?
+        ORDER BY c.name, c.id, i.due_date, i.id
+    `, asOf, asOf, asOf, asOf)
+    if err != nil {
+        return report, err
+    }
+    defer rows.Close()
+
+    var current *AgingCustomer
+    for rows.Next() {
+        var customerID int
+        var name string
+        var inv AgingInvoice
+        err := rows.Scan(&customerID, &name, &inv.InvoiceID, &inv.InvoiceNumber, &inv.DueDate,
+            &inv.DaysPastDue, &inv.Currency, &inv.ExchangeRate, &inv.Balance)
+        if err != nil {
+            return report, err
+        }
+        if inv.Balance == 0 {
+            continue
+        }
+
+        if current == nil || current.CustomerID != customerID {
+            report.Customers = append(report.Customers, AgingCustomer{
+                CustomerID: customerID,
+                Name:       name,
+                Buckets:    newBuckets(labels),
+            })
+            current = &report.Customers[len(report.Customers)-1]
+        }
+
+        b := agingBucket(boundaries, inv.DaysPastDue)
+        inv.Bucket = labels[b]
+        inv.BaseBalance = inv.Balance.ToBase(inv.ExchangeRate)
+        current.Invoices = append(current.Invoices, inv)
+        current.Buckets[b].Amount += inv.BaseBalance
+        current.Total += inv.BaseBalance
+        report.Totals[b].Amount += inv.BaseBalance
+        report.Total += inv.BaseBalance
+    }
+    return report, rows.Err()
+}
+
+// CSV flattens the report into one row per customer and a totals row.
+func (r AgingReport) CSV() [][]string {
+    header := []string{"customer_id", "customer"}
+    for _, b := range r.Totals {
+        header = append(header, b.Bucket)
+    }
+    records := [][]string{append(header, "total")}
+
+    for _, c := range r.Customers {
+        record := []string{strconv.Itoa(c.CustomerID), c.Name}
+        for _, b := range c.Buckets {
+            record = append(record, b.Amount.String())
+        }
+        records = append(records, append(record, c.Total.String()))
+    }
+
+    record := []string{"", "Total"}
+    for _, b := range r.Totals {
+        record = append(record, b.Amount.String())
+    }
+    return append(records, append(record, r.Total.String()))
+}
\ No newline at end of file
@@ -0,0 +1,77 @@
+package reports
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAgingBoundaries(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    []int
+        wantErr bool
+    }{
+        {in: "", want: DefaultAgingBoundaries},
+        {in: "30,60,90", want: []int{30, 60, 90}},
+        {in: " 15, 45 ", want: []int{15, 45}},
+        {in: "7", want: []int{7}},
+        {in: "1,2,3,4,5,6,7,8,9,10,11,12", want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+        {in: "1,2,3,4,5,6,7,8,9,10,11,12,13", wantErr: true},
+        {in: "60,30", wantErr: true},
+        {in: "30,30", wantErr: true},
+        {in: "0,30", wantErr: true},
+        {in: "-30", wantErr: true},
+        {in: "30,,60", wantErr: true},
+        {in: "thirty", wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := ParseAgingBoundaries(tt.in)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("ParseAgingBoundaries(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("ParseAgingBoundaries(%q) = %v, want %v", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestAgingLabels(t *testing.T) {
+    tests := []struct {
+        boundaries []int
+        want       []string
+    }{
+        {[]int{30, 60, 90}, []string{"current", "1-30", "31-60", "61-90", "90+"}},
+        {[]int{15}, []string{"current", "1-15", "15+"}},
+        {[]int{1, 7}, []string{"current", "1-1", "2-7", "7+"}},
+    }
+    for _, tt := range tests {
+        if got := AgingLabels(tt.boundaries); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("AgingLabels(%v) = %v, want %v", tt.boundaries, got, tt.want)
+        }
+    }
+}
+
+func TestAgingBucket(t *testing.T) {
+    boundaries := []int{30, 60, 90}
+    labels := AgingLabels(boundaries)
+    tests := []struct {
+        daysPastDue int
+        want        string
+    }{
+        {-5, "current"},
+        {0, "current"},
+        {1, "1-30"},
+        {30, "1-30"},
+        {31, "31-60"},
+        {60, "31-60"},
+        {90, "61-90"},
+        {91, "90+"},
+        {400, "90+"},
+    }
+    for _, tt := range tests {
+        if got := labels[agingBucket(boundaries, tt.daysPastDue)]; got != tt.want {
+            t.Errorf("agingBucket(%v, %d) = %s, want %s", boundaries, tt.daysPastDue, got, tt.want)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,472 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"invoice-system/internal/fx"
+	"invoice-system/internal/money"
+)
+
+// Revenue bases: issued recognises invoices and credit notes on their issue
+// date, cash recognises payments and refunds on the day they were made.
+const (
+    BasisIssued = "issued"
+    BasisCash   = "cash"
+)
+
+// Revenue groupings
+const (
+    GroupDay      = "day"
+    GroupWeek     = "week"
+    GroupMonth    = "month"
+    GroupQuarter  = "quarter"
+    GroupCustomer = "customer"
+    GroupItem     = "item"
+)
+
+// maxPeriods bounds the rows of a report grouped by time.
+const maxPeriods = 1000
+
+var ErrTooManyPeriods = errors.New("date range has too many periods")
+
+func ValidBasis(s string) bool {
+    return s == BasisIssued || s == BasisCash
+}
+
+func ValidGroup(s string) bool {
+    switch s {
+    case GroupDay, GroupWeek, GroupMonth, GroupQuarter, GroupCustomer, GroupItem:
+        return true
+    }
+    return false
+}
+
+// RevenueAmounts are in the base currency. Net excludes tax and Gross
+// includes it; Quantity is only counted on the issued basis.
+type RevenueAmounts struct {
+    Quantity int          `json:"quantity"`
+    Net      money.Amount `json:"net"`
+    Tax      money.Amount `json:"tax"`
+    Gross    money.Amount `json:"gross"`
+}
+
+func (a *RevenueAmounts) add(b RevenueAmounts) {
+    a.Quantity += b.Quantity
+    a.Net += b.Net
+    a.Tax += b.Tax
+    a.Gross += b.Gross
+}
+
+// RevenueRow is one period, customer or item. Previous, Change and
+// ChangePercent compare its net revenue with the previous period: the same
+// customer or item, or the period in the same position.
+type RevenueRow struct {
+    Key   string `json:"key"`
+    Label string `json:"label"`
+    Start string `json:"start,omitempty"`
+    End   string `json:"end,omitempty"`
+    RevenueAmounts
+    Previous      *RevenueAmounts `json:"previous,omitempty"`
+    Change        *money.Amount   `json:"change,omitempty"`
+    ChangePercent *float64        `json:"change_percent,omitempty"`
+}
+
+type RevenueComparison struct {
+    StartDate     string         `json:"start_date"`
+    EndDate       string         `json:"end_date"`
+    Totals        RevenueAmounts `json:"totals"`
+    Change        money.Amount   `json:"change"`
+    ChangePercent *float64       `json:"change_percent"`
+}
+
+type RevenueReport struct {
+    Basis        string             `json:"basis"`
+    GroupBy      string             `json:"group_by"`
+    StartDate    string             `json:"start_date"`
+    EndDate      string             `json:"end_date"`
+    BaseCurrency string             `json:"base_currency"`
+    Rows         []RevenueRow       `json:"rows"`
+    Totals       RevenueAmounts     `json:"totals"`
+    Comparison   *RevenueComparison `json:"comparison,omitempty"`
+}
+
+// revenueLine is revenue recognised on one invoice line.
+type revenueLine struct {
+    date         time.Time
+    customerID   int
+    customerName string
+    itemID       int
+    itemName     string
+    amounts      RevenueAmounts
+}
+
+// PreviousPeriod returns the period just before start to end. Ranges of
+// whole months step back by the same number of months, so October is
+// compared with September; other ranges step back by their length in days.
+func PreviousPeriod(start, end time.Time) (time.Time, time.Time) {
+    if start.Day() == 1 && end.AddDate(0, 0, 1).Day() == 1 {
+        months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+        return start.AddDate(0, -months, 0), start.AddDate(0, 0, -1)
+    }
+    days := int(end.Sub(start).Hours()/24) + 1
+    return start.AddDate(0, 0, -days), start.AddDate(0, 0, -1)
+}
+
+// Revenue reports what was recognised between start and end, both
+// inclusive, grouped by period, customer or item, optionally compared with
+// the previous period.
+func Revenue(q queryer, basis, groupBy string, start, end time.Time, compare bool) (RevenueReport, error) {
+    report := RevenueReport{
+        Basis:        basis,
+        GroupBy:      groupBy,
+        StartDate:    start.Format("2006-01-02"),
+        EndDate:      end.Format("2006-01-02"),
+        BaseCurrency: fx.BaseCurrency,
+    }
+
+    rows, totals, err := revenueRows(q, basis, groupBy, start, end)
+    if err != nil {
+        return report, err
+    }
+    report.Rows = rows
+    report.Totals = totals
+    if !compare {
+        return report, nil
+    }
+
+    prevStart, prevEnd := PreviousPeriod(start, end)
+    prevRows, prevTotals, err := revenueRows(q, basis, groupBy, prevStart, prevEnd)
+    if err != nil {
+        return report, err
+    }
+    report.Comparison = &RevenueComparison{
+        StartDate:     prevStart.Format("2006-01-02"),
+        EndDate:       prevEnd.Format("2006-01-02"),
+        Totals:        prevTotals,
+        Change:        totals.Net - prevTotals.Net,
+        ChangePercent: changePercent(totals.Net, prevTotals.Net),
+    }
+
+    previous := map[string]RevenueAmounts{}
+    for i, r := range prevRows {
+        previous[comparisonKey(groupBy, i, r)] = r.RevenueAmounts
+    }
+    for i := range report.Rows {
+        row := &report.Rows[i]
+        prev, ok := previous[comparisonKey(groupBy, i, *row)]
+        if !ok {
+            continue
+        }
+        change := row.Net - prev.Net
+        row.Previous = &prev
+        row.Change = &change
+        row.ChangePercent = changePercent(row.Net, prev.Net)
+    }
+    return report, nil
+}
+
+// comparisonKey matches customers and items by id and periods by position.
+func comparisonKey(groupBy string, i int, r RevenueRow) string {
+    if groupBy == GroupCustomer || groupBy == GroupItem {
+        return r.Key
+    }
+    return strconv.Itoa(i)
+}
+
+func changePercent(current, previous money.Amount) *float64 {
+    if previous == 0 {
+        return nil
+    }
+    p := math.Round(float64(current-previous)/math.Abs(float64(previous))*10000) / 100
+    return &p
+}
+
+func revenueRows(q queryer, basis, groupBy string, start, end time.Time) ([]RevenueRow, RevenueAmounts, error) {
+    var totals RevenueAmounts
+
+    var lines []revenueLine
+    var err error
+    if basis == BasisCash {
+        lines, err = cashRevenue(q, start, end)
+    } else {
+        lines, err = issuedRevenue(q, start, end)
+    }
+    if err != nil {
+        return nil, totals, err
+    }
+
+    var rows []RevenueRow
+    index := map[string]int{}
+    if groupBy != GroupCustomer && groupBy != GroupItem {
+        // Every period is listed, including those without revenue
+        rows, err = periods(groupBy, start, end)
+        if err != nil {
+            return nil, totals, err
+        }
+        for i, r := range rows {
+            index[r.Key] = i
+        }
+    }
+
+    for _, l := range lines {
+        var key, label string
+        switch groupBy {
+        case GroupCustomer:
+            key, label = strconv.Itoa(l.customerID), l.customerName
+        case GroupItem:
+            key, label = strconv.Itoa(l.itemID), l.itemName
+        default:
+            key, _, _ = period(groupBy, l.date)
+        }
+        i, ok := index[key]
+        if !ok {
+            index[key] = len(rows)
+            i = len(rows)
+            rows = append(rows, RevenueRow{Key: key, Label: label})
+        }
+        rows[i].add(l.amounts)
+        totals.add(l.amounts)
+    }
+
+    if groupBy == GroupCustomer || groupBy == GroupItem {
+        sort.SliceStable(rows, func(i, j int) bool { return rows[i].Net > rows[j].Net })
+    }
+    if rows == nil {
+        rows = []RevenueRow{}
+    }
+    return rows, totals, nil
+}
+
+// period returns the key and bounds of the period containing t. Weeks are
+// ISO weeks starting on Monday.
+func period(groupBy string, t time.Time) (string, time.Time, time.Time) {
+    switch groupBy {
+    case GroupWeek:
+        start := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+        year, week := t.ISOWeek()
+        return fmt.Sprintf("%d-W%02d", year, week), start, start.AddDate(0, 0, 6)
+    case GroupMonth:
+        start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+        return t.Format("2006-01"), start, start.AddDate(0, 1, -1)
+    case GroupQuarter:
+        q := (int(t.Month()) - 1) / 3
+        start := time.Date(t.Year(), time.Month(q*3+1), 1, 0, 0, 0, 0, time.UTC)
+        return fmt.Sprintf("%d-Q%d", t.Year(), q+1), start, start.AddDate(0, 3, -1)
+    }
+    return t.Format("2006-01-02"), t, t
+}
+
+// periods lists the periods overlapping start to end, cut to the range.
+func periods(groupBy string, start, end time.Time) ([]RevenueRow, error) {
+    var rows []RevenueRow
+    for t := start; !t.After(end); {
+        key, ps, pe := period(groupBy, t)
+        if len(rows) == maxPeriods {
+            return nil, fmt.Errorf("%w: more than %d %ss", ErrTooManyPeriods, maxPeriods, groupBy)
+        }
+        next := pe.AddDate(0, 0, 1)
+        if ps.Before(start) {
+            ps = start
+        }
+        if pe.After(end) {
+            pe = end
+        }
+        rows = append(rows, RevenueRow{
+            Key:   key,
+            Label: key,
+            Start: ps.Format("2006-01-02"),
+            End:   pe.Format("2006-01-02"),
+        })
+        t = next
+    }
+    return rows, nil
+}
+
+// issuedRevenue recognises invoice lines on the invoice issue date and
+// credited quantities, as negative revenue, on the credit note date. Both
+// are converted at the rate the invoice was booked at.
+func issuedRevenue(q queryer, start, end time.Time) ([]revenueLine, error) {
+    from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+    var lines []revenueLine
+
+    rows, err := q.Query(`
+        SELECT DATE_FORMAT(i.issue_date, '%Y-%m-%d'), i.customer_id, c.name, ii.item_id, it.name,
+            i.exchange_rate, ii.quantity, ii.net_amount, ii.tax_amount, ii.line_total
+        FROM invoice_items ii
+        JOIN invoices i ON i.id = ii.invoice_id
+        JOIN customers c ON c.id = i.customer_id
+        JOIN items it ON it.id = ii.item_id
+        WHERE i.status NOT IN ('draft', 'void') AND i.issue_date BETWEEN ? AND ?
+    `, from, to)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var l revenueLine
+        var date string
+        var rate money.ExchangeRate
+        var net, tax, gross money.Amount
+        err := rows.Scan(&date, &l.customerID, &l.customerName, &l.itemID, &l.itemName,
+            &rate, &l.amounts.Quantity, &net, &tax, &gross)
+        if err != nil {
+            rows.Close()
+            return nil, err
+        }
+        l.date, _ = time.Parse("2006-01-02", date)
+        l.amounts.Net = net.ToBase(rate)
+        l.amounts.Gross = gross.ToBase(rate)
+        l.amounts.Tax = l.amounts.Gross - l.amounts.Net
+        lines = append(lines, l)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    rows, err = q.Query(`
+        SELECT DATE_FORMAT(cn.issue_date, '%Y-%m-%d'), i.customer_id, c.name, ii.item_id, it.name,
+            i.exchange_rate, cni.quantity, ii.quantity, ii.net_amount, cni.amount
+        FROM credit_note_items cni
+        JOIN credit_notes cn ON cn.id = cni.credit_note_id
+        JOIN invoice_items ii ON ii.id = cni.invoice_item_id
+        JOIN invoices i ON i.id = cn.invoice_id
+        JOIN customers c ON c.id = i.customer_id
+        JOIN items it ON it.id = ii.item_id
+        WHERE cn.issue_date BETWEEN ? AND ?
+    `, from, to)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var l revenueLine
+        var date string
+        var rate money.ExchangeRate
+        var credited, invoiced int
+        var lineNet, amount money.Amount
+        err := rows.Scan(&date, &l.customerID, &l.customerName, &l.itemID, &l.itemName,
+            &rate, &credited, &invoiced, &lineNet, &amount)
+        if err != nil {
+            return nil, err
+        }
+        l.date, _ = time.Parse("2006-01-02", date)
+        net := lineNet.Share(money.Amount(credited), money.Amount(invoiced))
+        l.amounts.Quantity = -credited
+        l.amounts.Net = -net.ToBase(rate)
+        l.amounts.Gross = -amount.ToBase(rate)
+        l.amounts.Tax = l.amounts.Gross - l.amounts.Net
+        lines = append(lines, l)
+    }
+    return lines, rows.Err()
+}
+
+// cashRevenue recognises payments, and refunds as negative payments, on the
+// day they were made at the rate they were made at. Each payment is spread
+// over the invoice lines in proportion to their totals; the last line takes
+// the rounding difference so the lines add up to the payment.
+func cashRevenue(q queryer, start, end time.Time) ([]revenueLine, error) {
+    rows, err := q.Query(`
+        SELECT p.id, DATE_FORMAT(p.paid_at, '%Y-%m-%d'), i.customer_id, c.name, ii.item_id, it.name,
+            p.exchange_rate, p.amount, i.total_amount, ii.net_amount, ii.line_total
+        FROM payments p
+        JOIN invoices i ON i.id = p.invoice_id
+        JOIN invoice_items ii ON ii.invoice_id = i.id
+        JOIN customers c ON c.id = i.customer_id
+        JOIN items it ON it.id = ii.item_id
+        WHERE p.paid_at BETWEEN ? AND ? AND i.total_amount <> 0
+        ORDER BY p.id, ii.id
+    `, start.Format("2006-01-02"), end.Format("2006-01-02"))
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    type share struct {
+        line      revenueLine
+        rate      money.ExchangeRate
+        payment   money.Amount
+        total     money.Amount
+        lineNet   money.Amount
+        lineTotal money.Amount
+    }
+    var shares []share
+    var paymentIDs []int
+    for rows.Next() {
+        var s share
+        var paymentID int
+        var date string
+        err := rows.Scan(&paymentID, &date, &s.line.customerID, &s.line.customerName, &s.line.itemID,
+            &s.line.itemName, &s.rate, &s.payment, &s.total, &s.lineNet, &s.lineTotal)
+        if err != nil {
+            return nil, err
+        }
+        s.line.date, _ = time.Parse("2006-01-02", date)
+        shares = append(shares, s)
+        paymentIDs = append(paymentIDs, paymentID)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    lines := make([]revenueLine, len(shares))
+    var allocated money.Amount
+    for i, s := range shares {
+        gross := s.payment.Share(s.lineTotal, s.total)
+        last := i == len(shares)-1 || paymentIDs[i+1] != paymentIDs[i]
+        if last {
+            gross = s.payment - allocated
+            allocated = 0
+        } else {
+            allocated += gross
+        }
+
+        net := gross.Share(s.lineNet, s.lineTotal)
+        l := s.line
+        l.amounts.Net = net.ToBase(s.rate)
+        l.amounts.Gross = gross.ToBase(s.rate)
+        l.amounts.Tax = l.amounts.Gross - l.amounts.Net
+        lines[i] = l
+    }
+    return lines, nil
+}
+
+// CSV flattens the report into one row per period, customer or item.
+func (r RevenueReport) CSV() [][]string {
+    header := []string{"key", "label", "quantity", "net", "tax", "gross"}
+    if r.Comparison != nil {
+        header = append(header, "previous_net", "change", "change_percent")
+    }
+    records := [][]string{header}
+
+    for _, row := range r.Rows {
+        record := []string{row.Key, row.Label, strconv.Itoa(row.Quantity),
+            row.Net.String(), row.Tax.String(), row.Gross.String()}
+        if r.Comparison != nil {
+            record = append(record, "", "", "")
+            if row.Previous != nil {
+                record[6] = row.Previous.Net.String()
+                record[7] = row.Change.String()
+            }
+            if row.ChangePercent != nil {
+                record[8] = strconv.FormatFloat(*row.ChangePercent, 'f', 2, 64)
+            }
+        }
+        records = append(records, record)
+    }
+
+    total := []string{"", "Total", strconv.Itoa(r.Totals.Quantity),
+        r.Totals.Net.String(), r.Totals.Tax.String(), r.Totals.Gross.String()}
+    if c := r.Comparison; c != nil {
+        percent := ""
+        if c.ChangePercent != nil {
+            percent = strconv.FormatFloat(*c.ChangePercent, 'f', 2, 64)
+        }
+        total = append(total, c.Totals.Net.String(), c.Change.String(), percent)
+    }
+    return append(records, total)
+}
\ No newline at end of file
@@ -0,0 +1,219 @@
+package reports
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"invoice-system/internal/money"
+)
+
+func day(s string) time.Time {
+    t, err := time.Parse("2006-01-02", s)
+    if err != nil {
+        panic(err)
+    }
+    return t
+}
+
+func TestPreviousPeriod(t *testing.T) {
+    tests := []struct {
+        name       string
+        start, end string
+        wantStart  string
+        wantEnd    string
+    }{
+        {"month", "2024-10-01", "2024-10-31", "2024-09-01", "2024-09-30"},
+        {"month after a shorter one", "2024-03-01", "2024-03-31", "2024-02-01", "2024-02-29"},
+        {"leap february", "2024-02-01", "2024-02-29", "2024-01-01", "2024-01-31"},
+        {"quarter", "2024-04-01", "2024-06-30", "2024-01-01", "2024-03-31"},
+        {"year", "2024-01-01", "2024-12-31", "2023-01-01", "2023-12-31"},
+        {"days across a year", "2024-01-10", "2024-01-19", "2023-12-31", "2024-01-09"},
+        {"month-long but not whole months", "2024-01-15", "2024-02-14", "2023-12-15", "2024-01-14"},
+        {"single day", "2024-03-01", "2024-03-01", "2024-02-29", "2024-02-29"},
+    }
+    for _, tt := range tests {
+        start, end := PreviousPeriod(day(tt.start), day(tt.end))
+        if got, want := start.Format("2006-01-02")+" "+end.Format("2006-01-02"), tt.wantStart+" "+tt.wantEnd; got != want {
+            t.Errorf("%s: PreviousPeriod(%s, %s) = %s, want %s", tt.name, tt.start, tt.end, got, want)
+        }
+    }
+}
+
+func TestPeriod(t *testing.T) {
+    tests := []struct {
+        groupBy string
+        date    string
+        key     string
+        start   string
+        end     string
+    }{
+        {GroupDay, "2024-02-29", "2024-02-29", "2024-02-29", "2024-02-29"},
+        {GroupWeek, "2024-12-30", "2025-W01", "2024-12-30", "2025-01-05"},
+        {GroupWeek, "2025-01-05", "2025-W01", "2024-12-30", "2025-01-05"},
+        {GroupWeek, "2021-01-03", "2020-W53", "2020-12-28", "2021-01-03"},
+        {GroupWeek, "2024-07-17", "2024-W29", "2024-07-15", "2024-07-21"},
+        {GroupMonth, "2024-02-10", "2024-02", "2024-02-01", "2024-02-29"},
+        {GroupQuarter, "2024-03-31", "2024-Q1", "2024-01-01", "2024-03-31"},
+        {GroupQuarter, "2024-04-01", "2024-Q2", "2024-04-01", "2024-06-30"},
+        {GroupQuarter, "2024-12-31", "2024-Q4", "2024-10-01", "2024-12-31"},
+    }
+    for _, tt := range tests {
+        key, start, end := period(tt.groupBy, day(tt.date))
+        got := key + " " + start.Format("2006-01-02") + " " + end.Format("2006-01-02")
+        if want := tt.key + " " + tt.start + " " + tt.end; got != want {
+            t.Errorf("period(%s, %s) = %s, want %s", tt.groupBy, tt.date, got, want)
+        }
+    }
+}
+
+func TestPeriods(t *testing.T) {
+    tests := []struct {
+        groupBy    string
+        start, end string
+        want       []string
+    }{
+        {GroupWeek, "2024-12-25", "2025-01-08", []string{
+            "2024-W52 2024-12-25 2024-12-29",
+            "2025-W01 2024-12-30 2025-01-05",
+            "2025-W02 2025-01-06 2025-01-08",
+        }},
+        {GroupQuarter, "2024-02-15", "2024-07-10", []string{
+            "2024-Q1 2024-02-15 2024-03-31",
+            "2024-Q2 2024-04-01 2024-06-30",
+            "2024-Q3 2024-07-01 2024-07-10",
+        }},
+        {GroupMonth, "2024-01-31", "2024-02-01", []string{
+            "2024-01 2024-01-31 2024-01-31",
+            "2024-02 2024-02-01 2024-02-01",
+        }},
+        {GroupDay, "2024-02-28", "2024-03-01", []string{
+            "2024-02-28 2024-02-28 2024-02-28",
+            "2024-02-29 2024-02-29 2024-02-29",
+            "2024-03-01 2024-03-01 2024-03-01",
+        }},
+    }
+    for _, tt := range tests {
+        rows, err := periods(tt.groupBy, day(tt.start), day(tt.end))
+        if err != nil {
+            t.Errorf("periods(%s, %s, %s) error = %v", tt.groupBy, tt.start, tt.end, err)
+            continue
+        }
+        var got []string
+        for _, r := range rows {
+            got = append(got, r.Key+" "+r.Start+" "+r.End)
+        }
+        if len(got) != len(tt.want) {
+            t.Errorf("periods(%s, %s, %s) = %v, want %v", tt.groupBy, tt.start, tt.end, got, tt.want)
+            continue
+        }
+        for i := range got {
+            if got[i] != tt.want[i] {
+                t.Errorf("periods(%s, %s, %s)[%d] = %s, want %s", tt.groupBy, tt.start, tt.end, i, got[i], tt.want[i])
+            }
+        }
+    }
+
+    _, err := periods(GroupDay, day("2020-01-01"), day("2023-01-01"))
+    if !errors.Is(err, ErrTooManyPeriods) {
+        t.Errorf("periods over three years by day: error = %v, want ErrTooManyPeriods", err)
+    }
+}
+
+// cannedDriver answers every query with the rows registered under the data
+// source name.
+type cannedDriver map[string][][]driver.Value
+
+func (d cannedDriver) Open(name string) (driver.Conn, error) { return cannedConn(d[name]), nil }
+
+type cannedConn [][]driver.Value
+
+func (c cannedConn) Prepare(string) (driver.Stmt, error) { return cannedStmt(c), nil }
+func (c cannedConn) Close() error                        { return nil }
+func (c cannedConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type cannedStmt [][]driver.Value
+
+func (s cannedStmt) Close() error  { return nil }
+func (s cannedStmt) NumInput() int { return -1 }
+func (s cannedStmt) Exec([]driver.Value) (driver.Result, error) {
+    return nil, errors.New("not supported")
+}
+func (s cannedStmt) Query([]driver.Value) (driver.Rows, error) {
+    return &cannedRows{rows: s}, nil
+}
+
+type cannedRows struct{ rows [][]driver.Value }
+
+func (r *cannedRows) Columns() []string {
+    if len(r.rows) == 0 {
+        return nil
+    }
+    return make([]string, len(r.rows[0]))
+}
+func (r *cannedRows) Close() error { return nil }
+func (r *cannedRows) Next(dest []driver.Value) error {
+    if len(r.rows) == 0 {
+        return io.EOF
+    }
+    copy(dest, r.rows[0])
+    r.rows = r.rows[1:]
+    return nil
+}
+
+// payment is one row of the cashRevenue query: a payment joined with one
+// line of its invoice.
+func payment(id int64, date string, item int64, rate, amount, total, lineNet, lineTotal string) []driver.Value {
+    return []driver.Value{id, date, int64(1), "PT Contoh", item, "Item", rate, amount, total, lineNet, lineTotal}
+}
+
+func TestCashRevenue(t *testing.T) {
+    sql.Register("reportstest", cannedDriver{
+        "payments": {
+            // 100.00 paid on an invoice of three equal lines of 100.00
+            payment(1, "2024-05-02", 1, "1.00000000", "100.00", "300.00", "100.00", "100.00"),
+            payment(1, "2024-05-02", 2, "1.00000000", "100.00", "300.00", "100.00", "100.00"),
+            payment(1, "2024-05-02", 3, "1.00000000", "100.00", "300.00", "100.00", "100.00"),
+            // 55.50 USD on one taxed line of 111.00 with 11.00 tax
+            payment(2, "2024-05-03", 4, "15000.00000000", "55.50", "111.00", "100.00", "111.00"),
+            // A refund of 10.00 spread over two lines of 50.00 and 25.00
+            payment(3, "2024-05-04", 5, "1.00000000", "-10.00", "75.00", "50.00", "50.00"),
+            payment(3, "2024-05-04", 6, "1.00000000", "-10.00", "75.00", "25.00", "25.00"),
+        },
+    })
+    db, err := sql.Open("reportstest", "payments")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+
+    lines, err := cashRevenue(db, day("2024-05-01"), day("2024-05-31"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []struct {
+        item       int
+        net, gross money.Amount
+    }{
+        {1, 3333, 3333},
+        {2, 3333, 3333},
+        // The last line of a payment takes the rounding remainder
+        {3, 3334, 3334},
+        {4, 75000000, 83250000},
+        {5, -667, -667},
+        {6, -333, -333},
+    }
+    if len(lines) != len(want) {
+        t.Fatalf("cashRevenue returned %d lines, want %d", len(lines), len(want))
+    }
+    for i, w := range want {
+        l := lines[i]
+        if l.itemID != w.item || l.amounts.Net != w.net || l.amounts.Gross != w.gross || l.amounts.Tax != w.gross-w.net {
+            t.Errorf("line %d = item %d net %s tax %s gross %s, want item %d net %s gross %s",
+                i, l.itemID, l.amounts.Net, l.amounts.Tax, l.amounts.Gross, w.item, w.net, w.gross)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,62 @@
+// Package scheduler runs background jobs inside the API process.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is run with the current time on every tick. Runs of the same job
+// never overlap.
+type Job struct {
+    Name     string
+    Interval time.Duration
+    Run      func(now time.Time) error
+}
+
+type Scheduler struct {
+    jobs []Job
+}
+
+func New() *Scheduler {
+    return &Scheduler{}
+}
+
+func (s *Scheduler) Every(name string, interval time.Duration, run func(now time.Time) error) {
+    s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start runs every job once straight away and then on its interval until
+// ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+    for _, job := range s.jobs {
+        go loop(ctx, job)
+    }
+}
+
+func loop(ctx context.Context, job Job) {
+    ticker := time.NewTicker(job.Interval)
+    defer ticker.Stop()
+
+    for {
+        run(job)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func run(job Job) {
+    defer func() {
+        if p := recover(); p != nil {
+            log.Printf("Job %s panicked: %v", job.Name, p)
+        }
+    }()
+
+    if err := job.Run(time.Now()); err != nil {
+        log.Printf("Job %s failed: %v", job.Name, err)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Local keeps objects as files below Dir.
+type Local struct {
+    Dir string
+}
+
+func (l Local) path(key string) (string, error) {
+    if err := validKey(key); err != nil {
+        return "", err
+    }
+    return filepath.Join(l.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object to a temporary file first so readers never see a
+// partly written one.
+func (l Local) Put(key, contentType string, data []byte) error {
+    path, err := l.path(key)
+    if err != nil {
+        return err
+    }
+    err = os.MkdirAll(filepath.Dir(path), 0o750)
+    if err != nil {
+        return err
+    }
+
+    f, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    _, err = f.Write(data)
+    if closeErr := f.Close(); err == nil {
+        err = closeErr
+    }
+    if err == nil {
+        err = os.Rename(f.Name(), path)
+    }
+    if err != nil {
+        os.Remove(f.Name())
+    }
+    return err
+}
+
+func (l Local) Get(key string) (io.ReadCloser, error) {
+    path, err := l.path(key)
+    if err != nil {
+        return nil, err
+    }
+    f, err := os.Open(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil, ErrNotFound
+    } else if err != nil {
+        return nil, err
+    }
+    return f, nil
+}
+
+func (l Local) Delete(key string) error {
+    path, err := l.path(key)
+    if err != nil {
+        return err
+    }
+    err = os.Remove(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil
+    }
+    return err
+}
\ No newline at end of file
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+    dir := t.TempDir()
+    l := Local{Dir: dir}
+
+    err := l.Put("invoice/1/po.pdf", "application/pdf", []byte("%PDF-1.4"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    rc, err := l.Get("invoice/1/po.pdf")
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, _ := io.ReadAll(rc)
+    rc.Close()
+    if string(data) != "%PDF-1.4" {
+        t.Errorf("Get = %q", data)
+    }
+
+    // No temporary files are left next to the object
+    entries, _ := os.ReadDir(filepath.Join(dir, "invoice", "1"))
+    if len(entries) != 1 {
+        t.Errorf("directory holds %d entries, want 1", len(entries))
+    }
+
+    if err := l.Delete("invoice/1/po.pdf"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := l.Get("invoice/1/po.pdf"); err != ErrNotFound {
+        t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+    }
+    if err := l.Delete("invoice/1/po.pdf"); err != nil {
+        t.Errorf("second Delete = %v", err)
+    }
+}
+
+func TestValidKey(t *testing.T) {
+    tests := []struct {
+        key   string
+        valid bool
+    }{
+        {"invoice/1/0123abcd", true},
+        {"customer/7/PO 2026.pdf", true},
+        {"", false},
+        {"/etc/passwd", false},
+        {"invoice/../../etc/passwd", false},
+        {"invoice/./1", false},
+        {"invoice//1", false},
+        {"invoice/1/", false},
+        {`invoice\1`, false},
+    }
+    for _, tt := range tests {
+        if err := validKey(tt.key); (err == nil) != tt.valid {
+            t.Errorf("validKey(%q) = %v, want valid %v", tt.key, err, tt.valid)
+        }
+    }
+}
\ No newline at end of file
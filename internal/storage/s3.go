@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 keeps objects in a bucket of an S3-compatible service such as AWS S3
+// or MinIO. Objects are addressed path-style, endpoint/bucket/key, which
+// every implementation supports, and requests are signed with AWS
+// Signature Version 4.
+type S3 struct {
+    // Endpoint is the service URL, e.g. https://s3.eu-west-1.amazonaws.com
+    // or http://localhost:9000.
+    Endpoint        string
+    Region          string
+    Bucket          string
+    AccessKeyID     string
+    SecretAccessKey string
+    // SessionToken is only needed with temporary credentials.
+    SessionToken    string
+    Client          *http.Client
+}
+
+func (s *S3) Put(key, contentType string, data []byte) error {
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+    resp, err := s.do(http.MethodPut, key, data, map[string]string{"Content-Type": contentType})
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    return s3Error(resp)
+}
+
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+    resp, err := s.do(http.MethodGet, key, nil, nil)
+    if err != nil {
+        return nil, err
+    }
+    if resp.StatusCode == http.StatusNotFound {
+        resp.Body.Close()
+        return nil, ErrNotFound
+    }
+    if err := s3Error(resp); err != nil {
+        resp.Body.Close()
+        return nil, err
+    }
+    return resp.Body, nil
+}
+
+// Delete relies on S3 answering 204 for missing keys as well.
+func (s *S3) Delete(key string) error {
+    resp, err := s.do(http.MethodDelete, key, nil, nil)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode == http.StatusNotFound {
+        return nil
+    }
+    return s3Error(resp)
+}
+
+func (s *S3) do(method, key string, body []byte, headers map[string]string) (*http.Response, error) {
+    if err := validKey(key); err != nil {
+        return nil, err
+    }
+    u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+    if err != nil {
+        return nil, err
+    }
+    u.Path += "/" + s.Bucket + "/" + key
+    u.RawPath = uriEncode(u.Path)
+
+    req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    for name, value := range headers {
+        req.Header.Set(name, value)
+    }
+    s.sign(req, body, time.Now().UTC())
+
+    client := s.Client
+    if client == nil {
+        client = http.DefaultClient
+    }
+    return client.Do(req)
+}
+
+// sign adds the Signature Version 4 headers to req.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+    amzDate := now.Format("20060102T150405Z")
+    day := now.Format("20060102")
+    payloadHash := sha256Hex(body)
+
+    req.Header.Set("X-Amz-Date", amzDate)
+    req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+    if s.SessionToken != "" {
+        req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+    }
+
+    // Canonical headers: host plus every header set above, lower-cased
+    // and sorted
+    values := map[string]string{"host": req.URL.Host}
+    for name := range req.Header {
+        values[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+    }
+    names := make([]string, 0, len(values))
+    for name := range values {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    var canonicalHeaders strings.Builder
+    for _, name := range names {
+        canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        req.URL.EscapedPath(),
+        req.URL.RawQuery,
+        canonicalHeaders.String(),
+        signedHeaders,
+        payloadHash,
+    }, "\n")
+
+    scope := day + "/" + s.Region + "/s3/aws4_request"
+    stringToSign := strings.Join([]string{
+        "AWS4-HMAC-SHA256",
+        amzDate,
+        scope,
+        sha256Hex([]byte(canonicalRequest)),
+    }, "\n")
+
+    key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), day)
+    key = hmacSHA256(key, s.Region)
+    key = hmacSHA256(key, "s3")
+    key = hmacSHA256(key, "aws4_request")
+    signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+    req.Header.Set("Authorization", fmt.Sprintf(
+        "AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        s.AccessKeyID, scope, signedHeaders, signature,
+    ))
+}
+
+// s3Error turns an unsuccessful response into an error carrying the start
+// of the XML error document.
+func s3Error(resp *http.Response) error {
+    if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+        return nil
+    }
+    msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    return fmt.Errorf("s3: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// uriEncode escapes every byte of a path except the unreserved characters
+// and slashes, as Signature Version 4 expects of the canonical URI.
+func uriEncode(path string) string {
+    var b strings.Builder
+    for i := 0; i < len(path); i++ {
+        c := path[i]
+        if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+            c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+            b.WriteByte(c)
+        } else {
+            fmt.Fprintf(&b, "%%%02X", c)
+        }
+    }
+    return b.String()
+}
+
+func sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(data))
+    return mac.Sum(nil)
+}
\ No newline at end of file
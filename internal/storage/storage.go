@@ -0,0 +1,41 @@
+// Package storage keeps uploaded files in a local directory or an
+// S3-compatible bucket.
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+    ErrNotFound   = errors.New("object not found")
+    ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Storage stores objects under slash-separated keys. Local and S3 are the
+// implementations.
+type Storage interface {
+    Put(key, contentType string, data []byte) error
+    // Get returns ErrNotFound for a key that was never stored or has been
+    // deleted. The caller closes the reader.
+    Get(key string) (io.ReadCloser, error)
+    // Delete succeeds for keys that do not exist.
+    Delete(key string) error
+}
+
+// Default is the storage attachments are kept in, configured at start-up.
+var Default Storage
+
+// validKey rejects keys that could escape the storage root.
+func validKey(key string) error {
+    if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+        return ErrInvalidKey
+    }
+    for _, part := range strings.Split(key, "/") {
+        if part == "" || part == "." || part == ".." {
+            return ErrInvalidKey
+        }
+    }
+    return nil
+}
\ No newline at end of file
@@ -0,0 +1,228 @@
+// Package templates renders invoices as HTML with html/template layouts
+// kept in the database, in a directory or built into the binary.
+package templates
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"invoice-system/internal/models"
+	"invoice-system/internal/money"
+)
+
+// DefaultName is the layout used when a request does not choose one.
+const DefaultName = "default"
+
+const (
+    SourceDatabase  = "database"
+    SourceDirectory = "directory"
+    SourceBuiltin   = "builtin"
+)
+
+// Dir holds <name>.html layouts. It is set at start-up from TEMPLATE_DIR.
+var Dir string
+
+var ErrNotFound = errors.New("template not found")
+
+//go:embed default.html
+var builtin string
+
+//go:embed statement.html
+var statementLayout string
+
+var statementTemplate = template.Must(Parse("statement", statementLayout))
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+
+type queryer interface {
+    Query(query string, args ...interface{}) (*sql.Rows, error)
+    QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Data is what a layout is executed with.
+type Data struct {
+    Invoice  models.InvoiceDetail
+    Customer models.Customer
+    Company  models.CompanyProfile
+}
+
+type StatementData struct {
+    Statement models.Statement
+    Company   models.CompanyProfile
+}
+
+var funcs = template.FuncMap{
+    "money": func(a money.Amount) string { return a.Format() },
+    "add": func(amounts ...money.Amount) money.Amount {
+        var sum money.Amount
+        for _, a := range amounts {
+            sum += a
+        }
+        return sum
+    },
+    "rate": func(r money.Rate) string { return r.Format() },
+    "date": formatDate,
+    "lines": func(s string) []string {
+        if s == "" {
+            return nil
+        }
+        return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+    },
+    "label": func(s interface{}) string {
+        return strings.ReplaceAll(fmt.Sprint(s), "_", " ")
+    },
+}
+
+// formatDate prints a DATE column (YYYY-MM-DD, with or without the time the
+// driver adds) with an optional Go layout: {{date "02 Jan 2006" .DueDate}}.
+func formatDate(args ...string) (string, error) {
+    if len(args) == 0 || len(args) > 2 {
+        return "", errors.New("date takes a value and an optional layout")
+    }
+    value := args[len(args)-1]
+    if len(value) > 10 {
+        value = value[:10]
+    }
+    if len(args) == 1 {
+        return value, nil
+    }
+    t, err := time.Parse("2006-01-02", value)
+    if err != nil {
+        return value, nil
+    }
+    return t.Format(args[0]), nil
+}
+
+func ValidateName(name string) error {
+    if !namePattern.MatchString(name) {
+        return errors.New("name must be 1-50 lowercase letters, digits, '-' or '_'")
+    }
+    return nil
+}
+
+// Parse compiles a layout with the template functions.
+func Parse(name, body string) (*template.Template, error) {
+    return template.New(name).Funcs(funcs).Parse(body)
+}
+
+// ParseText compiles a plain text template, such as an email subject, with
+// the same functions.
+func ParseText(name, body string) (*texttemplate.Template, error) {
+    return texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs)).Parse(body)
+}
+
+// Load finds a layout in the database, then in Dir, then among the
+// built-in ones.
+func Load(q queryer, name string) (models.InvoiceTemplate, error) {
+    t := models.InvoiceTemplate{Name: name}
+    if err := ValidateName(name); err != nil {
+        return t, ErrNotFound
+    }
+
+    err := q.QueryRow(`
+        SELECT body, updated_at FROM invoice_templates WHERE name = ?
+    `, name).Scan(&t.Body, &t.UpdatedAt)
+    if err == nil {
+        t.Source = SourceDatabase
+        return t, nil
+    } else if err != sql.ErrNoRows {
+        return t, err
+    }
+
+    if Dir != "" {
+        path := filepath.Join(Dir, name+".html")
+        body, err := os.ReadFile(path)
+        if err == nil {
+            t.Source = SourceDirectory
+            t.Body = string(body)
+            if info, err := os.Stat(path); err == nil {
+                t.UpdatedAt = info.ModTime()
+            }
+            return t, nil
+        } else if !errors.Is(err, os.ErrNotExist) {
+            return t, err
+        }
+    }
+
+    if name == DefaultName {
+        t.Source = SourceBuiltin
+        t.Body = builtin
+        return t, nil
+    }
+    return t, ErrNotFound
+}
+
+// List returns every layout that Load can find, without bodies. A name in
+// the database hides the file or built-in layout of the same name.
+func List(q queryer) ([]models.InvoiceTemplate, error) {
+    found := map[string]models.InvoiceTemplate{
+        DefaultName: {Name: DefaultName, Source: SourceBuiltin},
+    }
+
+    if Dir != "" {
+        paths, err := filepath.Glob(filepath.Join(Dir, "*.html"))
+        if err != nil {
+            return nil, err
+        }
+        for _, path := range paths {
+            name := strings.TrimSuffix(filepath.Base(path), ".html")
+            if ValidateName(name) != nil {
+                continue
+            }
+            t := models.InvoiceTemplate{Name: name, Source: SourceDirectory}
+            if info, err := os.Stat(path); err == nil {
+                t.UpdatedAt = info.ModTime()
+            }
+            found[name] = t
+        }
+    }
+
+    rows, err := q.Query("SELECT name, updated_at FROM invoice_templates")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        t := models.InvoiceTemplate{Source: SourceDatabase}
+        if err := rows.Scan(&t.Name, &t.UpdatedAt); err != nil {
+            return nil, err
+        }
+        found[t.Name] = t
+    }
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    list := make([]models.InvoiceTemplate, 0, len(found))
+    for _, t := range found {
+        list = append(list, t)
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+    return list, nil
+}
+
+// Render executes a layout. Callers that write to a response should render
+// into a buffer first so a failing layout does not leave half a page.
+func Render(w io.Writer, t models.InvoiceTemplate, data Data) error {
+    tmpl, err := Parse(t.Name, t.Body)
+    if err != nil {
+        return err
+    }
+    return tmpl.Execute(w, data)
+}
+
+// RenderStatement prints a statement of account with the built-in layout.
+func RenderStatement(w io.Writer, data StatementData) error {
+    return statementTemplate.Execute(w, data)
+}
\ No newline at end of file
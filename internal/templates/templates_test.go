@@ -0,0 +1,230 @@
+package templates
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"invoice-system/internal/models"
+)
+
+// templateTable is a stand-in for the invoice_templates table, keyed by
+// name.
+type templateTable map[string]string
+
+func (t templateTable) Open(string) (driver.Conn, error) { return templateConn{t}, nil }
+
+type templateConn struct{ t templateTable }
+
+func (c templateConn) Prepare(string) (driver.Stmt, error) { return templateStmt{c.t}, nil }
+func (c templateConn) Close() error                        { return nil }
+func (c templateConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type templateStmt struct{ t templateTable }
+
+func (s templateStmt) Close() error  { return nil }
+func (s templateStmt) NumInput() int { return -1 }
+func (s templateStmt) Exec([]driver.Value) (driver.Result, error) {
+    return nil, errors.New("not supported")
+}
+
+// Query answers the body lookup of Load when given a name and the listing
+// of List otherwise.
+func (s templateStmt) Query(args []driver.Value) (driver.Rows, error) {
+    updated := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+    rows := &templateRows{}
+    if len(args) == 1 {
+        if body, ok := s.t[args[0].(string)]; ok {
+            rows.values = append(rows.values, []driver.Value{body, updated})
+        }
+        return rows, nil
+    }
+    for name := range s.t {
+        rows.values = append(rows.values, []driver.Value{name, updated})
+    }
+    return rows, nil
+}
+
+type templateRows struct{ values [][]driver.Value }
+
+func (r *templateRows) Columns() []string { return []string{"a", "b"} }
+func (r *templateRows) Close() error      { return nil }
+func (r *templateRows) Next(dest []driver.Value) error {
+    if len(r.values) == 0 {
+        return io.EOF
+    }
+    copy(dest, r.values[0])
+    r.values = r.values[1:]
+    return nil
+}
+
+func openTemplates(t *testing.T) *sql.DB {
+    sql.Register("templatestest", templateTable{
+        "stored":  "<p>stored</p>",
+        "default": "<p>stored default</p>",
+    })
+    db, err := sql.Open("templatestest", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { db.Close() })
+    return db
+}
+
+func TestLoad(t *testing.T) {
+    db := openTemplates(t)
+
+    dir := t.TempDir()
+    for name, body := range map[string]string{
+        "stored":  "<p>file hidden by the database</p>",
+        "branded": "<p>branded</p>",
+    } {
+        if err := os.WriteFile(filepath.Join(dir, name+".html"), []byte(body), 0o644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tests := []struct {
+        dir        string
+        name       string
+        wantSource string
+        wantBody   string
+        wantErr    error
+    }{
+        // The database comes first, then the directory, then the binary
+        {dir, "stored", SourceDatabase, "<p>stored</p>", nil},
+        {dir, "branded", SourceDirectory, "<p>branded</p>", nil},
+        {"", "branded", "", "", ErrNotFound},
+        {dir, "missing", "", "", ErrNotFound},
+        // Names that could leave the directory are never looked up
+        {dir, "../branded", "", "", ErrNotFound},
+        {dir, "Branded", "", "", ErrNotFound},
+    }
+    for _, tt := range tests {
+        Dir = tt.dir
+        got, err := Load(db, tt.name)
+        if err != tt.wantErr {
+            t.Errorf("Load(%q) with dir %q error = %v, want %v", tt.name, tt.dir, err, tt.wantErr)
+            continue
+        }
+        if err == nil && (got.Source != tt.wantSource || got.Body != tt.wantBody) {
+            t.Errorf("Load(%q) with dir %q = %s %q, want %s %q", tt.name, tt.dir, got.Source, got.Body, tt.wantSource, tt.wantBody)
+        }
+    }
+    Dir = ""
+}
+
+func TestLoadBuiltin(t *testing.T) {
+    sql.Register("templatestest-empty", templateTable{})
+    db, err := sql.Open("templatestest-empty", "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+
+    Dir = t.TempDir()
+    defer func() { Dir = "" }()
+    got, err := Load(db, DefaultName)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.Source != SourceBuiltin || got.Body != builtin {
+        t.Errorf("Load(%q) = %s, want the built-in layout", DefaultName, got.Source)
+    }
+}
+
+func TestValidateName(t *testing.T) {
+    tests := []struct {
+        name    string
+        wantErr bool
+    }{
+        {"default", false},
+        {"branded-2024_v2", false},
+        {strings.Repeat("a", 50), false},
+        {strings.Repeat("a", 51), true},
+        {"", true},
+        {"Branded", true},
+        {"../etc", true},
+        {"a/b", true},
+        {"a.html", true},
+        {"a b", true},
+    }
+    for _, tt := range tests {
+        if err := ValidateName(tt.name); (err != nil) != tt.wantErr {
+            t.Errorf("ValidateName(%q) = %v, want error %v", tt.name, err, tt.wantErr)
+        }
+    }
+}
+
+func builtinTemplate() models.InvoiceTemplate {
+    return models.InvoiceTemplate{Name: DefaultName, Source: SourceBuiltin, Body: builtin}
+}
+
+func TestRenderEscapesCustomer(t *testing.T) {
+    var data Data
+    data.Invoice.InvoiceNumber = "INV-1"
+    data.Invoice.IssueDate = "2024-05-01"
+    data.Invoice.DueDate = "2024-05-31"
+    data.Invoice.Currency = "IDR"
+    data.Customer.Name = `<script>alert("x")</script>`
+    data.Customer.Email = `a&b@example.com`
+    data.Customer.Address = "Jl. <b>Sudirman</b>\nJakarta"
+
+    var buf bytes.Buffer
+    err := Render(&buf, builtinTemplate(), data)
+    if err != nil {
+        t.Fatal(err)
+    }
+    out := buf.String()
+    for _, raw := range []string{"<script>", "<b>Sudirman</b>", "a&b@"} {
+        if strings.Contains(out, raw) {
+            t.Errorf("customer field %q is not escaped", raw)
+        }
+    }
+    for _, escaped := range []string{"&lt;script&gt;", "Jl. &lt;b&gt;Sudirman&lt;/b&gt;", "Jakarta", "a&amp;b@example.com"} {
+        if !strings.Contains(out, escaped) {
+            t.Errorf("output does not contain %q", escaped)
+        }
+    }
+}
+
+func TestRenderErrors(t *testing.T) {
+    var buf bytes.Buffer
+    layout := builtinTemplate()
+    layout.Body = "{{.Invoice.InvoiceNumber"
+    if err := Render(&buf, layout, Data{}); err == nil {
+        t.Error("Render of an unclosed action succeeded")
+    }
+    layout.Body = "{{.Customer.Nickname}}"
+    if err := Render(&buf, layout, Data{}); err == nil {
+        t.Error("Render of an unknown field succeeded")
+    }
+}
+
+func TestFormatDate(t *testing.T) {
+    tests := []struct {
+        args    []string
+        want    string
+        wantErr bool
+    }{
+        {[]string{"2024-05-01"}, "2024-05-01", false},
+        {[]string{"2024-05-01T00:00:00Z"}, "2024-05-01", false},
+        {[]string{"02 Jan 2006", "2024-05-01"}, "01 May 2024", false},
+        {[]string{"02 Jan 2006", "soon"}, "soon", false},
+        {nil, "", true},
+        {[]string{"a", "b", "c"}, "", true},
+    }
+    for _, tt := range tests {
+        got, err := formatDate(tt.args...)
+        if (err != nil) != tt.wantErr || got != tt.want {
+            t.Errorf("formatDate(%q) = %q, %v; want %q, error %v", tt.args, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,74 @@
+// Package terms derives invoice due dates from payment terms.
+package terms
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Terms are written as due_on_receipt, net_N (N days after issue), eom (end
+// of the issue month) or eom_N (N days after the end of the issue month).
+type Terms string
+
+const (
+    DueOnReceipt Terms = "due_on_receipt"
+    Net7         Terms = "net_7"
+    Net14        Terms = "net_14"
+    Net30        Terms = "net_30"
+    EndOfMonth   Terms = "eom"
+)
+
+// MaxDays bounds custom day counts.
+const MaxDays = 365
+
+// Default applies to customers without terms of their own. It is set at
+// start-up from DEFAULT_PAYMENT_TERMS.
+var Default = Net30
+
+func Parse(s string) (Terms, error) {
+    t := Terms(strings.ToLower(strings.TrimSpace(s)))
+    if t == DueOnReceipt || t == EndOfMonth {
+        return t, nil
+    }
+    if _, _, err := t.split(); err != nil {
+        return "", err
+    }
+    return t, nil
+}
+
+// DueDate returns the due date of an invoice issued on issue.
+func (t Terms) DueDate(issue time.Time) time.Time {
+    switch t {
+    case DueOnReceipt:
+        return issue
+    case EndOfMonth:
+        return endOfMonth(issue)
+    }
+
+    eom, days, err := t.split()
+    if err != nil {
+        return issue
+    }
+    if eom {
+        return endOfMonth(issue).AddDate(0, 0, days)
+    }
+    return issue.AddDate(0, 0, days)
+}
+
+func (t Terms) split() (bool, int, error) {
+    prefix, n, ok := strings.Cut(string(t), "_")
+    if !ok || (prefix != "net" && prefix != "eom") {
+        return false, 0, fmt.Errorf("unknown payment terms %q", string(t))
+    }
+    days, err := strconv.Atoi(n)
+    if err != nil || days < 0 || days > MaxDays {
+        return false, 0, fmt.Errorf("payment terms %q need a day count between 0 and %d", string(t), MaxDays)
+    }
+    return prefix == "eom", days, nil
+}
+
+func endOfMonth(t time.Time) time.Time {
+    return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location())
+}
\ No newline at end of file
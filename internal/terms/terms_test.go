@@ -0,0 +1,60 @@
+package terms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    Terms
+        wantErr bool
+    }{
+        {in: "due_on_receipt", want: DueOnReceipt},
+        {in: " NET_30 ", want: Net30},
+        {in: "eom", want: EndOfMonth},
+        {in: "eom_15", want: "eom_15"},
+        {in: "net_0", want: "net_0"},
+        {in: "net_365", want: "net_365"},
+        {in: "net_366", wantErr: true},
+        {in: "net_-1", wantErr: true},
+        {in: "net", wantErr: true},
+        {in: "net_x", wantErr: true},
+        {in: "cod_7", wantErr: true},
+        {in: "", wantErr: true},
+    }
+    for _, tt := range tests {
+        got, err := Parse(tt.in)
+        if (err != nil) != tt.wantErr {
+            t.Errorf("Parse(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestDueDate(t *testing.T) {
+    tests := []struct {
+        terms Terms
+        issue string
+        want  string
+    }{
+        {DueOnReceipt, "2026-03-15", "2026-03-15"},
+        {Net30, "2026-03-15", "2026-04-14"},
+        {Net14, "2026-12-25", "2027-01-08"},
+        {EndOfMonth, "2026-02-10", "2026-02-28"},
+        {EndOfMonth, "2028-02-10", "2028-02-29"},
+        {EndOfMonth, "2026-01-31", "2026-01-31"},
+        {"eom_15", "2026-01-20", "2026-02-15"},
+        {"eom_30", "2026-12-05", "2027-01-30"},
+    }
+    for _, tt := range tests {
+        issue, _ := time.Parse("2006-01-02", tt.issue)
+        if got := tt.terms.DueDate(issue).Format("2006-01-02"); got != tt.want {
+            t.Errorf("%s.DueDate(%s) = %s, want %s", tt.terms, tt.issue, got, tt.want)
+        }
+    }
+}
\ No newline at end of file
@@ -2,34 +2,110 @@ CREATE DATABASE IF NOT EXISTS invoice_system;
 
 USE invoice_system;
 
-CREATE TABLE IF NOT EXISTS customers (
+-- rate is a percentage; compound rates are charged on top of the other taxes
+CREATE TABLE IF NOT EXISTS tax_rates (
     id INT AUTO_INCREMENT PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
-    email VARCHAR(100) UNIQUE NOT NULL,
-    address TEXT NOT NULL,
+    rate DECIMAL(7,4) NOT NULL,
+    compound BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
+INSERT INTO tax_rates (id, name, rate)
+VALUES
+    (1, 'PPN 11%', 11.0000),
+    (2, 'PPN 12%', 12.0000)
+ON DUPLICATE KEY UPDATE id = id;
+
+-- rate is the value of one unit of currency in the base currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    currency CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate DECIMAL(18,8) NOT NULL,
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_exchange_rate (currency, rate_date)
+);
+
 CREATE TABLE IF NOT EXISTS items (
     id INT AUTO_INCREMENT PRIMARY KEY,
     name VARCHAR(100) NOT NULL,
-    price DECIMAL(10,2) NOT NULL,
+    price DECIMAL(15,2) NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    tax_rate_id INT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (tax_rate_id) REFERENCES tax_rates(id)
 );
 
+-- amount and max_amount are in the invoice currency; rate is a percentage,
+-- charged per day late for daily_interest
+CREATE TABLE IF NOT EXISTS late_fee_policies (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    fee_type ENUM('flat', 'percent', 'daily_interest') NOT NULL,
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    rate DECIMAL(7,4) NOT NULL DEFAULT 0,
+    grace_days INT NOT NULL DEFAULT 0,
+    max_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    apply_as ENUM('line', 'invoice') NOT NULL DEFAULT 'line',
+    fee_item_id INT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (fee_item_id) REFERENCES items(id)
+);
+
+-- Email is unique among customers that are not deleted, so a deleted
+-- customer's address can be used again
+CREATE TABLE IF NOT EXISTS customers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    address TEXT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    late_fee_policy_id INT NULL,
+    payment_terms VARCHAR(20) NOT NULL DEFAULT '',
+    reminders_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    live_email VARCHAR(100) AS (CASE WHEN deleted_at IS NULL THEN email END) UNIQUE,
+    FOREIGN KEY (late_fee_policy_id) REFERENCES late_fee_policies(id)
+);
+
+-- Only drafts are ever deleted, and only softly through deleted_at; issued
+-- invoices are voided so their numbers stay accounted for
 CREATE TABLE IF NOT EXISTS invoices (
     id INT AUTO_INCREMENT PRIMARY KEY,
-    invoice_number VARCHAR(20) UNIQUE NOT NULL,
+    invoice_number VARCHAR(50) UNIQUE NOT NULL,
     customer_id INT NOT NULL,
     issue_date DATE NOT NULL,
     due_date DATE NOT NULL,
-    total_amount DECIMAL(10,2) NOT NULL,
-    status ENUM('paid', 'unpaid') DEFAULT 'unpaid',
+    payment_terms VARCHAR(20) NOT NULL DEFAULT '',
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1,
+    prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE,
+    discount_type ENUM('', 'percent', 'amount') NOT NULL DEFAULT '',
+    discount_value DECIMAL(15,4) NOT NULL DEFAULT 0,
+    line_discount_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+    discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    subtotal DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tax_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(15,2) NOT NULL,
+    status ENUM('draft', 'issued', 'sent', 'partially_paid', 'paid', 'credited', 'overdue', 'void') NOT NULL DEFAULT 'draft',
+    late_fee_policy_id INT NULL,
+    void_reason VARCHAR(500) NOT NULL DEFAULT '',
+    voided_at TIMESTAMP NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    FOREIGN KEY (customer_id) REFERENCES customers(id)
+    deleted_at TIMESTAMP NULL,
+    FOREIGN KEY (customer_id) REFERENCES customers(id),
+    FOREIGN KEY (late_fee_policy_id) REFERENCES late_fee_policies(id)
 );
 
 CREATE TABLE IF NOT EXISTS invoice_items (
@@ -37,14 +113,359 @@ CREATE TABLE IF NOT EXISTS invoice_items (
     invoice_id INT NOT NULL,
     item_id INT NOT NULL,
     quantity INT NOT NULL,
-    price DECIMAL(10,2) NOT NULL,
+    price DECIMAL(15,2) NOT NULL,
+    discount_type ENUM('', 'percent', 'amount') NOT NULL DEFAULT '',
+    discount_value DECIMAL(15,4) NOT NULL DEFAULT 0,
+    discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    invoice_discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    net_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    line_total DECIMAL(15,2) NOT NULL DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     FOREIGN KEY (invoice_id) REFERENCES invoices(id),
     FOREIGN KEY (item_id) REFERENCES items(id)
 );
 
+-- Snapshot of each rate applied to a line at the time it was priced
+CREATE TABLE IF NOT EXISTS invoice_item_taxes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_item_id INT NOT NULL,
+    tax_rate_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    rate DECIMAL(7,4) NOT NULL,
+    compound BOOLEAN NOT NULL DEFAULT FALSE,
+    taxable_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    UNIQUE KEY uq_invoice_item_tax (invoice_item_id, tax_rate_id),
+    FOREIGN KEY (invoice_item_id) REFERENCES invoice_items(id),
+    FOREIGN KEY (tax_rate_id) REFERENCES tax_rates(id)
+);
+
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    credit_note_number VARCHAR(50) UNIQUE NOT NULL,
+    invoice_id INT NOT NULL,
+    issue_date DATE NOT NULL,
+    reason TEXT NOT NULL,
+    total_amount DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+CREATE TABLE IF NOT EXISTS credit_note_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    credit_note_id INT NOT NULL,
+    invoice_item_id INT NOT NULL,
+    quantity INT NOT NULL,
+    price DECIMAL(15,2) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (credit_note_id) REFERENCES credit_notes(id),
+    FOREIGN KEY (invoice_item_id) REFERENCES invoice_items(id)
+);
+
+-- Refunds are negative payments linked to the credit note they pay out
+CREATE TABLE IF NOT EXISTS payments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    credit_note_id INT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1,
+    paid_at DATE NOT NULL,
+    method VARCHAR(20) NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+    FOREIGN KEY (credit_note_id) REFERENCES credit_notes(id)
+);
+
+CREATE TABLE IF NOT EXISTS invoice_status_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(100) NOT NULL,
+    note TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+-- Databases from before the invoice lifecycle hold paid/unpaid statuses.
+-- Widen the column to fit both sets, map unpaid invoices to issued (the
+-- scheduler moves those past due to overdue) and narrow it again. On a new
+-- database this changes nothing.
+ALTER TABLE invoices MODIFY status ENUM('unpaid', 'draft', 'issued', 'sent', 'partially_paid', 'paid', 'credited', 'overdue', 'void') NULL;
+UPDATE invoices SET status = 'issued' WHERE status = 'unpaid' OR status IS NULL;
+ALTER TABLE invoices MODIFY status ENUM('draft', 'issued', 'sent', 'partially_paid', 'paid', 'credited', 'overdue', 'void') NOT NULL DEFAULT 'draft';
+
+CREATE TABLE IF NOT EXISTS number_sequences (
+    name VARCHAR(50) PRIMARY KEY,
+    pattern VARCHAR(50) NOT NULL,
+    reset_policy ENUM('never', 'yearly', 'monthly') NOT NULL DEFAULT 'yearly',
+    current_period VARCHAR(7) NOT NULL DEFAULT '',
+    last_value INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+INSERT IGNORE INTO number_sequences (name, pattern, reset_policy)
+VALUES
+    ('invoice', 'INV/{YYYY}/{MM}/{SEQ:5}', 'yearly'),
+    ('credit_note', 'CN/{YYYY}/{MM}/{SEQ:5}', 'yearly'),
+    ('quote', 'QUO/{YYYY}/{MM}/{SEQ:5}', 'yearly');
+
+-- currency '' bills in the customer's currency and payment_terms '' on the
+-- customer's terms. day_of_month 0 keeps the day of start_date and -1
+-- means the last day of the month.
+CREATE TABLE IF NOT EXISTS recurring_invoices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    customer_id INT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT '',
+    prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE,
+    discount_type ENUM('', 'percent', 'amount') NOT NULL DEFAULT '',
+    discount_value DECIMAL(15,4) NOT NULL DEFAULT 0,
+    interval_unit ENUM('week', 'month', 'year') NOT NULL,
+    interval_count INT NOT NULL DEFAULT 1,
+    day_of_month INT NOT NULL DEFAULT 0,
+    start_date DATE NOT NULL,
+    end_date DATE NULL,
+    payment_terms VARCHAR(20) NOT NULL DEFAULT '',
+    auto_issue BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_date DATE NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(id)
+);
+
+-- tax_rate_ids NULL applies the item default tax rate
+CREATE TABLE IF NOT EXISTS recurring_invoice_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    recurring_invoice_id INT NOT NULL,
+    item_id INT NOT NULL,
+    quantity INT NOT NULL,
+    discount_type ENUM('', 'percent', 'amount') NOT NULL DEFAULT '',
+    discount_value DECIMAL(15,4) NOT NULL DEFAULT 0,
+    tax_rate_ids JSON NULL,
+    FOREIGN KEY (recurring_invoice_id) REFERENCES recurring_invoices(id),
+    FOREIGN KEY (item_id) REFERENCES items(id)
+);
+
+-- One row per generated run date so a restarted scheduler never bills twice
+CREATE TABLE IF NOT EXISTS recurring_invoice_runs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    recurring_invoice_id INT NOT NULL,
+    run_date DATE NOT NULL,
+    invoice_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_recurring_run (recurring_invoice_id, run_date),
+    FOREIGN KEY (recurring_invoice_id) REFERENCES recurring_invoices(id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+-- Each assessment keeps a copy of the policy terms it was computed with
+CREATE TABLE IF NOT EXISTS late_fees (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    policy_id INT NOT NULL,
+    fee_type VARCHAR(20) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    rate DECIMAL(7,4) NOT NULL,
+    grace_days INT NOT NULL,
+    max_amount DECIMAL(15,2) NOT NULL,
+    assessed_on DATE NOT NULL,
+    days_late INT NOT NULL,
+    base_amount DECIMAL(15,2) NOT NULL,
+    fee_amount DECIMAL(15,2) NOT NULL,
+    total_accrued DECIMAL(15,2) NOT NULL,
+    invoice_item_id INT NULL,
+    fee_invoice_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_late_fee_day (invoice_id, assessed_on),
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+    FOREIGN KEY (policy_id) REFERENCES late_fee_policies(id),
+    FOREIGN KEY (invoice_item_id) REFERENCES invoice_items(id),
+    FOREIGN KEY (fee_invoice_id) REFERENCES invoices(id)
+);
+
+-- Single row (id 1) with the seller details printed on invoices
+CREATE TABLE IF NOT EXISTS company_profile (
+    id INT PRIMARY KEY,
+    legal_name VARCHAR(255) NOT NULL,
+    address TEXT NOT NULL,
+    tax_id VARCHAR(50) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(50) NOT NULL,
+    logo_url VARCHAR(500) NOT NULL,
+    bank_details TEXT NOT NULL,
+    footer_terms TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- HTML invoice layouts; these take precedence over TEMPLATE_DIR files
+CREATE TABLE IF NOT EXISTS invoice_templates (
+    name VARCHAR(50) PRIMARY KEY,
+    body MEDIUMTEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Every attempt to email an invoice, successful or not
+CREATE TABLE IF NOT EXISTS email_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    template VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL,
+    sent_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+-- offset_days counts from the due date: -3 is three days before, 7 a week
+-- after. An empty subject or body uses the built-in reminder.
+CREATE TABLE IF NOT EXISTS reminder_rules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    offset_days INT NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+INSERT INTO reminder_rules (id, name, offset_days, subject, body)
+VALUES
+    (1, '3 days before due', -3, '', ''),
+    (2, 'On due date', 0, '', ''),
+    (3, '7 days overdue', 7, '', ''),
+    (4, '30 days overdue', 30, '', '')
+ON DUPLICATE KEY UPDATE id = id;
+
+-- One entry per rule and invoice keeps a reminder from going out twice
+CREATE TABLE IF NOT EXISTS payment_reminders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    invoice_id INT NOT NULL,
+    rule_id INT NOT NULL,
+    days_from_due INT NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_reminder_invoice_rule (invoice_id, rule_id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id),
+    FOREIGN KEY (rule_id) REFERENCES reminder_rules(id)
+);
+
+-- Quotes are priced like invoices; invoice_id is set once an accepted
+-- quote has been converted
+CREATE TABLE IF NOT EXISTS quotes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    quote_number VARCHAR(50) UNIQUE NOT NULL,
+    customer_id INT NOT NULL,
+    issue_date DATE NOT NULL,
+    expiry_date DATE NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1,
+    prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE,
+    discount_type ENUM('', 'percent', 'amount') NOT NULL DEFAULT '',
+    discount_value DECIMAL(15,4) NOT NULL DEFAULT 0,
+    line_discount_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+    discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    subtotal DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tax_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    status ENUM('draft', 'sent', 'accepted', 'declined', 'expired', 'converted') NOT NULL DEFAULT 'draft',
+    notes TEXT NOT NULL,
+    invoice_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_quote_invoice (invoice_id),
+    FOREIGN KEY (customer_id) REFERENCES customers(id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id)
+);
+
+CREATE TABLE IF NOT EXISTS quote_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    quote_id INT NOT NULL,
+    item_id INT NOT NULL,
+    quantity INT NOT NULL,
+    price DECIMAL(15,2) NOT NULL,
+    discount_type ENUM('', 'percent', 'amount') NOT NULL DEFAULT '',
+    discount_value DECIMAL(15,4) NOT NULL DEFAULT 0,
+    discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    quote_discount_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    net_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    line_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (quote_id) REFERENCES quotes(id),
+    FOREIGN KEY (item_id) REFERENCES items(id)
+);
+
+CREATE TABLE IF NOT EXISTS quote_item_taxes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    quote_item_id INT NOT NULL,
+    tax_rate_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    rate DECIMAL(7,4) NOT NULL,
+    compound BOOLEAN NOT NULL DEFAULT FALSE,
+    taxable_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    UNIQUE KEY uq_quote_item_tax (quote_item_id, tax_rate_id),
+    FOREIGN KEY (quote_item_id) REFERENCES quote_items(id),
+    FOREIGN KEY (tax_rate_id) REFERENCES tax_rates(id)
+);
+
+-- Append-only record of changes made through the API and by background
+-- jobs, which have no request_id; before_data is NULL for creations
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    request_id VARCHAR(64) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id INT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    before_data JSON NULL,
+    after_data JSON NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Files uploaded against an invoice or customer; the content lives in the
+-- configured storage under storage_key
+CREATE TABLE IF NOT EXISTS attachments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    entity_type ENUM('invoice', 'customer') NOT NULL,
+    entity_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) UNIQUE NOT NULL,
+    uploaded_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Indexes
 CREATE INDEX idx_customer_email ON customers(email);
 CREATE INDEX idx_invoice_status ON invoices(status);
-CREATE INDEX idx_invoice_customer ON invoices(customer_id);
\ No newline at end of file
+CREATE INDEX idx_invoice_customer ON invoices(customer_id);
+CREATE INDEX idx_payment_invoice ON payments(invoice_id);
+CREATE INDEX idx_credit_note_invoice ON credit_notes(invoice_id);
+CREATE INDEX idx_recurring_next_run ON recurring_invoices(active, next_run_date);
+CREATE INDEX idx_invoice_due_date ON invoices(status, due_date);
+CREATE INDEX idx_email_delivery_invoice ON email_deliveries(invoice_id);
+CREATE INDEX idx_quote_status ON quotes(status, expiry_date);
+CREATE INDEX idx_quote_customer ON quotes(customer_id);
+CREATE INDEX idx_audit_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_request ON audit_log(request_id);
+CREATE INDEX idx_attachment_entity ON attachments(entity_type, entity_id);
\ No newline at end of file